@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	"nodabackend/internal/auth/interface/http"
+	"nodabackend/internal/auth/oauth"
+	"nodabackend/internal/auth/repository"
 	"nodabackend/pkg/database"
+	"nodabackend/pkg/encryption"
+	"nodabackend/pkg/env"
 	"nodabackend/pkg/jwthelper"
-
-	// "nodabackend/pkg/redis"
+	"nodabackend/pkg/mailer"
+	"nodabackend/pkg/otp"
+	"nodabackend/pkg/redis"
+	"nodabackend/pkg/sms"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,14 +26,41 @@ func main() {
 	}
 	db := dbInstance.GetDB()
 
+	if err := repository.Migrate(db); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
 	// 2. Подключение к Redis
-	// redisClient, err := redis.NewClient()
-	// if err != nil {
-	// 	log.Fatal("Failed to connect to Redis:", err)
-	// }
+	// Используется для отзыва токенов (logout) и хранения OTP кодов
+	redisClient, err := redis.NewClient()
+	if err != nil {
+		log.Fatal("Failed to connect to Redis:", err)
+	}
 
 	// 3. Инициализация JWT helper
-	jwtHelper := jwthelper.NewJWTHelper()
+	// Ключи подписи загружаются из PEM файлов или окружения; без них запуск возможен только при APP_ENV=development
+	jwtHelper, err := jwthelper.NewJWTHelperFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+
+	// 3.1 Сервисы доставки кодов: OTP, SMS и email
+	// OTP_STORAGE=memory хранит коды в памяти процесса (только для single-node разработки)
+	otpConfig := otp.DefaultConfig()
+	otpConfig.Storage = env.GetEnvOrDefault("OTP_STORAGE", otp.StorageRedis)
+	otpService := otp.NewOTPService(redisClient, otpConfig)
+	smsService := sms.NewTwilioSMSServiceFromEnv()
+	smtpMailer := mailer.NewSMTPMailerFromEnv()
+
+	// 3.2 Шифрование секретов, хранящихся в БД (TOTP)
+	// Без ENCRYPTION_KEY запуск возможен только при APP_ENV=development
+	encryptor, err := encryption.NewEncryptorFromEnv()
+	if err != nil {
+		log.Fatal("Failed to load encryption key:", err)
+	}
+
+	// 3.3 Внешние провайдеры входа (OAUTH_PROVIDERS), настройки загружаются через OIDC discovery
+	oauthProviders := oauth.NewProvidersFromEnv(context.Background())
 
 	// 4. Настройка HTTP сервера
 	app := fiber.New()
@@ -39,19 +73,30 @@ func main() {
 		})
 	})
 
+	// Открытые ключи для проверки access токенов другими сервисами
+	app.Get("/.well-known/jwks.json", http.JWKSHandler(jwtHelper))
+
 	// API v1 routes
 	api := app.Group("/api/v1")
 
 	// Auth routes
-	// TODO: Передать redisClient в RegisterRoutes, когда он понадобится
-	authMiddleware := http.RegisterRoutes(api, db, jwtHelper)
+	authMiddleware := http.RegisterRoutes(api, http.Dependencies{
+		DB:             db,
+		Redis:          redisClient,
+		JWTHelper:      jwtHelper,
+		Mailer:         smtpMailer,
+		OTPService:     otpService,
+		SMSService:     smsService,
+		Encryptor:      encryptor,
+		OAuthProviders: oauthProviders,
+	})
 
 	// Example protected route
 	protected := api.Group("/protected", authMiddleware.RequireAuth)
 	protected.Get("/profile", func(c *fiber.Ctx) error {
 		userID := c.Locals("userID").(uint)
 		phone := c.Locals("phone").(string)
-		
+
 		return c.JSON(fiber.Map{
 			"message": "This is a protected route",
 			"user_id": userID,
@@ -61,4 +106,4 @@ func main() {
 
 	log.Println("Server starting on :3000")
 	app.Listen(":3000")
-}
\ No newline at end of file
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix начало каждого API ключа, чтобы его было легко узнать (например, сканерам секретов)
+const APIKeyPrefix = "nk_"
+
+// APIKey персональный API ключ пользователя для скриптов и интеграций.
+// Сам ключ не хранится — только его SHA-256 хеш; для отображения сохраняется начало ключа.
+// Scopes ограничивают права ключа подмножеством прав роли пользователя.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Scope      string     `json:"scope" gorm:"type:varchar(512)"` // права через пробел, например "profile:read users:read"
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`           // nil — бессрочный ключ
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+// Scopes возвращает права, разрешенные ключу
+func (k *APIKey) Scopes() []string {
+	return strings.Fields(k.Scope)
+}
+
+// IsActive проверяет, что ключ не отозван и не истек
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// HashAPIKey хеширует API ключ для хранения и поиска.
+// Ключ случайный (256 бит), поэтому, как и для refresh токенов, достаточно SHA-256.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// APIKeyRepository интерфейс для работы с API ключами
+type APIKeyRepository interface {
+	CreateAPIKey(key *APIKey) error
+	GetAPIKeyByHash(hash string) (*APIKey, error)
+	// GetAPIKeysByUserID возвращает неотозванные ключи пользователя, в том числе истекшие
+	GetAPIKeysByUserID(userID uint) ([]*APIKey, error)
+	// RevokeAPIKey отзывает ключ пользователя; false — ключ не найден или уже отозван
+	RevokeAPIKey(userID, id uint) (bool, error)
+	// TouchAPIKey сохраняет время последнего использования ключа
+	TouchAPIKey(id uint, usedAt time.Time) error
+}
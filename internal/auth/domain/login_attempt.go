@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttemptStore счетчики неудачных попыток входа и блокировки.
+// Ключ — идентификатор, по которому считаются попытки (логин или IP клиента).
+type LoginAttemptStore interface {
+	// RegisterLoginFailure увеличивает счетчик неудач; окно отсчитывается от первой неудачи
+	RegisterLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	// ResetLoginFailures сбрасывает счетчик неудач и блокировку
+	ResetLoginFailures(ctx context.Context, key string) error
+	// BlockLogin запрещает попытки входа по ключу на время d
+	BlockLogin(ctx context.Context, key string, d time.Duration) error
+	// LoginRetryAfter возвращает, сколько осталось ждать до следующей попытки (0 — попытка разрешена).
+	// Если заблокировано несколько ключей, возвращается наибольшее время.
+	LoginRetryAfter(ctx context.Context, keys ...string) (time.Duration, error)
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMagicLinkNotFound ссылка не найдена, уже использована или истекла
+var ErrMagicLinkNotFound = errors.New("magic link not found or expired")
+
+// MagicLink выданная, но еще не использованная ссылка для входа по email
+type MagicLink struct {
+	UserID   uint   `json:"user_id"`
+	IP       string `json:"ip,omitempty"`        // не пусто — ссылка действует только с этого IP
+	DeviceID string `json:"device_id,omitempty"` // не пусто — ссылка действует только на этом устройстве
+}
+
+// MagicLinkStore хранит одноразовые ссылки для входа
+type MagicLinkStore interface {
+	SaveMagicLink(ctx context.Context, tokenID string, link *MagicLink, ttl time.Duration) error
+	// PopMagicLink возвращает и удаляет ссылку, чтобы ее нельзя было использовать повторно
+	PopMagicLink(ctx context.Context, tokenID string) (*MagicLink, error)
+	// AcquireMagicLinkCooldown разрешает отправку не чаще одного раза за cooldown.
+	// Возвращает false, если письмо пользователю уже отправлялось недавно.
+	AcquireMagicLinkCooldown(ctx context.Context, userID uint, cooldown time.Duration) (bool, error)
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrOAuthStateNotFound state не найден, уже использован или истек
+var ErrOAuthStateNotFound = errors.New("oauth state not found or expired")
+
+// ExternalIdentity пользователь внешнего провайдера, подтвержденный ID токеном
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string // claim "sub" — стабильный идентификатор у провайдера
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthProvider внешний провайдер входа (OAuth2 authorization code + PKCE)
+type OAuthProvider interface {
+	// Name имя провайдера в маршрутах, например "google"
+	Name() string
+	// AuthCodeURL адрес страницы авторизации провайдера.
+	// codeVerifier — PKCE verifier, провайдер передает его S256 challenge.
+	AuthCodeURL(state, nonce, codeVerifier string) string
+	// Exchange обменивает code на токены и проверяет ID токен (подпись по JWKS, aud, exp, nonce)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error)
+}
+
+// OAuthState данные незавершенной авторизации у провайдера
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	BindingHash  string `json:"binding_hash"`           // SHA-256 секрета из cookie браузера, начавшего авторизацию
+	LinkUserID   uint   `json:"link_user_id,omitempty"` // не 0 — привязка к уже вошедшему пользователю
+}
+
+// MatchesBinding проверяет, что callback пришел из браузера, начавшего авторизацию
+func (s *OAuthState) MatchesBinding(binding string) bool {
+	return binding != "" && subtle.ConstantTimeCompare([]byte(HashOAuthBinding(binding)), []byte(s.BindingHash)) == 1
+}
+
+// HashOAuthBinding хеширует секрет привязки авторизации к браузеру для хранения вместе со state
+func HashOAuthBinding(binding string) string {
+	hash := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(hash[:])
+}
+
+// OAuthStateStore хранит state, nonce и PKCE verifier между редиректами
+type OAuthStateStore interface {
+	SaveOAuthState(ctx context.Context, state string, data *OAuthState, ttl time.Duration) error
+	// PopOAuthState возвращает и удаляет данные, чтобы state нельзя было использовать повторно
+	PopOAuthState(ctx context.Context, state string) (*OAuthState, error)
+}
+
+// LinkedIdentity связь аккаунта внешнего провайдера с пользователем
+type LinkedIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"index;not null"`
+	Provider  string    `json:"provider" gorm:"type:varchar(50);not null;uniqueIndex:idx_linked_identities_provider_subject"`
+	Subject   string    `json:"-" gorm:"type:varchar(255);not null;uniqueIndex:idx_linked_identities_provider_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LinkedIdentityRepository интерфейс для работы со связанными аккаунтами
+type LinkedIdentityRepository interface {
+	CreateLinkedIdentity(identity *LinkedIdentity) error
+	GetLinkedIdentity(provider, subject string) (*LinkedIdentity, error)
+	GetLinkedIdentitiesByUserID(userID uint) ([]*LinkedIdentity, error)
+	DeleteLinkedIdentity(userID uint, provider string) error
+}
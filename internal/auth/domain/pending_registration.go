@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPendingRegistrationNotFound регистрация не найдена, уже подтверждена или истекла
+var ErrPendingRegistrationNotFound = errors.New("pending registration not found or expired")
+
+// PendingRegistration регистрация, ожидающая подтверждения телефона кодом из SMS.
+// Пользователь создается только после подтверждения, до этого записи в базе не меняются.
+type PendingRegistration struct {
+	Phone        string `json:"phone"`
+	Email        string `json:"email,omitempty"`
+	PasswordHash string `json:"password_hash"`
+	Name         string `json:"name"`
+}
+
+// PendingRegistrationStore хранит неподтвержденные регистрации
+type PendingRegistrationStore interface {
+	SavePendingRegistration(ctx context.Context, id string, registration *PendingRegistration, ttl time.Duration) error
+	// PopPendingRegistration возвращает и удаляет регистрацию, чтобы ее нельзя было подтвердить повторно
+	PopPendingRegistration(ctx context.Context, id string) (*PendingRegistration, error)
+}
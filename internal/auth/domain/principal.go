@@ -0,0 +1,14 @@
+package domain
+
+// Тип субъекта запроса, который AuthMiddleware сохраняет в c.Locals("principalType")
+const (
+	PrincipalUser    = "user"    // пользователь, вошедший в приложение
+	PrincipalService = "service" // внутренний сервис с токеном client credentials
+)
+
+// Способ аутентификации пользователя, который AuthMiddleware сохраняет в c.Locals("authMethod")
+const (
+	AuthMethodBearer = "bearer"  // access токен из заголовка Authorization: Bearer
+	AuthMethodAPIKey = "api_key" // персональный API ключ из заголовка Authorization: ApiKey
+	AuthMethodCookie = "cookie"  // access токен из HttpOnly cookie (режим cookie-сессий браузера)
+)
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// RecoveryCode одноразовый код восстановления доступа для пользователя с 2FA.
+// Сам код не хранится — только его SHA-256 хеш.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`
+	CodeHash  string     `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time // время использования; nil — код еще действует
+	CreatedAt time.Time
+}
+
+// RecoveryCodeRepository интерфейс для работы с кодами восстановления
+type RecoveryCodeRepository interface {
+	// ReplaceRecoveryCodes удаляет все коды пользователя и сохраняет новые
+	ReplaceRecoveryCodes(userID uint, codes []*RecoveryCode) error
+	// UseRecoveryCode помечает код использованным.
+	// Возвращает false, если кода нет или он уже использован (в том числе конкурентным запросом).
+	UseRecoveryCode(userID uint, codeHash string) (bool, error)
+	CountRemainingRecoveryCodes(userID uint) (int64, error)
+}
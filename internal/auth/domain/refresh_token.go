@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// RefreshToken представляет сохраненный на сервере refresh токен.
+// Сам токен не хранится — только его SHA-256 хеш.
+// Все токены, полученные ротацией из одного логина, принадлежат одному семейству (FamilyID).
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`
+	FamilyID  string     `gorm:"type:varchar(64);index;not null"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	RotatedAt *time.Time // время, когда токен был обменян на новый
+	RevokedAt *time.Time // время отзыва токена
+	CreatedAt time.Time
+}
+
+// RefreshTokenRepository интерфейс для работы с refresh токенами
+type RefreshTokenRepository interface {
+	CreateRefreshToken(token *RefreshToken) error
+	GetRefreshTokenByHash(hash string) (*RefreshToken, error)
+	// MarkRefreshTokenRotated помечает токен использованным.
+	// Возвращает false, если токен уже был использован или отозван (конкурентный запрос).
+	MarkRefreshTokenRotated(id uint) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeUserRefreshTokens(userID uint) error
+}
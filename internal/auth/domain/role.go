@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+// Permission право на действие в формате "ресурс:действие", например "users:write"
+type Permission string
+
+const (
+	PermissionProfileRead   Permission = "profile:read"
+	PermissionProfileWrite  Permission = "profile:write"
+	PermissionUsersRead     Permission = "users:read"
+	PermissionUsersBan      Permission = "users:ban"
+	PermissionUsersWrite    Permission = "users:write"
+	PermissionSessionsWrite Permission = "sessions:write"
+	PermissionRolesWrite    Permission = "roles:write"
+)
+
+// RoleDefinition роль, хранящаяся в базе.
+// Роль наследует все права родительской роли: admin -> moderator -> user,
+// поэтому администратор проходит проверки, рассчитанные на пользователя.
+type RoleDefinition struct {
+	Name        Role              `json:"name" gorm:"primaryKey;type:varchar(20)"`
+	Description string            `json:"description"`
+	Parent      *Role             `json:"parent" gorm:"type:varchar(20)"` // nil — корневая роль
+	Permissions []*RolePermission `json:"permissions" gorm:"foreignKey:RoleName;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// RolePermission право, выданное роли напрямую (без учета наследования)
+type RolePermission struct {
+	RoleName   Role       `json:"-" gorm:"primaryKey;type:varchar(20)"`
+	Permission Permission `json:"permission" gorm:"primaryKey;type:varchar(50)"`
+}
+
+// RoleRepository интерфейс для работы с ролями и их правами
+type RoleRepository interface {
+	// GetRoles возвращает все роли вместе с их собственными правами
+	GetRoles() ([]*RoleDefinition, error)
+}
+
+// DefaultRoles роли, которые создаются при миграции, если их еще нет в базе.
+// Права существующих ролей миграция не трогает, чтобы не откатывать изменения администратора.
+func DefaultRoles() []*RoleDefinition {
+	userRole, moderatorRole := UserRole, ModeratorRole
+	return []*RoleDefinition{
+		{
+			Name:        UserRole,
+			Description: "Regular user",
+			Permissions: rolePermissions(UserRole, PermissionProfileRead, PermissionProfileWrite),
+		},
+		{
+			Name:        ModeratorRole,
+			Description: "Can view and ban users",
+			Parent:      &userRole,
+			Permissions: rolePermissions(ModeratorRole, PermissionUsersRead, PermissionUsersBan),
+		},
+		{
+			Name:        AdminRole,
+			Description: "Full access to user management",
+			Parent:      &moderatorRole,
+			Permissions: rolePermissions(AdminRole, PermissionUsersWrite, PermissionSessionsWrite, PermissionRolesWrite),
+		},
+	}
+}
+
+// rolePermissions собирает список прав роли
+func rolePermissions(role Role, permissions ...Permission) []*RolePermission {
+	result := make([]*RolePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		result = append(result, &RolePermission{RoleName: role, Permission: permission})
+	}
+	return result
+}
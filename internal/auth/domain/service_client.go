@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// ScopeIntrospect scope, разрешающий клиенту проверять чужие токены через /auth/introspect
+const ScopeIntrospect = "introspect"
+
+// ServiceClient зарегистрированный внутренний сервис (воркер), получающий токены
+// по client credentials grant. Секрет не хранится — только его SHA-256 хеш.
+type ServiceClient struct {
+	ID         string     `json:"client_id" gorm:"type:varchar(64);primaryKey"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	SecretHash string     `json:"-" gorm:"type:varchar(64);not null"`
+	Scope      string     `json:"scope" gorm:"type:varchar(512)"` // разрешенные scopes через пробел (RFC 6749)
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Scopes возвращает разрешенные клиенту scopes
+func (c *ServiceClient) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope проверяет, разрешен ли клиенту scope
+func (c *ServiceClient) HasScope(scope string) bool {
+	for _, allowed := range c.Scopes() {
+		if allowed == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRevoked проверяет, отозван ли клиент
+func (c *ServiceClient) IsRevoked() bool {
+	return c.RevokedAt != nil
+}
+
+// ServiceClientRepository интерфейс для работы с сервисными клиентами
+type ServiceClientRepository interface {
+	CreateServiceClient(client *ServiceClient) error
+	// GetServiceClient возвращает клиента по ID, в том числе отозванного
+	GetServiceClient(id string) (*ServiceClient, error)
+	ListServiceClients() ([]*ServiceClient, error)
+	// RevokeServiceClient отзывает клиента; false — клиент не найден или уже отозван
+	RevokeServiceClient(id string) (bool, error)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Session вход пользователя на устройстве.
+// Одной сессии соответствует одно семейство refresh токенов: ID сессии совпадает с FamilyID.
+type Session struct {
+	ID         string     `json:"id" gorm:"type:varchar(64);primaryKey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	DeviceName string     `json:"device_name" gorm:"type:varchar(100)"`
+	UserAgent  string     `json:"user_agent" gorm:"type:varchar(512)"`
+	IP         string     `json:"ip" gorm:"type:varchar(45)"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"` // истекает вместе с последним refresh токеном семейства
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current" gorm:"-"` // сессия, из которой сделан запрос
+}
+
+// SessionRepository интерфейс для работы с сессиями
+type SessionRepository interface {
+	CreateSession(session *Session) error
+	// GetActiveSessionsByUserID возвращает неотозванные и неистекшие сессии пользователя
+	GetActiveSessionsByUserID(userID uint) ([]*Session, error)
+	// ExtendSession обновляет сессию при ротации refresh токена
+	ExtendSession(id, ip string, lastSeenAt, expiresAt time.Time) error
+	// TouchSessions сохраняет время последней активности сразу для нескольких сессий
+	TouchSessions(lastSeen map[string]time.Time) error
+	// RevokeSession отзывает сессию пользователя; false — сессия не найдена или уже отозвана
+	RevokeSession(userID uint, id string) (bool, error)
+	RevokeUserSessions(userID uint) error
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// TokenRevocationStore хранилище отозванных access токенов.
+// Отдельные токены отзываются по jti, токены сессии — по ее ID, все токены пользователя — через
+// отметку времени: токены, выпущенные раньше нее, считаются недействительными.
+type TokenRevocationStore interface {
+	// RevokeToken добавляет jti в denylist до истечения срока действия токена
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	// RevokeUserTokens делает недействительными все токены пользователя, выпущенные до момента before
+	// включительно. Токены, выпущенные позже, действуют, даже если выпущены в ту же секунду.
+	RevokeUserTokens(ctx context.Context, userID uint, before time.Time, ttl time.Duration) error
+	// RevokeSession делает недействительными все access токены сессии
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+	// IsTokenRevoked проверяет токен по jti, сессии и отметке времени пользователя.
+	// Пустой sessionID — токен выпущен без сессии, проверка по сессии пропускается.
+	IsTokenRevoked(ctx context.Context, jti, sessionID string, userID uint, issuedAt time.Time) (bool, error)
+}
@@ -1,29 +1,144 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Role представляет роль пользователя в системе
 type Role string
 
 const (
-	AdminRole Role = "admin"
-	UserRole  Role = "user"
+	AdminRole     Role = "admin"
+	ModeratorRole Role = "moderator"
+	UserRole      Role = "user"
 )
 
 // User представляет пользователя в системе
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Phone     string    `json:"phone" gorm:"uniqueIndex"`
-	Password  string    `json:"-" gorm:"column:password"`  // пароль не возвращается в JSON
-	Name      string    `json:"name"`
-	Role      Role      `json:"role" gorm:"type:varchar(20);default:'user'"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                    uint           `json:"id" gorm:"primaryKey"`
+	Phone                 string         `json:"phone" gorm:"uniqueIndex"`
+	Password              string         `json:"-" gorm:"column:password"` // пароль не возвращается в JSON
+	Name                  string         `json:"name"`
+	AvatarURL             string         `json:"avatar_url"`
+	Locale                string         `json:"locale" gorm:"type:varchar(35)"`   // BCP 47, например "ru-RU"
+	Timezone              string         `json:"timezone" gorm:"type:varchar(64)"` // IANA, например "Asia/Almaty"
+	Role                  Role           `json:"role" gorm:"type:varchar(20);default:'user'"`
+	PhoneVerifiedAt       *time.Time     `json:"phone_verified_at"`        // nil — телефон не подтвержден кодом из SMS
+	PendingPhone          *string        `json:"pending_phone,omitempty"`  // новый телефон, ожидающий подтверждения кодом
+	Email                 *string        `json:"email" gorm:"uniqueIndex"` // необязательный, nil не нарушает уникальность
+	EmailVerifiedAt       *time.Time     `json:"email_verified_at"`
+	TOTPSecret            string         `json:"-"`               // зашифрованный TOTP секрет; пусто — приложение не подключалось
+	TOTPEnabledAt         *time.Time     `json:"totp_enabled_at"` // nil — 2FA через приложение не включена
+	TOTPLastStep          int64          `json:"-"`               // последний принятый шаг TOTP, защита от повторного использования кода
+	LockedUntil           *time.Time     `json:"locked_until"`    // вход по паролю заблокирован до этого времени после серии блокировок
+	BannedAt              *time.Time     `json:"banned_at"`       // nil — пользователь не заблокирован администратором
+	BanReason             string         `json:"ban_reason,omitempty"`
+	PasswordResetRequired bool           `json:"password_reset_required"` // администратор потребовал сброс: вход по паролю запрещен до сброса по коду
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `json:"deleted_at" gorm:"index"` // мягкое удаление: запросы не видят удаленных пользователей
+	AnonymizedAt          *time.Time     `json:"anonymized_at"`           // персональные данные стерты после удаления
+}
+
+// IsPhoneVerified проверяет, подтвержден ли телефон пользователя
+func (u *User) IsPhoneVerified() bool {
+	return u.PhoneVerifiedAt != nil
+}
+
+// IsEmailVerified проверяет, подтвержден ли email пользователя
+func (u *User) IsEmailVerified() bool {
+	return u.Email != nil && u.EmailVerifiedAt != nil
+}
+
+// IsTOTPEnabled проверяет, включена ли двухфакторная аутентификация через приложение
+func (u *User) IsTOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
+// IsLocked проверяет, заблокирован ли вход по паролю на момент now
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
+}
+
+// IsBanned проверяет, заблокирован ли пользователь администратором
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
+}
+
+// Ошибки уникальности. Мягко удаленный пользователь занимает телефон и email до обезличивания.
+var (
+	ErrPhoneTaken = errors.New("phone already in use")
+	ErrEmailTaken = errors.New("email already in use")
+)
+
+// Статусы пользователя для фильтрации списка
+const (
+	UserStatusActive  = "active"
+	UserStatusBanned  = "banned"
+	UserStatusDeleted = "deleted"
+)
+
+// UserFilter параметры выборки списка пользователей
+type UserFilter struct {
+	Search  string // подстрока телефона, email или имени
+	Role    Role
+	Status  string // UserStatusActive, UserStatusBanned, UserStatusDeleted; пусто — все, кроме удаленных
+	Page    int    // начиная с 1
+	PerPage int
 }
 
 // UserRepository интерфейс для работы с пользователями
 type UserRepository interface {
+	// CreateUser создает пользователя; ErrPhoneTaken или ErrEmailTaken — телефон или email заняты
 	CreateUser(user *User) error
 	GetUserByPhone(phone string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	// IsPhoneTaken проверяет, занят ли телефон, в том числе мягко удаленным пользователем
+	IsPhoneTaken(phone string) (bool, error)
+	// IsEmailTaken проверяет, занят ли email, в том числе мягко удаленным пользователем
+	IsEmailTaken(email string) (bool, error)
 	GetUserByID(id uint) (*User, error)
+	// GetUserByIDWithDeleted получает пользователя по ID, в том числе мягко удаленного
+	GetUserByIDWithDeleted(id uint) (*User, error)
+	// ListUsers возвращает страницу пользователей и общее число подходящих под фильтр
+	ListUsers(filter UserFilter) ([]*User, int64, error)
+	// Методы Update* и Mark* меняют только свои столбцы: конкурентные изменения других полей
+	// (например, блокировка администратором во время правки профиля) не затираются.
+	// UpdateUserRole меняет роль пользователя
+	UpdateUserRole(id uint, role Role) error
+	// UpdateUserBan блокирует (bannedAt не nil) или разблокирует пользователя
+	UpdateUserBan(id uint, bannedAt *time.Time, reason string) error
+	// UpdateUserLock блокирует вход по паролю до lockedUntil; nil снимает блокировку
+	UpdateUserLock(id uint, lockedUntil *time.Time) error
+	// RequirePasswordReset запрещает вход по паролю до сброса пароля
+	RequirePasswordReset(id uint) error
+	// UpdateUserPassword сохраняет хеш нового пароля и снимает требование сброса
+	UpdateUserPassword(id uint, passwordHash string) error
+	// UpdateUserProfile сохраняет имя, аватар, локаль и часовой пояс пользователя
+	UpdateUserProfile(user *User) error
+	// UpdateUserPendingPhone сохраняет телефон, ожидающий подтверждения; nil — смена отменена
+	UpdateUserPendingPhone(id uint, phone *string) error
+	// UpdateUserPhone меняет подтвержденный телефон и сбрасывает ожидающий; ErrPhoneTaken — телефон занят
+	UpdateUserPhone(id uint, phone string, verifiedAt time.Time) error
+	// MarkPhoneVerified отмечает телефон подтвержденным
+	MarkPhoneVerified(id uint, verifiedAt time.Time) error
+	// MarkEmailVerified отмечает email подтвержденным
+	MarkEmailVerified(id uint, verifiedAt time.Time) error
+	// UpdateTOTPSecret сохраняет новый зашифрованный TOTP секрет и сбрасывает последний шаг
+	UpdateTOTPSecret(id uint, secret string) error
+	// EnableTOTP включает 2FA через приложение
+	EnableTOTP(id uint, enabledAt time.Time) error
+	// AdvanceTOTPStep сохраняет принятый шаг TOTP, только если он больше последнего.
+	// Возвращает false, если шаг уже использован (в том числе конкурентным запросом).
+	AdvanceTOTPStep(id uint, step int64) (bool, error)
+	// DeleteUser мягко удаляет пользователя
+	DeleteUser(id uint) error
+	// GetUsersDeletedBefore возвращает до limit удаленных раньше before и еще не обезличенных пользователей
+	GetUsersDeletedBefore(before time.Time, limit int) ([]*User, error)
+	// AnonymizeUser стирает персональные данные удаленного пользователя
+	// и удаляет связанные с ним сессии, ключи и привязки
+	AnonymizeUser(id uint) error
 }
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrWebAuthnSessionNotFound церемония не найдена, уже завершена или истекла
+var ErrWebAuthnSessionNotFound = errors.New("webauthn session not found or expired")
+
+// WebAuthnCredential зарегистрированный ключ доступа (passkey) пользователя.
+// Хранится только публичный ключ; приватный ключ никогда не покидает аутентификатор.
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"-" gorm:"index;not null"`
+	CredentialID    []byte     `json:"-" gorm:"uniqueIndex;not null"`
+	PublicKey       []byte     `json:"-" gorm:"not null"`
+	AttestationType string     `json:"-" gorm:"type:varchar(32)"`
+	AAGUID          []byte     `json:"-"`
+	Transports      string     `json:"-" gorm:"type:varchar(255)"` // через запятую: internal,hybrid,usb...
+	SignCount       uint32     `json:"-" gorm:"not null;default:0"`
+	BackupEligible  bool       `json:"backup_eligible"` // ключ может синхронизироваться между устройствами
+	BackupState     bool       `json:"backup_state"`
+	Name            string     `json:"name" gorm:"type:varchar(100)"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// WebAuthnCredentialRepository интерфейс для работы с ключами доступа
+type WebAuthnCredentialRepository interface {
+	CreateWebAuthnCredential(credential *WebAuthnCredential) error
+	GetWebAuthnCredentialsByUserID(userID uint) ([]*WebAuthnCredential, error)
+	UpdateWebAuthnCredential(credential *WebAuthnCredential) error
+}
+
+// WebAuthnSessionStore хранит данные незавершенных WebAuthn церемоний (challenge)
+type WebAuthnSessionStore interface {
+	SaveWebAuthnSession(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// PopWebAuthnSession возвращает и удаляет данные церемонии, чтобы challenge нельзя было использовать повторно
+	PopWebAuthnSession(ctx context.Context, key string) ([]byte, error)
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/usecase"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ChangeRoleRequest структура для смены роли пользователя
+type ChangeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// BanUserRequest структура для блокировки пользователя
+type BanUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListUsers возвращает страницу пользователей.
+// Параметры: page, per_page, role, status (active, banned, deleted), q — поиск по телефону, email и имени.
+func (h *AuthHandler) ListUsers(c *fiber.Ctx) error {
+	page, err := h.authUseCase.ListUsers(domain.UserFilter{
+		Search:  c.Query("q"),
+		Role:    domain.Role(c.Query("role")),
+		Status:  c.Query("status"),
+		Page:    c.QueryInt("page", 1),
+		PerPage: c.QueryInt("per_page", 0),
+	})
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data": page,
+	})
+}
+
+// GetUser возвращает пользователя по ID
+func (h *AuthHandler) GetUser(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	user, err := h.authUseCase.GetUser(userID)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data": user,
+	})
+}
+
+// ChangeUserRole назначает пользователю роль
+func (h *AuthHandler) ChangeUserRole(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	var req ChangeRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	adminID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.ChangeUserRole(c.UserContext(), adminID, userID, domain.Role(req.Role))
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User role changed successfully",
+		"data":    user,
+	})
+}
+
+// BanUser блокирует пользователя и завершает его сессии
+func (h *AuthHandler) BanUser(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	// Причина необязательна, поэтому пустое тело допустимо
+	var req BanUserRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	adminID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.BanUser(c.UserContext(), adminID, userID, req.Reason)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User banned successfully",
+		"data":    user,
+	})
+}
+
+// UnbanUser снимает блокировку с пользователя
+func (h *AuthHandler) UnbanUser(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	adminID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.UnbanUser(adminID, userID)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User unbanned successfully",
+		"data":    user,
+	})
+}
+
+// ForcePasswordReset требует от пользователя сбросить пароль перед следующим входом
+func (h *AuthHandler) ForcePasswordReset(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	adminID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.ForcePasswordReset(c.UserContext(), adminID, userID)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password reset required on next login",
+		"data":    user,
+	})
+}
+
+// RevokeUserSessions завершает все сессии пользователя
+func (h *AuthHandler) RevokeUserSessions(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	adminID := c.Locals("userID").(uint)
+	if err := h.authUseCase.RevokeUserSessions(c.UserContext(), adminID, userID); err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "All user sessions revoked successfully",
+	})
+}
+
+// DeleteUser мягко удаляет пользователя
+func (h *AuthHandler) DeleteUser(c *fiber.Ctx) error {
+	userID, err := userIDParam(c)
+	if err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	adminID := c.Locals("userID").(uint)
+	if err := h.authUseCase.DeleteUser(c.UserContext(), adminID, userID); err != nil {
+		return adminErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User deleted successfully",
+	})
+}
+
+// errInvalidUserID некорректный ID пользователя в пути запроса
+var errInvalidUserID = errors.New("Invalid user ID")
+
+// userIDParam читает ID пользователя из параметра :id
+func userIDParam(c *fiber.Ctx) (uint, error) {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || userID == 0 {
+		return 0, errInvalidUserID
+	}
+	return uint(userID), nil
+}
+
+// adminErrorResponse выбирает HTTP статус для ошибок административных действий
+func adminErrorResponse(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, usecase.ErrUserNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, usecase.ErrCannotModifySelf):
+		status = fiber.StatusForbidden
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
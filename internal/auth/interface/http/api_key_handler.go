@@ -0,0 +1,83 @@
+package http
+
+import (
+	"errors"
+	"nodabackend/internal/auth/usecase"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateAPIKeyRequest структура для создания API ключа
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`     // права ключа, например ["profile:read"]
+	ExpiresAt *time.Time `json:"expires_at"` // необязательный, RFC 3339
+}
+
+// GetAPIKeys возвращает API ключи текущего пользователя (без самих ключей)
+func (h *AuthHandler) GetAPIKeys(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	keys, err := h.authUseCase.GetAPIKeys(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get api keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": keys,
+	})
+}
+
+// CreateAPIKey создает API ключ. Ключ возвращается только в этом ответе.
+func (h *AuthHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	credentials, err := h.authUseCase.CreateAPIKey(userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key created. Store it now, it will not be shown again",
+		"data":    credentials,
+	})
+}
+
+// RevokeAPIKey отзывает API ключ текущего пользователя
+func (h *AuthHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	keyID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || keyID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key ID",
+		})
+	}
+
+	if err := h.authUseCase.RevokeAPIKey(userID, uint(keyID)); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, usecase.ErrAPIKeyNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/middleware"
+	"nodabackend/internal/auth/usecase"
+	"nodabackend/pkg/env"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthModeHeader заголовок, которым браузерный клиент просит выдать токены в cookie (значение "cookie")
+const AuthModeHeader = "X-Auth-Mode"
+
+// MagicLinkDeviceCookie HttpOnly cookie с устройством, запросившим ссылку входа.
+// Ссылку из письма браузер открывает без заголовка X-Device-ID, поэтому привязка переносится в cookie.
+const MagicLinkDeviceCookie = "magic_link_device"
+
+// OAuthBindingCookie HttpOnly cookie, привязывающая авторизацию у внешнего провайдера к браузеру,
+// который ее начал (см. usecase.OAuthAuthorization)
+const OAuthBindingCookie = "oauth_binding"
+
+// CookieConfig настройки режима cookie-сессий для браузерных клиентов.
+// В этом режиме токены не попадают в тело ответа и недоступны JavaScript.
+type CookieConfig struct {
+	Enabled     bool
+	Domain      string
+	Secure      bool
+	SameSite    string // Strict, Lax или None
+	RefreshPath string // refresh cookie отправляется только на маршруты аутентификации
+	// LoginRedirectURL страница приложения, на которую браузер возвращается после входа по ссылке из письма
+	LoginRedirectURL string
+}
+
+// DefaultCookieConfig возвращает настройки по умолчанию (режим выключен)
+func DefaultCookieConfig() *CookieConfig {
+	return &CookieConfig{
+		Enabled:          false,
+		Secure:           true,
+		SameSite:         fiber.CookieSameSiteLaxMode,
+		RefreshPath:      "/api/v1/auth",
+		LoginRedirectURL: "/",
+	}
+}
+
+// NewCookieConfigFromEnv читает настройки cookie-сессий из окружения
+func NewCookieConfigFromEnv() *CookieConfig {
+	config := DefaultCookieConfig()
+
+	config.Enabled = env.GetEnvOrDefault("AUTH_COOKIE_MODE", "false") == "true"
+	config.Domain = env.GetEnvOrDefault("AUTH_COOKIE_DOMAIN", config.Domain)
+	config.Secure = env.GetEnvOrDefault("AUTH_COOKIE_SECURE", "true") == "true"
+	config.RefreshPath = env.GetEnvOrDefault("AUTH_REFRESH_COOKIE_PATH", config.RefreshPath)
+	config.LoginRedirectURL = env.GetEnvOrDefault("AUTH_LOGIN_REDIRECT_URL", config.LoginRedirectURL)
+
+	switch sameSite := strings.ToLower(env.GetEnvOrDefault("AUTH_COOKIE_SAMESITE", config.SameSite)); sameSite {
+	case fiber.CookieSameSiteStrictMode, fiber.CookieSameSiteLaxMode, fiber.CookieSameSiteNoneMode:
+		config.SameSite = sameSite
+	default:
+		log.Printf("[AUTH] Unknown AUTH_COOKIE_SAMESITE %q, using %s", sameSite, config.SameSite)
+	}
+
+	// Браузеры отклоняют SameSite=None без Secure
+	if config.SameSite == fiber.CookieSameSiteNoneMode && !config.Secure {
+		log.Printf("[AUTH] SameSite=None requires Secure cookies, enabling Secure")
+		config.Secure = true
+	}
+
+	return config
+}
+
+// useCookies проверяет, нужно ли выдать токены в cookie: клиент попросил об этом заголовком
+// X-Auth-Mode: cookie или уже аутентифицирован cookie (например, при смене пароля)
+func (h *AuthHandler) useCookies(c *fiber.Ctx) bool {
+	if !h.cookies.Enabled {
+		return false
+	}
+	return c.Get(AuthModeHeader) == "cookie" || c.Locals("authMethod") == domain.AuthMethodCookie
+}
+
+// sessionResponse в режиме cookie-сессий переносит токены из ответа в HttpOnly cookie
+// и выставляет CSRF cookie. Иначе возвращает ответ без изменений.
+func (h *AuthHandler) sessionResponse(c *fiber.Ctx, authResponse *usecase.AuthResponse) (*usecase.AuthResponse, error) {
+	if authResponse == nil || authResponse.Token == "" || !h.useCookies(c) {
+		return authResponse, nil
+	}
+
+	if err := h.setSessionCookies(c, authResponse); err != nil {
+		return nil, err
+	}
+
+	withoutTokens := *authResponse
+	withoutTokens.Token = ""
+	withoutTokens.RefreshToken = ""
+	return &withoutTokens, nil
+}
+
+// sessionJSON отвечает сообщением и данными входа (см. sessionResponse).
+// Если cookie сессии выставить не удалось, клиент получает 500 и не получает токенов.
+func (h *AuthHandler) sessionJSON(c *fiber.Ctx, status int, message string, authResponse *usecase.AuthResponse) error {
+	data, err := h.sessionResponse(c, authResponse)
+	if err != nil {
+		log.Printf("[AUTH] Failed to create cookie session: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create session",
+		})
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"message": message,
+		"data":    data,
+	})
+}
+
+// setSessionCookies выставляет HttpOnly cookie с токенами и CSRF cookie.
+// CSRF токен генерируется до того, как выставлена хоть одна cookie: при ошибке
+// браузер не должен остаться с cookie сессии, но без CSRF токена.
+func (h *AuthHandler) setSessionCookies(c *fiber.Ctx, authResponse *usecase.AuthResponse) error {
+	// CSRF токен сохраняется между обновлениями, чтобы не ломать параллельные запросы клиента
+	csrfToken := c.Cookies(middleware.CSRFCookie)
+	if csrfToken == "" {
+		var err error
+		if csrfToken, err = newCSRFToken(); err != nil {
+			return fmt.Errorf("failed to generate CSRF token: %w", err)
+		}
+	}
+
+	h.setCookie(c, middleware.AccessTokenCookie, authResponse.Token, "/", authResponse.ExpiresIn, true)
+	h.setCookie(c, middleware.RefreshTokenCookie, authResponse.RefreshToken, h.cookies.RefreshPath, authResponse.RefreshExpiresIn, true)
+	h.setCookie(c, middleware.CSRFCookie, csrfToken, "/", authResponse.RefreshExpiresIn, false)
+	return nil
+}
+
+// csrfRandRead источник случайности для CSRF токенов; тесты подменяют его, чтобы проверить обработку ошибки
+var csrfRandRead = rand.Read
+
+// newCSRFToken генерирует случайный CSRF токен
+func newCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := csrfRandRead(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// clearSessionCookies удаляет cookie сессии после выхода
+func (h *AuthHandler) clearSessionCookies(c *fiber.Ctx) {
+	if !h.cookies.Enabled {
+		return
+	}
+	h.setCookie(c, middleware.AccessTokenCookie, "", "/", -1, true)
+	h.setCookie(c, middleware.RefreshTokenCookie, "", h.cookies.RefreshPath, -1, true)
+	h.setCookie(c, middleware.CSRFCookie, "", "/", -1, false)
+}
+
+// setOAuthBindingCookie выставляет или (пустое значение) удаляет cookie привязки OAuth авторизации.
+// Провайдер возвращает браузер межсайтовым переходом, с которым SameSite=Strict cookie не отправляется,
+// поэтому для нее Strict ослабляется до Lax.
+func (h *AuthHandler) setOAuthBindingCookie(c *fiber.Ctx, binding string) {
+	cookie := &fiber.Cookie{
+		Name:     OAuthBindingCookie,
+		Value:    binding,
+		Path:     h.cookies.RefreshPath,
+		Domain:   h.cookies.Domain,
+		Secure:   h.cookies.Secure,
+		HTTPOnly: true,
+		SameSite: h.cookies.SameSite,
+	}
+	if cookie.SameSite == fiber.CookieSameSiteStrictMode {
+		cookie.SameSite = fiber.CookieSameSiteLaxMode
+	}
+	if binding == "" {
+		cookie.Expires = time.Unix(0, 0)
+	}
+	c.Cookie(cookie)
+}
+
+// setCookie выставляет cookie с общими настройками. Отрицательный maxAge удаляет cookie.
+func (h *AuthHandler) setCookie(c *fiber.Ctx, name, value, path string, maxAge int64, httpOnly bool) {
+	cookie := &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.cookies.Domain,
+		MaxAge:   int(maxAge),
+		Secure:   h.cookies.Secure,
+		HTTPOnly: httpOnly,
+		SameSite: h.cookies.SameSite,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = 0
+		cookie.Expires = time.Unix(0, 0)
+	}
+	c.Cookie(cookie)
+}
@@ -1,7 +1,19 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"math"
+	"net/url"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/middleware"
 	"nodabackend/internal/auth/usecase"
+	"nodabackend/pkg/jwthelper"
+	"nodabackend/pkg/otp"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -9,26 +21,153 @@ import (
 // AuthHandler HTTP обработчики для аутентификации
 type AuthHandler struct {
 	authUseCase *usecase.AuthUseCase
+	cookies     *CookieConfig
 }
 
-// NewAuthHandler создает новый handler
-func NewAuthHandler(uc *usecase.AuthUseCase) *AuthHandler {
-	return &AuthHandler{authUseCase: uc}
+// NewAuthHandler создает новый handler. nil cookies — режим cookie-сессий выключен.
+func NewAuthHandler(uc *usecase.AuthUseCase, cookies *CookieConfig) *AuthHandler {
+	if cookies == nil {
+		cookies = DefaultCookieConfig()
+	}
+	return &AuthHandler{authUseCase: uc, cookies: cookies}
 }
 
-// LoginRequest структура для запроса входа
+// LoginRequest структура для запроса входа (по телефону или email)
 type LoginRequest struct {
 	Phone    string `json:"phone"`
+	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 // RegisterRequest структура для запроса регистрации
 type RegisterRequest struct {
 	Phone    string `json:"phone"`
+	Email    string `json:"email"` // необязательный
 	Password string `json:"password"`
 	Name     string `json:"name"`
 }
 
+// VerifyEmailRequest структура для подтверждения email
+type VerifyEmailRequest struct {
+	Code string `json:"code"`
+}
+
+// RefreshRequest структура для запроса обновления токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutRequest структура для запроса выхода
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// OTPRequest структура для запроса кода входа
+type OTPRequest struct {
+	Phone string `json:"phone"`
+}
+
+// OTPVerifyRequest структура для входа по коду
+type OTPVerifyRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+// ConfirmRegistrationRequest структура для подтверждения телефона после регистрации или входа
+type ConfirmRegistrationRequest struct {
+	Phone          string `json:"phone"`
+	Code           string `json:"code"`
+	RegistrationID string `json:"registration_id"` // из ответа /auth/register; пусто — подтверждение существующего аккаунта
+}
+
+// ForgotPasswordRequest структура для запроса кода сброса пароля (по телефону или email)
+type ForgotPasswordRequest struct {
+	Phone string `json:"phone"`
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest структура для сброса пароля по коду
+type ResetPasswordRequest struct {
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordRequest структура для смены пароля
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// TOTPConfirmRequest структура для подтверждения подключения приложения-аутентификатора
+type TOTPConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+// MFAVerifyRequest структура для второго шага входа
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"` // вместо code, если нет доступа к приложению
+}
+
+// WebAuthnRegisterRequest структура для завершения регистрации ключа доступа
+type WebAuthnRegisterRequest struct {
+	Name       string          `json:"name"`       // например "iPhone" или "YubiKey"
+	Credential json.RawMessage `json:"credential"` // результат navigator.credentials.create()
+}
+
+// WebAuthnLoginRequest структура для завершения входа по ключу доступа
+type WebAuthnLoginRequest struct {
+	SessionID  string          `json:"session_id"`
+	Credential json.RawMessage `json:"credential"` // результат navigator.credentials.get()
+}
+
+// MagicLinkRequest структура для запроса ссылки входа по email
+type MagicLinkRequest struct {
+	Email    string `json:"email"`
+	DeviceID string `json:"device_id"` // необязательный, если не передан заголовок X-Device-ID; ссылка сработает только с тем же устройством
+}
+
+// MagicLinkConsumeRequest структура для входа по ссылке из письма
+type MagicLinkConsumeRequest struct {
+	Token     string `json:"token" form:"token"`
+	DeviceID  string `json:"device_id" form:"device_id"` // если не передан заголовок X-Device-ID
+	CSRFToken string `json:"-" form:"csrf_token"`        // только для формы страницы подтверждения
+}
+
+// magicLinkPageData данные страницы подтверждения входа по ссылке
+type magicLinkPageData struct {
+	Token     string
+	DeviceID  string
+	CSRFToken string
+}
+
+// magicLinkConfirmPage страница подтверждения входа по ссылке.
+// Почтовые сканеры открывают ссылки из писем GET запросом, поэтому GET только показывает
+// кнопку, а одноразовая ссылка расходуется POST запросом формы. Форма несет устройство,
+// запросившее ссылку, и CSRF токен. html/template экранирует значения полей.
+var magicLinkConfirmPage = template.Must(template.New("magic_link_confirm").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<title>Вход в аккаунт</title>
+</head>
+<body>
+	<h2>Вход в аккаунт</h2>
+	<form method="post">
+		<input type="hidden" name="token" value="{{.Token}}">
+		<input type="hidden" name="device_id" value="{{.DeviceID}}">
+		<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+		<button type="submit">Войти</button>
+	</form>
+</body>
+</html>
+`))
+
 // Login обрабатывает вход пользователя
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req LoginRequest
@@ -38,29 +177,380 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	authResponse, err := h.authUseCase.AuthenticateUser(req.Phone, req.Password)
+	authResponse, err := h.authUseCase.AuthenticateUser(c.UserContext(), loginIdentifier(req.Phone, req.Email), req.Password, c.IP())
+	if err != nil {
+		return loginErrorResponse(c, err)
+	}
+
+	return h.loginResponse(c, authResponse)
+}
+
+// Register обрабатывает регистрацию пользователя
+func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	var req RegisterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	authResponse, err := h.authUseCase.RegisterUser(c.UserContext(), req.Phone, req.Email, req.Password, req.Name)
+	if err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, err)
+	}
+
+	if authResponse.VerificationRequired {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Verification code sent, confirm registration to continue",
+			"data":    authResponse,
+		})
+	}
+
+	return h.sessionJSON(c, fiber.StatusCreated, "User registered successfully", authResponse)
+}
+
+// ConfirmRegistration подтверждает телефон кодом из SMS
+func (h *AuthHandler) ConfirmRegistration(c *fiber.Ctx) error {
+	var req ConfirmRegistrationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	authResponse, err := h.authUseCase.ConfirmRegistration(c.UserContext(), req.Phone, req.Code, req.RegistrationID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserBanned) {
+			return loginErrorResponse(c, err)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if authResponse.MFARequired {
+		return h.loginResponse(c, authResponse)
+	}
+
+	return h.sessionJSON(c, fiber.StatusOK, "Phone number verified successfully", authResponse)
+}
+
+// Refresh обменивает refresh токен на новую пару токенов.
+// В режиме cookie-сессий токен можно не передавать в теле: он берется из cookie.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if req.RefreshToken == "" && h.useCookies(c) {
+		req.RefreshToken = c.Cookies(middleware.RefreshTokenCookie)
+	}
+
+	authResponse, err := h.authUseCase.RefreshTokens(c.UserContext(), req.RefreshToken)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	return h.sessionJSON(c, fiber.StatusOK, "Tokens refreshed successfully", authResponse)
+}
+
+// Logout завершает текущую сессию
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req LogoutRequest
+	// refresh_token необязателен, поэтому пустое тело допустимо
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	claims := c.Locals("claims").(*jwthelper.Claims)
+	if err := h.authUseCase.Logout(c.UserContext(), claims, req.RefreshToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.clearSessionCookies(c)
+
 	return c.JSON(fiber.Map{
-		"message": "Login successful",
-		"data":    authResponse,
+		"message": "Logged out successfully",
 	})
 }
 
-// Register обрабатывает регистрацию пользователя
-func (h *AuthHandler) Register(c *fiber.Ctx) error {
-	var req RegisterRequest
+// LogoutAll завершает все сессии пользователя
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*jwthelper.Claims)
+	if err := h.authUseCase.LogoutAll(c.UserContext(), claims); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.clearSessionCookies(c)
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out from all devices",
+	})
+}
+
+// GetSessions возвращает активные сессии пользователя
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	claims := c.Locals("claims").(*jwthelper.Claims)
+	sessions, err := h.authUseCase.GetSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": sessions,
+	})
+}
+
+// RevokeSession завершает сессию на другом устройстве
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	if err := h.authUseCase.RevokeSession(c.UserContext(), userID, c.Params("id")); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RequestOTP отправляет код входа по SMS
+func (h *AuthHandler) RequestOTP(c *fiber.Ctx) error {
+	var req OTPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.authUseCase.RequestLoginOTP(c.UserContext(), req.Phone); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If the phone number is valid, a code has been sent",
+	})
+}
+
+// VerifyOTP выполняет вход по коду из SMS
+func (h *AuthHandler) VerifyOTP(c *fiber.Ctx) error {
+	var req OTPVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	authResponse, err := h.authUseCase.VerifyLoginOTP(c.UserContext(), req.Phone, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return h.loginResponse(c, authResponse)
+}
+
+// ForgotPassword отправляет код для сброса пароля
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.authUseCase.ForgotPassword(c.UserContext(), loginIdentifier(req.Phone, req.Email)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If an account exists, a reset code has been sent",
+	})
+}
+
+// ResetPassword устанавливает новый пароль по коду
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.authUseCase.ResetPassword(c.UserContext(), loginIdentifier(req.Phone, req.Email), req.Code, req.NewPassword); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password has been reset, please log in again",
+	})
+}
+
+// ChangePassword меняет пароль текущего пользователя
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	authResponse, err := h.authUseCase.ChangePassword(c.UserContext(), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return h.sessionJSON(c, fiber.StatusOK, "Password changed successfully", authResponse)
+}
+
+// VerifyEmail подтверждает email текущего пользователя кодом из письма
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.VerifyEmail(c.UserContext(), userID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified successfully",
+		"data":    user,
+	})
+}
+
+// SetupTOTP генерирует секрет для приложения-аутентификатора
+func (h *AuthHandler) SetupTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	setup, err := h.authUseCase.SetupTOTP(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Scan the QR code and confirm with a code from the app",
+		"data":    setup,
+	})
+}
+
+// ConfirmTOTP включает 2FA кодом из приложения-аутентификатора
+func (h *AuthHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	var req TOTPConfirmRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	confirmation, err := h.authUseCase.ConfirmTOTP(userID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication enabled, store the recovery codes in a safe place",
+		"data":    confirmation,
+	})
+}
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	codes, err := h.authUseCase.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Recovery codes regenerated, previous codes are no longer valid",
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// VerifyMFA завершает вход кодом второго фактора
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var req MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	authResponse, err := h.authUseCase.VerifyMFA(c.UserContext(), req.MFAToken, req.Code, req.RecoveryCode)
+	if err != nil {
+		return loginErrorResponse(c, err)
+	}
+
+	return h.sessionJSON(c, fiber.StatusOK, "Login successful", authResponse)
+}
+
+// BeginWebAuthnRegistration возвращает параметры для создания ключа доступа
+func (h *AuthHandler) BeginWebAuthnRegistration(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	options, err := h.authUseCase.BeginWebAuthnRegistration(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": options,
+	})
+}
+
+// FinishWebAuthnRegistration сохраняет созданный ключ доступа
+func (h *AuthHandler) FinishWebAuthnRegistration(c *fiber.Ctx) error {
+	var req WebAuthnRegisterRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
 
-	authResponse, err := h.authUseCase.RegisterUser(req.Phone, req.Password, req.Name)
+	userID := c.Locals("userID").(uint)
+	credential, err := h.authUseCase.FinishWebAuthnRegistration(c.UserContext(), userID, req.Name, req.Credential)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -68,16 +558,308 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "User registered successfully",
-		"data":    authResponse,
+		"message": "Passkey registered successfully",
+		"data":    credential,
+	})
+}
+
+// BeginWebAuthnLogin возвращает параметры для входа по ключу доступа
+func (h *AuthHandler) BeginWebAuthnLogin(c *fiber.Ctx) error {
+	challenge, err := h.authUseCase.BeginWebAuthnLogin(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": challenge,
+	})
+}
+
+// FinishWebAuthnLogin выполняет вход по ключу доступа
+func (h *AuthHandler) FinishWebAuthnLogin(c *fiber.Ctx) error {
+	var req WebAuthnLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	authResponse, err := h.authUseCase.FinishWebAuthnLogin(c.UserContext(), req.SessionID, req.Credential)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return h.sessionJSON(c, fiber.StatusOK, "Login successful", authResponse)
+}
+
+// OAuthAuthorize перенаправляет на страницу авторизации провайдера
+func (h *AuthHandler) OAuthAuthorize(c *fiber.Ctx) error {
+	authorization, err := h.authUseCase.BeginOAuthLogin(c.UserContext(), c.Params("provider"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.setOAuthBindingCookie(c, authorization.Binding)
+	return c.Redirect(authorization.URL, fiber.StatusFound)
+}
+
+// OAuthLink возвращает адрес авторизации для привязки аккаунта провайдера.
+// Адрес отдается в JSON: запрос идет с Bearer токеном, поэтому редирект браузера не подходит.
+func (h *AuthHandler) OAuthLink(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	authorization, err := h.authUseCase.BeginOAuthLink(c.UserContext(), userID, c.Params("provider"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Cookie выставляется в ответ на запрос клиента, поэтому переход к провайдеру
+	// должен начаться в том же браузере
+	h.setOAuthBindingCookie(c, authorization.Binding)
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"authorization_url": authorization.URL,
+		},
+	})
+}
+
+// OAuthCallback обрабатывает возврат от провайдера: вход или привязку аккаунта
+func (h *AuthHandler) OAuthCallback(c *fiber.Ctx) error {
+	if providerError := c.Query("error"); providerError != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "OAuth authorization was denied: " + providerError,
+		})
+	}
+
+	// Cookie одноразовая, как и state
+	binding := c.Cookies(OAuthBindingCookie)
+	if binding != "" {
+		h.setOAuthBindingCookie(c, "")
+	}
+
+	result, err := h.authUseCase.HandleOAuthCallback(c.UserContext(), c.Params("provider"), c.Query("code"), c.Query("state"), binding)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if result.LinkedIdentity != nil {
+		return c.JSON(fiber.Map{
+			"message": "Account linked successfully",
+			"data":    result.LinkedIdentity,
+		})
+	}
+
+	return h.loginResponse(c, result.AuthResponse)
+}
+
+// GetLinkedIdentities возвращает привязанные аккаунты провайдеров
+func (h *AuthHandler) GetLinkedIdentities(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	identities, err := h.authUseCase.GetLinkedIdentities(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": identities,
+	})
+}
+
+// OAuthUnlink отвязывает аккаунт провайдера
+func (h *AuthHandler) OAuthUnlink(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	if err := h.authUseCase.UnlinkOAuthIdentity(userID, c.Params("provider")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account unlinked successfully",
+	})
+}
+
+// RequestMagicLink отправляет ссылку для входа на email
+func (h *AuthHandler) RequestMagicLink(c *fiber.Ctx) error {
+	var req MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	deviceID := c.Get("X-Device-ID")
+	if deviceID == "" {
+		deviceID = req.DeviceID
+	}
+
+	if err := h.authUseCase.RequestMagicLink(c.UserContext(), req.Email, c.IP(), deviceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Ссылку из письма браузер откроет без заголовка X-Device-ID, устройство возьмется из cookie
+	if deviceID != "" {
+		h.setCookie(c, MagicLinkDeviceCookie, deviceID, h.cookies.RefreshPath, 0, true)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "If the account exists, a sign-in link has been sent",
+	})
+}
+
+// MagicLinkConfirmPage показывает страницу с кнопкой входа по ссылке из письма.
+// Сама ссылка при этом не расходуется.
+func (h *AuthHandler) MagicLinkConfirmPage(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	deviceID := c.Get("X-Device-ID")
+	if deviceID == "" {
+		deviceID = c.Cookies(MagicLinkDeviceCookie)
+	}
+
+	// Форма отправляется без заголовков, поэтому CSRF токен передается ее полем
+	csrfToken := c.Cookies(middleware.CSRFCookie)
+	if csrfToken == "" {
+		var err error
+		if csrfToken, err = newCSRFToken(); err != nil {
+			log.Printf("[AUTH] Failed to generate CSRF token: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+		h.setCookie(c, middleware.CSRFCookie, csrfToken, "/", 0, false)
+	}
+
+	// Токен в адресе страницы не должен попадать в кеш и в Referer
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return magicLinkConfirmPage.Execute(c, magicLinkPageData{
+		Token:     token,
+		DeviceID:  deviceID,
+		CSRFToken: csrfToken,
+	})
+}
+
+// ConsumeMagicLink выполняет вход по ссылке из письма. JSON запрос получает обычный ответ входа,
+// форма страницы подтверждения — перенаправление на LoginRedirectURL.
+func (h *AuthHandler) ConsumeMagicLink(c *fiber.Ctx) error {
+	var req MagicLinkConsumeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	fromForm := strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationForm)
+	// Форма проверяется и без cookie сессии: иначе чужой сайт мог бы войти в браузере жертвы в свой аккаунт
+	if fromForm && !middleware.CSRFTokenMatches(c, req.CSRFToken) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Invalid CSRF token",
+			"code":  "csrf_token_invalid",
+		})
+	}
+
+	deviceID := c.Get("X-Device-ID")
+	if deviceID == "" {
+		deviceID = req.DeviceID
+	}
+	if deviceID == "" {
+		deviceID = c.Cookies(MagicLinkDeviceCookie)
+	}
+	if c.Cookies(MagicLinkDeviceCookie) != "" {
+		h.setCookie(c, MagicLinkDeviceCookie, "", h.cookies.RefreshPath, -1, true)
+	}
+
+	authResponse, err := h.authUseCase.ConsumeMagicLink(c.UserContext(), req.Token, c.IP(), deviceID)
+	if fromForm {
+		return h.magicLinkRedirect(c, authResponse, err)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return h.loginResponse(c, authResponse)
+}
+
+// magicLinkRedirect завершает вход из формы перенаправлением браузера в приложение.
+// В режиме cookie-сессий токены выставляются в cookie, иначе передаются во фрагменте адреса,
+// который браузер не отправляет на сервер. Ошибка и MFA токен тоже передаются фрагментом.
+func (h *AuthHandler) magicLinkRedirect(c *fiber.Ctx, authResponse *usecase.AuthResponse, err error) error {
+	fragment := url.Values{}
+	switch {
+	case err != nil:
+		fragment.Set("error", err.Error())
+	case authResponse.MFARequired:
+		fragment.Set("mfa_token", authResponse.MFAToken)
+	case h.cookies.Enabled:
+		if err := h.setSessionCookies(c, authResponse); err != nil {
+			log.Printf("[AUTH] Failed to create browser session: %v", err)
+			fragment.Set("error", "failed to create session")
+		}
+	default:
+		fragment.Set("access_token", authResponse.Token)
+		fragment.Set("refresh_token", authResponse.RefreshToken)
+		fragment.Set("expires_in", strconv.FormatInt(authResponse.ExpiresIn, 10))
+		fragment.Set("refresh_expires_in", strconv.FormatInt(authResponse.RefreshExpiresIn, 10))
+	}
+
+	target := h.cookies.LoginRedirectURL
+	if len(fragment) > 0 {
+		target += "#" + fragment.Encode()
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(target, fiber.StatusSeeOther)
+}
+
+// UnlockUser снимает блокировку входа с пользователя (только для администратора)
+func (h *AuthHandler) UnlockUser(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	user, err := h.authUseCase.UnlockUser(c.UserContext(), uint(userID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User unlocked successfully",
+		"data":    user,
 	})
 }
 
 // Me возвращает информацию о текущем пользователе
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
-	
-	user, err := h.authUseCase.GetUserByID(userID)
+
+	profile, err := h.authUseCase.GetProfile(userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": err.Error(),
@@ -85,6 +867,109 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"data": user,
+		"data": profile,
+	})
+}
+
+// loginResponse отвечает на успешную проверку первого фактора:
+// токенами или требованием пройти второй шаг входа
+func (h *AuthHandler) loginResponse(c *fiber.Ctx, authResponse *usecase.AuthResponse) error {
+	if authResponse.MFARequired {
+		return c.JSON(fiber.Map{
+			"message": "Two-factor authentication required",
+			"data":    authResponse,
+		})
+	}
+
+	return h.sessionJSON(c, fiber.StatusOK, "Login successful", authResponse)
+}
+
+// loginErrorResponse отвечает на неудачный вход по паролю.
+// Блокировка, бан, требование сбросить пароль или подтвердить телефон
+// и неверные учетные данные различаются по полю code.
+func loginErrorResponse(c *fiber.Ctx, err error) error {
+	var lockedErr *usecase.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		retryAfter := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       err.Error(),
+			"code":        "account_locked",
+			"retry_after": retryAfter,
+		})
+	}
+
+	if errors.Is(err, usecase.ErrInvalidCredentials) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "invalid_credentials",
+		})
+	}
+
+	if errors.Is(err, usecase.ErrUserBanned) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "account_banned",
+		})
+	}
+
+	if errors.Is(err, usecase.ErrPasswordResetRequired) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "password_reset_required",
+		})
+	}
+
+	if errors.Is(err, usecase.ErrPhoneVerificationRequired) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+			"code":  "phone_verification_required",
+		})
+	}
+
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// withClientInfo передает в usecase данные клиента для записи сессии
+func withClientInfo(c *fiber.Ctx) error {
+	c.SetUserContext(usecase.WithClientInfo(c.UserContext(), usecase.ClientInfo{
+		IP:         c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		DeviceName: c.Get("X-Device-Name"),
+	}))
+	return c.Next()
+}
+
+// loginIdentifier выбирает идентификатор для входа: телефон, иначе email
+func loginIdentifier(phone, email string) string {
+	if phone != "" {
+		return phone
+	}
+	return email
+}
+
+// errorResponse отвечает ошибкой с переданным статусом.
+// Превышение лимита отправки кодов отдается как 429 с retry_after в секундах.
+func errorResponse(c *fiber.Ctx, status int, err error) error {
+	var rateErr *otp.RateLimitError
+	if errors.As(err, &rateErr) {
+		retryAfter := int(math.Ceil(rateErr.RetryAfter.Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       err.Error(),
+			"reason":      rateErr.Reason,
+			"retry_after": retryAfter,
+		})
+	}
+
+	// Телефон или email занят — конфликт, а не ошибка запроса
+	if errors.Is(err, domain.ErrPhoneTaken) || errors.Is(err, domain.ErrEmailTaken) {
+		status = fiber.StatusConflict
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
 	})
 }
@@ -0,0 +1,345 @@
+package http
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/middleware"
+	"nodabackend/internal/auth/repository"
+	"nodabackend/internal/auth/usecase"
+	"nodabackend/pkg/jwthelper"
+	"nodabackend/pkg/mailer"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const magicLinkTestEmail = "user@example.com"
+
+// magicLinkUsers хранит одного пользователя с подтвержденным email
+type magicLinkUsers struct {
+	domain.UserRepository
+	user domain.User
+}
+
+func (r *magicLinkUsers) GetUserByEmail(email string) (*domain.User, error) {
+	if r.user.Email == nil || *r.user.Email != email {
+		return nil, errors.New("user not found")
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (r *magicLinkUsers) GetUserByID(id uint) (*domain.User, error) {
+	if r.user.ID != id {
+		return nil, errors.New("user not found")
+	}
+	user := r.user
+	return &user, nil
+}
+
+type magicLinkSessions struct{ domain.SessionRepository }
+
+func (magicLinkSessions) CreateSession(*domain.Session) error { return nil }
+
+type magicLinkRefreshTokens struct{ domain.RefreshTokenRepository }
+
+func (magicLinkRefreshTokens) CreateRefreshToken(*domain.RefreshToken) error { return nil }
+
+// captureMailer запоминает последнее отправленное письмо
+type captureMailer struct {
+	last *mailer.EmailMessage
+}
+
+func (m *captureMailer) SendEmail(msg *mailer.EmailMessage) error {
+	m.last = msg
+	return nil
+}
+
+// cookieJar переносит cookie между запросами теста, как браузер
+type cookieJar map[string]string
+
+func (j cookieJar) update(resp *http.Response) {
+	for _, cookie := range resp.Cookies() {
+		if cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(j, cookie.Name)
+			continue
+		}
+		j[cookie.Name] = cookie.Value
+	}
+}
+
+func (j cookieJar) apply(req *http.Request) {
+	for name, value := range j {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+type magicLinkTestEnv struct {
+	app    *fiber.App
+	mailer *captureMailer
+	jar    cookieJar
+}
+
+func newMagicLinkTestEnv(t *testing.T, cookies *CookieConfig) *magicLinkTestEnv {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	keyring, err := jwthelper.NewKeyring(jwthelper.NewHMACKey([]byte("handler-test-secret-0123456789abcdef")))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	jwtHelper, err := jwthelper.NewJWTHelper(keyring, nil)
+	if err != nil {
+		t.Fatalf("NewJWTHelper: %v", err)
+	}
+
+	email := magicLinkTestEmail
+	verifiedAt := time.Now()
+	users := &magicLinkUsers{user: domain.User{
+		ID:              1,
+		Phone:           "+79001234567",
+		Email:           &email,
+		EmailVerifiedAt: &verifiedAt,
+		PhoneVerifiedAt: &verifiedAt,
+		Role:            domain.UserRole,
+	}}
+
+	config := usecase.DefaultConfig()
+	config.MagicLinkURL = "https://example.com/api/v1/auth/magic-link/consume"
+
+	mail := &captureMailer{}
+	uc := usecase.NewAuthUseCase(usecase.Dependencies{
+		UserRepo:         users,
+		SessionRepo:      magicLinkSessions{},
+		RefreshTokenRepo: magicLinkRefreshTokens{},
+		MagicLinks:       repository.NewMagicLinkStore(client),
+		JWTHelper:        jwtHelper,
+		Mailer:           mail,
+	}, config)
+	handler := NewAuthHandler(uc, cookies)
+
+	app := fiber.New()
+	api := app.Group("/api/v1")
+	api.Use(middleware.CSRFProtection)
+	auth := api.Group("/auth", withClientInfo)
+	auth.Post("/magic-link", handler.RequestMagicLink)
+	auth.Get("/magic-link/consume", handler.MagicLinkConfirmPage)
+	auth.Post("/magic-link/consume", handler.ConsumeMagicLink)
+
+	return &magicLinkTestEnv{app: app, mailer: mail, jar: cookieJar{}}
+}
+
+// do выполняет запрос с cookie браузера и сохраняет выставленные cookie
+func (e *magicLinkTestEnv) do(t *testing.T, req *http.Request) (*http.Response, string) {
+	t.Helper()
+
+	e.jar.apply(req)
+	resp, err := e.app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	e.jar.update(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp, string(body)
+}
+
+// requestLink запрашивает ссылку и возвращает путь с токеном из письма
+func (e *magicLinkTestEnv) requestLink(t *testing.T, deviceID string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/magic-link", strings.NewReader(`{"email":"`+magicLinkTestEmail+`"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if deviceID != "" {
+		req.Header.Set("X-Device-ID", deviceID)
+	}
+	if resp, body := e.do(t, req); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("request link: expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	if e.mailer.last == nil {
+		t.Fatal("expected a magic link email")
+	}
+	match := regexp.MustCompile(`href="([^"]+)"`).FindStringSubmatch(e.mailer.last.Body)
+	if match == nil {
+		t.Fatalf("no link in email: %s", e.mailer.last.Body)
+	}
+	link, err := url.Parse(strings.ReplaceAll(match[1], "&amp;", "&"))
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return link.RequestURI()
+}
+
+// openPage открывает страницу подтверждения и возвращает поля ее формы
+func (e *magicLinkTestEnv) openPage(t *testing.T, path string) url.Values {
+	t.Helper()
+
+	resp, body := e.do(t, httptest.NewRequest(fiber.MethodGet, path, nil))
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("confirm page: expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	form := url.Values{}
+	for _, field := range regexp.MustCompile(`name="(\w+)" value="([^"]*)"`).FindAllStringSubmatch(body, -1) {
+		form.Set(field[1], field[2])
+	}
+	return form
+}
+
+// submit отправляет форму страницы подтверждения
+func (e *magicLinkTestEnv) submit(t *testing.T, form url.Values) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/magic-link/consume", strings.NewReader(form.Encode()))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	resp, _ := e.do(t, req)
+	return resp
+}
+
+func TestMagicLink_BrowserFlowSetsSessionCookies(t *testing.T) {
+	cookies := DefaultCookieConfig()
+	cookies.Enabled = true
+	cookies.LoginRedirectURL = "/app"
+	env := newMagicLinkTestEnv(t, cookies)
+
+	path := env.requestLink(t, "device-1")
+	if env.jar[MagicLinkDeviceCookie] != "device-1" {
+		t.Fatalf("expected device cookie, got %v", env.jar)
+	}
+
+	form := env.openPage(t, path)
+	if form.Get("device_id") != "device-1" {
+		t.Fatalf("expected device id in the page, got %q", form.Get("device_id"))
+	}
+	if form.Get("csrf_token") == "" || form.Get("csrf_token") != env.jar[middleware.CSRFCookie] {
+		t.Fatalf("expected page CSRF token to match the cookie, got %q and %q", form.Get("csrf_token"), env.jar[middleware.CSRFCookie])
+	}
+
+	resp := env.submit(t, form)
+	if resp.StatusCode != fiber.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", resp.StatusCode)
+	}
+	if location := resp.Header.Get(fiber.HeaderLocation); location != "/app" {
+		t.Fatalf("expected redirect without tokens, got %q", location)
+	}
+	if env.jar[middleware.AccessTokenCookie] == "" || env.jar[middleware.RefreshTokenCookie] == "" {
+		t.Fatalf("expected session cookies, got %v", env.jar)
+	}
+	if _, ok := env.jar[MagicLinkDeviceCookie]; ok {
+		t.Fatal("expected device cookie to be cleared")
+	}
+
+	// Повторная отправка идет уже с cookie сессии и проходит CSRF проверку полем формы
+	resp = env.submit(t, form)
+	if resp.StatusCode != fiber.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", resp.StatusCode)
+	}
+	if location := resp.Header.Get(fiber.HeaderLocation); !strings.Contains(location, "#error=") {
+		t.Fatalf("expected reused link to fail, got %q", location)
+	}
+}
+
+func TestMagicLink_BrowserFlowWithoutCookieMode(t *testing.T) {
+	env := newMagicLinkTestEnv(t, nil)
+
+	resp := env.submit(t, env.openPage(t, env.requestLink(t, "")))
+	if resp.StatusCode != fiber.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get(fiber.HeaderLocation))
+	if err != nil {
+		t.Fatalf("parse location: %v", err)
+	}
+	fragment, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		t.Fatalf("parse fragment: %v", err)
+	}
+	if location.Path != "/" || fragment.Get("access_token") == "" || fragment.Get("refresh_token") == "" {
+		t.Fatalf("expected tokens in the fragment, got %q", location)
+	}
+	if _, ok := env.jar[middleware.AccessTokenCookie]; ok {
+		t.Fatal("expected no session cookies when cookie mode is disabled")
+	}
+}
+
+func TestMagicLink_FormRequiresCSRFToken(t *testing.T) {
+	env := newMagicLinkTestEnv(t, nil)
+
+	form := env.openPage(t, env.requestLink(t, ""))
+	form.Set("csrf_token", "forged")
+	if resp := env.submit(t, form); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+
+	// Отклоненная форма не расходует ссылку
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/magic-link/consume", strings.NewReader(`{"token":"`+form.Get("token")+`"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if resp, body := env.do(t, req); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected JSON consume to succeed, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestMagicLink_FormFromOtherDeviceFails(t *testing.T) {
+	env := newMagicLinkTestEnv(t, nil)
+
+	path := env.requestLink(t, "device-1")
+	// Ссылку открывают в другом браузере: cookie устройства там нет
+	delete(env.jar, MagicLinkDeviceCookie)
+
+	form := env.openPage(t, path)
+	if form.Get("device_id") != "" {
+		t.Fatalf("expected no device id, got %q", form.Get("device_id"))
+	}
+
+	resp := env.submit(t, form)
+	if location := resp.Header.Get(fiber.HeaderLocation); !strings.Contains(location, "#error=") {
+		t.Fatalf("expected device mismatch error, got %d %q", resp.StatusCode, location)
+	}
+}
+
+func TestSessionResponse_CSRFFailureSetsNoCookies(t *testing.T) {
+	cookies := DefaultCookieConfig()
+	cookies.Enabled = true
+	env := newMagicLinkTestEnv(t, cookies)
+	path := env.requestLink(t, "")
+
+	csrfRandRead = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+	t.Cleanup(func() { csrfRandRead = rand.Read })
+
+	token, err := url.Parse(path)
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/magic-link/consume", strings.NewReader(`{"token":"`+token.Query().Get("token")+`"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(AuthModeHeader, "cookie")
+
+	resp, body := env.do(t, req)
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", resp.StatusCode, body)
+	}
+	if len(env.jar) != 0 {
+		t.Fatalf("expected no cookies, got %v", env.jar)
+	}
+	if strings.Contains(body, "token") {
+		t.Fatalf("expected no tokens in the body, got %s", body)
+	}
+}
@@ -0,0 +1,17 @@
+package http
+
+import (
+	"nodabackend/pkg/jwthelper"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKSHandler отдает открытые ключи подписи access токенов (/.well-known/jwks.json),
+// чтобы другие сервисы могли проверять токены без общего секрета
+func JWKSHandler(jwtHelper *jwthelper.JWTHelper) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Клиенты кешируют ключи; после ротации старый ключ остается в наборе дольше этого времени
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(jwtHelper.JWKS())
+	}
+}
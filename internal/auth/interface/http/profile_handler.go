@@ -0,0 +1,123 @@
+package http
+
+import (
+	"nodabackend/internal/auth/usecase"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UpdateProfileRequest структура для частичного обновления профиля; отсутствующие поля не меняются
+type UpdateProfileRequest struct {
+	Name      *string `json:"name"`
+	AvatarURL *string `json:"avatar_url"`
+	Locale    *string `json:"locale"`
+	Timezone  *string `json:"timezone"`
+}
+
+// ChangePhoneRequest структура для запроса смены телефона
+type ChangePhoneRequest struct {
+	Phone string `json:"phone"`
+}
+
+// ConfirmPhoneChangeRequest структура для подтверждения нового телефона кодом из SMS
+type ConfirmPhoneChangeRequest struct {
+	Code string `json:"code"`
+}
+
+// DeleteAccountRequest структура для удаления аккаунта
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// UpdateProfile обновляет имя, аватар, язык и часовой пояс текущего пользователя
+func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
+	var req UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.UpdateProfile(userID, usecase.ProfileUpdate{
+		Name:      req.Name,
+		AvatarURL: req.AvatarURL,
+		Locale:    req.Locale,
+		Timezone:  req.Timezone,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// RequestPhoneChange отправляет код подтверждения на новый телефон
+func (h *AuthHandler) RequestPhoneChange(c *fiber.Ctx) error {
+	var req ChangePhoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	if err := h.authUseCase.RequestPhoneChange(c.UserContext(), userID, req.Phone); err != nil {
+		return errorResponse(c, fiber.StatusBadRequest, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Verification code sent to the new phone",
+	})
+}
+
+// ConfirmPhoneChange меняет телефон после проверки кода
+func (h *AuthHandler) ConfirmPhoneChange(c *fiber.Ctx) error {
+	var req ConfirmPhoneChangeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	user, err := h.authUseCase.ConfirmPhoneChange(c.UserContext(), userID, req.Code)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Phone changed successfully",
+		"data":    user,
+	})
+}
+
+// DeleteAccount удаляет аккаунт текущего пользователя
+func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
+	var req DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := c.Locals("userID").(uint)
+	deletion, err := h.authUseCase.DeleteAccount(c.UserContext(), userID, req.Password)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account deleted successfully",
+		"data":    deletion,
+	})
+}
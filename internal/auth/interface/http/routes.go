@@ -1,32 +1,152 @@
 package http
 
 import (
+	"nodabackend/internal/auth/domain"
 	"nodabackend/internal/auth/middleware"
+	"nodabackend/internal/auth/rbac"
 	"nodabackend/internal/auth/repository"
 	"nodabackend/internal/auth/usecase"
+	"nodabackend/pkg/encryption"
 	"nodabackend/pkg/jwthelper"
 	"nodabackend/pkg/mailer"
+	"nodabackend/pkg/otp"
+	"nodabackend/pkg/sms"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+// Dependencies внешние зависимости модуля аутентификации
+type Dependencies struct {
+	DB             *gorm.DB
+	Redis          *redis.Client
+	JWTHelper      *jwthelper.JWTHelper
+	Mailer         mailer.Mailer
+	OTPService     otp.OTPService
+	SMSService     sms.SMSService
+	Encryptor      *encryption.Encryptor
+	OAuthProviders []domain.OAuthProvider
+}
+
 // RegisterRoutes настраивает маршруты аутентификации и возвращает auth middleware
-func RegisterRoutes(api fiber.Router, db *gorm.DB, jwtHelper *jwthelper.JWTHelper) *middleware.AuthMiddleware {
-	authRepo := repository.NewUserRepository(db)
-	mailer := mailer.NewSMTPMailerFromEnv()
-	authUseCase := usecase.NewAuthUseCase(authRepo, jwtHelper, mailer)
-	authHandler := NewAuthHandler(authUseCase)
-	authMiddleware := middleware.NewAuthMiddleware(jwtHelper)
-
-	auth := api.Group("/auth")
+func RegisterRoutes(api fiber.Router, deps Dependencies) *middleware.AuthMiddleware {
+	authRepo := repository.NewUserRepository(deps.DB)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(deps.DB)
+	revocationStore := repository.NewTokenRevocationStore(deps.Redis)
+	sessionRepo := repository.NewSessionRepository(deps.DB)
+	roleRepo := repository.NewRoleRepository(deps.DB)
+	serviceClientRepo := repository.NewServiceClientRepository(deps.DB)
+	apiKeyRepo := repository.NewAPIKeyRepository(deps.DB)
+	authUseCase := usecase.NewAuthUseCase(usecase.Dependencies{
+		UserRepo:           authRepo,
+		RefreshTokenRepo:   refreshTokenRepo,
+		SessionRepo:        sessionRepo,
+		RoleRepo:           roleRepo,
+		RecoveryCodeRepo:   repository.NewRecoveryCodeRepository(deps.DB),
+		RevocationStore:    revocationStore,
+		WebAuthnRepo:       repository.NewWebAuthnCredentialRepository(deps.DB),
+		WebAuthnSessions:   repository.NewWebAuthnSessionStore(deps.Redis),
+		LinkedIdentityRepo: repository.NewLinkedIdentityRepository(deps.DB),
+		OAuthStates:        repository.NewOAuthStateStore(deps.Redis),
+		OAuthProviders:     deps.OAuthProviders,
+		MagicLinks:         repository.NewMagicLinkStore(deps.Redis),
+		LoginAttempts:      repository.NewLoginAttemptStore(deps.Redis),
+		Registrations:      repository.NewPendingRegistrationStore(deps.Redis),
+		ServiceClientRepo:  serviceClientRepo,
+		APIKeyRepo:         apiKeyRepo,
+		JWTHelper:          deps.JWTHelper,
+		Mailer:             deps.Mailer,
+		OTPService:         deps.OTPService,
+		SMSService:         deps.SMSService,
+		Encryptor:          deps.Encryptor,
+	}, usecase.NewConfigFromEnv())
+	authHandler := NewAuthHandler(authUseCase, NewCookieConfigFromEnv())
+
+	// Удаленные аккаунты обезличиваются после периода ожидания
+	authUseCase.StartDeletedUserPurge()
+	authorizer := rbac.NewAuthorizer(roleRepo, rbac.DefaultCacheTTL)
+	authMiddleware := middleware.NewAuthMiddleware(deps.JWTHelper, revocationStore, authRepo, sessionRepo, serviceClientRepo, apiKeyRepo, authorizer)
+
+	// Запросы браузера, аутентифицированные cookie, защищены от CSRF во всем API
+	api.Use(middleware.CSRFProtection)
+
+	// Действия с самой учетной записью недоступны по API ключу, только по токену входа
+	sessionOnly := authMiddleware.RequireSessionToken
+
+	// Данные клиента (IP, User-Agent, устройство) сохраняются в сессии при входе
+	auth := api.Group("/auth", withClientInfo)
 	auth.Post("/login", authHandler.Login)
 	auth.Post("/register", authHandler.Register)
+	auth.Post("/register/confirm", authHandler.ConfirmRegistration)
+	auth.Post("/refresh", authHandler.Refresh)
+
+	// Токены для внутренних сервисов (client credentials) и проверка токенов (RFC 7662)
+	auth.Post("/token", authHandler.ServiceToken)
+	auth.Post("/introspect", authHandler.Introspect)
+
+	// Вход без пароля по коду из SMS
+	auth.Post("/otp/request", authHandler.RequestOTP)
+	auth.Post("/otp/verify", authHandler.VerifyOTP)
+
+	// Вход без пароля по ссылке из письма: GET показывает страницу подтверждения,
+	// ссылка расходуется только POST запросом (почтовые сканеры переходят по ссылкам GET)
+	auth.Post("/magic-link", authHandler.RequestMagicLink)
+	auth.Get("/magic-link/consume", authHandler.MagicLinkConfirmPage)
+	auth.Post("/magic-link/consume", authHandler.ConsumeMagicLink)
+
+	// Двухфакторная аутентификация через приложение (TOTP)
+	auth.Post("/2fa/totp/setup", authMiddleware.RequireAuth, sessionOnly, authHandler.SetupTOTP)
+	auth.Post("/2fa/totp/confirm", authMiddleware.RequireAuth, sessionOnly, authHandler.ConfirmTOTP)
+	auth.Post("/2fa/recovery-codes", authMiddleware.RequireAuth, sessionOnly, authHandler.RegenerateRecoveryCodes)
+	auth.Post("/2fa/verify", authHandler.VerifyMFA)
+
+	// Вход по ключам доступа (WebAuthn / passkeys)
+	passkeys := auth.Group("/webauthn")
+	passkeys.Post("/register/begin", authMiddleware.RequireAuth, sessionOnly, authHandler.BeginWebAuthnRegistration)
+	passkeys.Post("/register/finish", authMiddleware.RequireAuth, sessionOnly, authHandler.FinishWebAuthnRegistration)
+	passkeys.Post("/login/begin", authHandler.BeginWebAuthnLogin)
+	passkeys.Post("/login/finish", authHandler.FinishWebAuthnLogin)
+
+	// Вход через внешних провайдеров (OAuth2 / OpenID Connect) и привязка аккаунтов
+	social := auth.Group("/oauth")
+	social.Get("/identities", authMiddleware.RequireAuth, sessionOnly, authHandler.GetLinkedIdentities)
+	social.Get("/:provider/authorize", authHandler.OAuthAuthorize)
+	social.Post("/:provider/link", authMiddleware.RequireAuth, sessionOnly, authHandler.OAuthLink)
+	social.Get("/:provider/callback", authHandler.OAuthCallback)
+	social.Delete("/:provider", authMiddleware.RequireAuth, sessionOnly, authHandler.OAuthUnlink)
+
+	auth.Post("/email/verify", authMiddleware.RequireAuth, sessionOnly, authHandler.VerifyEmail)
+
+	// Восстановление и смена пароля
+	auth.Post("/password/forgot", authHandler.ForgotPassword)
+	auth.Post("/password/reset", authHandler.ResetPassword)
+	auth.Post("/password/change", authMiddleware.RequireAuth, sessionOnly, authHandler.ChangePassword)
+
+	// Активные сессии пользователя на разных устройствах
+	auth.Get("/sessions", authMiddleware.RequireAuth, sessionOnly, authHandler.GetSessions)
+	auth.Delete("/sessions/:id", authMiddleware.RequireAuth, sessionOnly, authHandler.RevokeSession)
+
+	auth.Post("/logout", authMiddleware.RequireAuth, sessionOnly, authHandler.Logout)
+	auth.Post("/logout-all", authMiddleware.RequireAuth, sessionOnly, authHandler.LogoutAll)
 
 	auth.Get("/me", authMiddleware.RequireAuth, authHandler.Me)
 
-	// Пример защищенного маршрута для админа
-	// Сначала проверяем токен (RequireAuth), потом роль (RequireRole)
+	// Профиль текущего пользователя
+	me := api.Group("/users/me", authMiddleware.RequireAuth)
+	me.Patch("/", authMiddleware.RequirePermission(string(domain.PermissionProfileWrite)), authHandler.UpdateProfile)
+	me.Delete("/", sessionOnly, authHandler.DeleteAccount)
+	me.Post("/phone", sessionOnly, authMiddleware.RequirePermission(string(domain.PermissionProfileWrite)), authHandler.RequestPhoneChange)
+	me.Post("/phone/confirm", sessionOnly, authMiddleware.RequirePermission(string(domain.PermissionProfileWrite)), authHandler.ConfirmPhoneChange)
+
+	// Персональные API ключи для скриптов
+	me.Get("/api-keys", sessionOnly, authHandler.GetAPIKeys)
+	me.Post("/api-keys", sessionOnly, authHandler.CreateAPIKey)
+	me.Delete("/api-keys/:id", sessionOnly, authHandler.RevokeAPIKey)
+
+	// Управление пользователями для администратора
+	// Сначала проверяем токен (RequireAuth), потом роль (RequireRole).
+	// Отдельные действия дополнительно требуют права (RequirePermission)
 	admin := api.Group("/admin", authMiddleware.RequireAuth, authMiddleware.RequireRole("admin"))
 	admin.Get("/dashboard", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -34,5 +154,23 @@ func RegisterRoutes(api fiber.Router, db *gorm.DB, jwtHelper *jwthelper.JWTHelpe
 		})
 	})
 
+	canRead := authMiddleware.RequirePermission(string(domain.PermissionUsersRead))
+	canWrite := authMiddleware.RequirePermission(string(domain.PermissionUsersWrite))
+	canBan := authMiddleware.RequirePermission(string(domain.PermissionUsersBan))
+	admin.Get("/users", canRead, authHandler.ListUsers)
+	admin.Get("/users/:id", canRead, authHandler.GetUser)
+	admin.Put("/users/:id/role", authMiddleware.RequirePermission(string(domain.PermissionRolesWrite)), authHandler.ChangeUserRole)
+	admin.Post("/users/:id/ban", canBan, authHandler.BanUser)
+	admin.Delete("/users/:id/ban", canBan, authHandler.UnbanUser)
+	admin.Post("/users/:id/unlock", canWrite, authHandler.UnlockUser)
+	admin.Post("/users/:id/password-reset", canWrite, authHandler.ForcePasswordReset)
+	admin.Delete("/users/:id/sessions", authMiddleware.RequirePermission(string(domain.PermissionSessionsWrite)), authHandler.RevokeUserSessions)
+	admin.Delete("/users/:id", canWrite, authHandler.DeleteUser)
+
+	// Сервисные клиенты для client credentials grant
+	admin.Get("/clients", sessionOnly, authHandler.ListServiceClients)
+	admin.Post("/clients", sessionOnly, authHandler.CreateServiceClient)
+	admin.Delete("/clients/:id", sessionOnly, authHandler.RevokeServiceClient)
+
 	return authMiddleware
 }
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"nodabackend/internal/auth/usecase"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// grantTypeClientCredentials единственный grant type, поддерживаемый /auth/token
+const grantTypeClientCredentials = "client_credentials"
+
+// CreateServiceClientRequest структура для регистрации сервисного клиента
+type CreateServiceClientRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// ServiceTokenRequest запрос токена (RFC 6749, раздел 4.4).
+// Учетные данные клиента передаются в Basic заголовке или в теле запроса.
+type ServiceTokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type"`
+	Scope        string `json:"scope" form:"scope"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+}
+
+// IntrospectRequest запрос проверки токена (RFC 7662, раздел 2.1)
+type IntrospectRequest struct {
+	Token         string `json:"token" form:"token"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+	ClientID      string `json:"client_id" form:"client_id"`
+	ClientSecret  string `json:"client_secret" form:"client_secret"`
+}
+
+// ServiceToken выдает access токен сервисному клиенту (client credentials grant).
+// Ошибки возвращаются в формате RFC 6749, который ожидают стандартные OAuth клиенты.
+func (h *AuthHandler) ServiceToken(c *fiber.Ctx) error {
+	var req ServiceTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	if req.GrantType != grantTypeClientCredentials {
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "unsupported_grant_type", "Only client_credentials grant is supported")
+	}
+
+	clientID, clientSecret, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "invalid_request", "Malformed client credentials")
+	}
+
+	token, err := h.authUseCase.IssueServiceToken(clientID, clientSecret, req.Scope)
+	if err != nil {
+		return serviceClientErrorResponse(c, err)
+	}
+
+	// RFC 6749, раздел 5.1: ответ с токеном не должен кешироваться
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+	return c.JSON(token)
+}
+
+// Introspect проверяет токен по запросу доверенного клиента со scope introspect
+func (h *AuthHandler) Introspect(c *fiber.Ctx) error {
+	var req IntrospectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+	if req.Token == "" {
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "invalid_request", "Token is required")
+	}
+
+	clientID, clientSecret, ok := clientCredentials(c, req.ClientID, req.ClientSecret)
+	if !ok {
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "invalid_request", "Malformed client credentials")
+	}
+
+	response, err := h.authUseCase.IntrospectToken(c.UserContext(), clientID, clientSecret, req.Token)
+	if err != nil {
+		return serviceClientErrorResponse(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(response)
+}
+
+// CreateServiceClient регистрирует сервисный клиент. Секрет возвращается только в этом ответе.
+func (h *AuthHandler) CreateServiceClient(c *fiber.Ctx) error {
+	var req CreateServiceClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	credentials, err := h.authUseCase.CreateServiceClient(req.Name, req.Scopes)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Service client created. Store the secret now, it will not be shown again",
+		"data":    credentials,
+	})
+}
+
+// ListServiceClients возвращает зарегистрированных сервисных клиентов
+func (h *AuthHandler) ListServiceClients(c *fiber.Ctx) error {
+	clients, err := h.authUseCase.ListServiceClients()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get service clients",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": clients,
+	})
+}
+
+// RevokeServiceClient отзывает сервисного клиента и все его токены
+func (h *AuthHandler) RevokeServiceClient(c *fiber.Ctx) error {
+	if err := h.authUseCase.RevokeServiceClient(c.Params("id")); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, usecase.ErrServiceClientNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Service client revoked successfully",
+	})
+}
+
+// clientCredentials читает ID и секрет клиента из Basic заголовка (client_secret_basic)
+// или из тела запроса (client_secret_post). Использовать оба способа сразу нельзя.
+func clientCredentials(c *fiber.Ctx, bodyID, bodySecret string) (string, string, bool) {
+	authHeader := c.Get(fiber.HeaderAuthorization)
+	if authHeader == "" {
+		return bodyID, bodySecret, true
+	}
+	if bodySecret != "" {
+		return "", "", false
+	}
+
+	scheme, encoded, found := strings.Cut(authHeader, " ")
+	if !found || !strings.EqualFold(scheme, "Basic") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	rawID, rawSecret, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return "", "", false
+	}
+
+	// RFC 6749, раздел 2.3.1: ID и секрет перед кодированием в base64 кодируются как form-urlencoded
+	clientID, err := url.QueryUnescape(rawID)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err := url.QueryUnescape(rawSecret)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, clientSecret, true
+}
+
+// serviceClientErrorResponse сводит ошибки клиента к кодам RFC 6749
+func serviceClientErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidClient):
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="auth"`)
+		return oauthErrorResponse(c, fiber.StatusUnauthorized, "invalid_client", err.Error())
+	case errors.Is(err, usecase.ErrInvalidScope):
+		return oauthErrorResponse(c, fiber.StatusBadRequest, "invalid_scope", err.Error())
+	case errors.Is(err, usecase.ErrUnauthorizedClient):
+		return oauthErrorResponse(c, fiber.StatusForbidden, "unauthorized_client", err.Error())
+	default:
+		return oauthErrorResponse(c, fiber.StatusInternalServerError, "server_error", "Failed to process request")
+	}
+}
+
+// oauthErrorResponse отвечает ошибкой в формате RFC 6749, раздел 5.2
+func oauthErrorResponse(c *fiber.Ctx, status int, code, description string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error":             code,
+		"error_description": description,
+	})
+}
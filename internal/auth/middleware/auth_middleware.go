@@ -1,99 +1,423 @@
-package middleware
-
-import (
-	"nodabackend/pkg/jwthelper"
-	"strings"
-
-	"github.com/gofiber/fiber/v2"
-)
-
-// AuthMiddleware middleware для проверки JWT токенов
-type AuthMiddleware struct {
-	jwtHelper *jwthelper.JWTHelper
-}
-
-// NewAuthMiddleware создает новый auth middleware
-func NewAuthMiddleware(jwtHelper *jwthelper.JWTHelper) *AuthMiddleware {
-	return &AuthMiddleware{
-		jwtHelper: jwtHelper,
-	}
-}
-
-// RequireAuth middleware для защищенных маршрутов
-func (m *AuthMiddleware) RequireAuth(c *fiber.Ctx) error {
-	// Получаем токен из заголовка Authorization
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Authorization header required",
-		})
-	}
-
-	// Проверяем формат Bearer токена
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid authorization header format",
-		})
-	}
-
-	tokenString := tokenParts[1]
-
-	// Валидируем токен
-	claims, err := m.jwtHelper.ValidateToken(tokenString)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid or expired token",
-		})
-	}
-
-	// Сохраняем данные пользователя в контексте для использования в handlers
-	c.Locals("userID", claims.UserID)
-	c.Locals("phone", claims.Phone)
-	c.Locals("role", claims.Role)
-
-	return c.Next()
-}
-
-// RequireRole middleware для проверки роли пользователя
-// Этот middleware должен вызываться ПОСЛЕ RequireAuth
-func (m *AuthMiddleware) RequireRole(requiredRole string) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		// Получаем роль из контекста, которую установил RequireAuth
-		userRole, ok := c.Locals("role").(string)
-		if !ok {
-			// Это может произойти, если RequireAuth не был вызван перед этим middleware
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Role not found in context, ensure RequireAuth runs first",
-			})
-		}
-
-		// Проверяем, соответствует ли роль требуемой
-		if userRole != requiredRole {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Forbidden: insufficient permissions",
-			})
-		}
-
-		return c.Next()
-	}
-}
-
-
-// OptionalAuth middleware для маршрутов где авторизация опциональна
-func (m *AuthMiddleware) OptionalAuth(c *fiber.Ctx) error {
-	authHeader := c.Get("Authorization")
-	if authHeader != "" {
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) == 2 && tokenParts[0] == "Bearer" {
-			claims, err := m.jwtHelper.ValidateToken(tokenParts[1])
-			if err == nil {
-				c.Locals("userID", claims.UserID)
-				c.Locals("phone", claims.Phone)
-				c.Locals("role", claims.Role)
-			}
-		}
-	}
-	return c.Next()
-}
-
+package middleware
+
+import (
+	"errors"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/rbac"
+	"nodabackend/pkg/jwthelper"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyTouchInterval как часто время последнего использования API ключа записывается в базу
+const apiKeyTouchInterval = time.Minute
+
+// AuthMiddleware middleware для проверки JWT токенов и API ключей.
+// Различает пользователей и сервисные клиенты (client credentials): тип субъекта
+// сохраняется в c.Locals("principalType") — domain.PrincipalUser или domain.PrincipalService.
+type AuthMiddleware struct {
+	jwtHelper         *jwthelper.JWTHelper
+	revocationStore   domain.TokenRevocationStore
+	userRepo          domain.UserRepository
+	serviceClientRepo domain.ServiceClientRepository
+	apiKeyRepo        domain.APIKeyRepository
+	authorizer        *rbac.Authorizer
+	activity          *sessionActivity
+}
+
+// NewAuthMiddleware создает новый auth middleware
+func NewAuthMiddleware(jwtHelper *jwthelper.JWTHelper, revocationStore domain.TokenRevocationStore, userRepo domain.UserRepository, sessionRepo domain.SessionRepository, serviceClientRepo domain.ServiceClientRepository, apiKeyRepo domain.APIKeyRepository, authorizer *rbac.Authorizer) *AuthMiddleware {
+	return &AuthMiddleware{
+		jwtHelper:         jwtHelper,
+		revocationStore:   revocationStore,
+		userRepo:          userRepo,
+		serviceClientRepo: serviceClientRepo,
+		apiKeyRepo:        apiKeyRepo,
+		authorizer:        authorizer,
+		activity:          newSessionActivity(sessionRepo, sessionActivityFlushInterval),
+	}
+}
+
+// RequireAuth middleware для защищенных маршрутов пользователя.
+// Принимает access токен (Authorization: Bearer) или персональный API ключ (Authorization: ApiKey).
+// Без заголовка Authorization access токен берется из cookie (режим cookie-сессий браузера);
+// такие запросы дополнительно защищены CSRFProtection.
+// Токены сервисных клиентов отклоняются: для них есть RequireService.
+func (m *AuthMiddleware) RequireAuth(c *fiber.Ctx) error {
+	if scheme, key, found := strings.Cut(c.Get("Authorization"), " "); found && scheme == "ApiKey" {
+		return m.authenticateAPIKey(c, key)
+	}
+
+	claims, method, err := m.authenticate(c)
+	if claims == nil {
+		return err
+	}
+
+	if claims.IsService() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "This endpoint requires a user token",
+			"code":  "user_token_required",
+		})
+	}
+
+	if claims.SessionID != "" {
+		m.activity.touch(claims.SessionID, time.Now())
+	}
+
+	// Сохраняем данные пользователя в контексте для использования в handlers
+	setClaimsLocals(c, claims, method)
+
+	return c.Next()
+}
+
+// RequireService middleware для маршрутов внутренних сервисов: пропускает только токены
+// сервисных клиентов, у которых есть все перечисленные scopes.
+// Клиент проверяется по базе, поэтому отзыв клиента действует сразу.
+func (m *AuthMiddleware) RequireService(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, method, err := m.authenticate(c)
+		if claims == nil {
+			return err
+		}
+
+		if !claims.IsService() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "This endpoint requires a service token",
+				"code":  "service_token_required",
+			})
+		}
+
+		client, err := m.serviceClientRepo.GetServiceClient(claims.ClientID)
+		if err != nil || client.IsRevoked() {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Client has been revoked",
+			})
+		}
+
+		granted := strings.Fields(claims.Scope)
+		for _, scope := range scopes {
+			// Scope должен быть и в токене, и у клиента на момент запроса
+			if !containsString(granted, scope) || !client.HasScope(scope) {
+				c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Forbidden: insufficient scope",
+					"code":  "insufficient_scope",
+				})
+			}
+		}
+
+		setClaimsLocals(c, claims, method)
+
+		return c.Next()
+	}
+}
+
+// authenticate проверяет access токен и его отзыв, возвращая claims и способ передачи токена.
+// При ошибке отправляет ответ и возвращает nil claims.
+func (m *AuthMiddleware) authenticate(c *fiber.Ctx) (*jwthelper.Claims, string, error) {
+	tokenString, method, err := requestToken(c)
+	if err != nil {
+		return nil, "", c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Валидируем токен
+	claims, err := m.jwtHelper.ValidateToken(tokenString)
+	if err != nil {
+		return nil, "", tokenErrorResponse(c, err)
+	}
+
+	// Проверяем, не был ли токен отозван (logout, завершение сессии)
+	revoked, err := m.isRevoked(c, claims)
+	if err != nil {
+		return nil, "", c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Unable to verify token",
+		})
+	}
+	if revoked {
+		return nil, "", c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Token has been revoked",
+		})
+	}
+
+	return claims, method, nil
+}
+
+// requestToken извлекает access токен из заголовка Authorization: Bearer,
+// а если заголовка нет — из cookie режима cookie-сессий
+func requestToken(c *fiber.Ctx) (string, string, error) {
+	authHeader := c.Get("Authorization")
+	if authHeader == "" {
+		if token := c.Cookies(AccessTokenCookie); token != "" {
+			return token, domain.AuthMethodCookie, nil
+		}
+		return "", "", errors.New("Authorization header required")
+	}
+
+	// Проверяем формат Bearer токена
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return "", "", errors.New("Invalid authorization header format")
+	}
+
+	return tokenParts[1], domain.AuthMethodBearer, nil
+}
+
+// authenticateAPIKey проверяет персональный API ключ и сохраняет в контексте те же данные
+// пользователя, что и для access токена. Роль и статус пользователя берутся из базы.
+func (m *AuthMiddleware) authenticateAPIKey(c *fiber.Ctx, key string) error {
+	invalid := func(message, code string) error {
+		c.Set(fiber.HeaderWWWAuthenticate, `ApiKey error="invalid_key"`)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": message,
+			"code":  code,
+		})
+	}
+
+	if !strings.HasPrefix(key, domain.APIKeyPrefix) {
+		return invalid("Invalid API key", "api_key_invalid")
+	}
+
+	apiKey, err := m.apiKeyRepo.GetAPIKeyByHash(domain.HashAPIKey(key))
+	if err != nil {
+		return invalid("Invalid API key", "api_key_invalid")
+	}
+
+	now := time.Now()
+	if !apiKey.IsActive(now) {
+		return invalid("API key has expired or been revoked", "api_key_expired")
+	}
+
+	user, err := m.userRepo.GetUserByID(apiKey.UserID)
+	if err != nil {
+		return invalid("Invalid API key", "api_key_invalid")
+	}
+	if user.IsBanned() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Account is banned",
+			"code":  "account_banned",
+		})
+	}
+	if user.PasswordResetRequired {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Password reset required",
+			"code":  "password_reset_required",
+		})
+	}
+
+	// Время использования пишется не чаще раза в минуту, чтобы не нагружать базу
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := m.apiKeyRepo.TouchAPIKey(apiKey.ID, now); err != nil {
+			log.Printf("[AUTH] Failed to record API key %d usage: %v", apiKey.ID, err)
+		}
+	}
+
+	c.Locals("principalType", domain.PrincipalUser)
+	c.Locals("authMethod", domain.AuthMethodAPIKey)
+	c.Locals("userID", user.ID)
+	c.Locals("phone", user.Phone)
+	c.Locals("role", string(user.Role))
+	c.Locals("apiKeyID", apiKey.ID)
+	c.Locals("scopes", apiKey.Scopes())
+
+	return c.Next()
+}
+
+// RequireSessionToken middleware для действий с самой учетной записью (пароль, 2FA, сессии,
+// API ключи): пропускает только access токен входа, API ключи отклоняются.
+// Должен вызываться ПОСЛЕ RequireAuth
+func (m *AuthMiddleware) RequireSessionToken(c *fiber.Ctx) error {
+	if _, ok := c.Locals("claims").(*jwthelper.Claims); !ok || c.Locals("principalType") != domain.PrincipalUser {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "This endpoint requires a login session, API keys are not accepted",
+			"code":  "session_token_required",
+		})
+	}
+
+	return c.Next()
+}
+
+// RequireRole middleware для проверки роли пользователя с учетом иерархии:
+// роль, наследующая requiredRole, тоже проходит (admin проходит RequireRole("user")).
+// Этот middleware должен вызываться ПОСЛЕ RequireAuth
+func (m *AuthMiddleware) RequireRole(requiredRole string) fiber.Handler {
+	return m.RequireAnyRole(requiredRole)
+}
+
+// RequireAnyRole middleware пропускает пользователя, если его роль совпадает
+// с одной из перечисленных или наследует ее. Должен вызываться ПОСЛЕ RequireAuth
+func (m *AuthMiddleware) RequireAnyRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Получаем роль из контекста, которую установил RequireAuth
+		userRole, ok := c.Locals("role").(string)
+		if !ok {
+			// Это может произойти, если RequireAuth не был вызван перед этим middleware
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Role not found in context, ensure RequireAuth runs first",
+			})
+		}
+
+		for _, role := range roles {
+			allowed, err := m.authorizer.HasRole(domain.Role(userRole), domain.Role(role))
+			if err != nil {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "Unable to verify permissions",
+				})
+			}
+			if allowed {
+				return c.Next()
+			}
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden: insufficient permissions",
+		})
+	}
+}
+
+// RequirePermission middleware проверяет, что у роли пользователя есть право,
+// например RequirePermission("users:write"). Права берутся из кеша ролей, а не из токена,
+// поэтому изменение прав роли применяется без перевыпуска токенов.
+// Для API ключа право должно быть еще и в scopes ключа.
+// Должен вызываться ПОСЛЕ RequireAuth
+func (m *AuthMiddleware) RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRole, ok := c.Locals("role").(string)
+		if !ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Role not found in context, ensure RequireAuth runs first",
+			})
+		}
+
+		if c.Locals("authMethod") == domain.AuthMethodAPIKey {
+			scopes, _ := c.Locals("scopes").([]string)
+			if !containsString(scopes, permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Forbidden: API key scope does not include " + permission,
+					"code":  "insufficient_scope",
+				})
+			}
+		}
+
+		allowed, err := m.authorizer.HasPermission(domain.Role(userRole), domain.Permission(permission))
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Unable to verify permissions",
+			})
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Forbidden: insufficient permissions",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireVerifiedPhone middleware для маршрутов, доступных только пользователям
+// с подтвержденным телефоном. Должен вызываться ПОСЛЕ RequireAuth.
+// Статус проверяется по базе, а не по токену, чтобы подтверждение вступало в силу сразу.
+func (m *AuthMiddleware) RequireVerifiedPhone(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not found in context, ensure RequireAuth runs first",
+		})
+	}
+
+	user, err := m.userRepo.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if !user.IsPhoneVerified() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Phone number is not verified",
+		})
+	}
+
+	return c.Next()
+}
+
+// OptionalAuth middleware для маршрутов где авторизация опциональна.
+// Принимает токены пользователей и сервисных клиентов; тип смотрите в c.Locals("principalType").
+func (m *AuthMiddleware) OptionalAuth(c *fiber.Ctx) error {
+	tokenString, method, err := requestToken(c)
+	if err == nil {
+		claims, err := m.jwtHelper.ValidateToken(tokenString)
+		if err == nil {
+			// Отозванный токен или ошибка проверки — считаем запрос анонимным
+			if revoked, err := m.isRevoked(c, claims); err == nil && !revoked {
+				setClaimsLocals(c, claims, method)
+			}
+		}
+	}
+	return c.Next()
+}
+
+// isRevoked проверяет токен по denylist в Redis
+func (m *AuthMiddleware) isRevoked(c *fiber.Ctx, claims *jwthelper.Claims) (bool, error) {
+	if m.revocationStore == nil {
+		return false, nil
+	}
+
+	return m.revocationStore.IsTokenRevoked(c.UserContext(), claims.ID, claims.SessionID, claims.UserID, claims.IssuedAtTime())
+}
+
+// tokenErrorResponse отвечает на невалидный токен. Причина передается в поле code,
+// чтобы клиент мог отличить истекший токен (нужно обновить) от ошибок настройки.
+func tokenErrorResponse(c *fiber.Ctx, err error) error {
+	message, code := "Invalid token", "token_invalid"
+	switch {
+	case errors.Is(err, jwthelper.ErrTokenExpired):
+		message, code = "Token has expired", "token_expired"
+	case errors.Is(err, jwthelper.ErrTokenNotYetValid):
+		message, code = "Token is not valid yet", "token_not_yet_valid"
+	case errors.Is(err, jwthelper.ErrTokenInvalidAudience):
+		message, code = "Token was issued for another audience", "token_invalid_audience"
+	case errors.Is(err, jwthelper.ErrTokenInvalidIssuer):
+		message, code = "Token was issued by an unknown issuer", "token_invalid_issuer"
+	}
+
+	// RFC 6750: причина отказа дублируется в заголовке для стандартных клиентов
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token", error_description="`+message+`"`)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": message,
+		"code":  code,
+	})
+}
+
+// setClaimsLocals сохраняет данные токена в контексте запроса.
+// Для пользователя — userID, phone, role и способ передачи токена (authMethod),
+// для сервисного клиента — clientID и scopes.
+func setClaimsLocals(c *fiber.Ctx, claims *jwthelper.Claims, method string) {
+	if claims.IsService() {
+		c.Locals("principalType", domain.PrincipalService)
+		c.Locals("clientID", claims.ClientID)
+		c.Locals("scopes", strings.Fields(claims.Scope))
+	} else {
+		c.Locals("principalType", domain.PrincipalUser)
+		c.Locals("authMethod", method)
+		c.Locals("userID", claims.UserID)
+		c.Locals("phone", claims.Phone)
+		c.Locals("role", claims.Role)
+	}
+	c.Locals("claims", claims)
+}
+
+// containsString проверяет, есть ли строка в списке
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Имена cookie и заголовка режима cookie-сессий для браузерных клиентов
+const (
+	AccessTokenCookie  = "access_token"  // HttpOnly, access токен
+	RefreshTokenCookie = "refresh_token" // HttpOnly, refresh токен; отправляется только на маршруты /auth
+	CSRFCookie         = "csrf_token"    // читается JavaScript и копируется в заголовок CSRFHeader
+	CSRFHeader         = "X-CSRF-Token"
+	CSRFFormField      = "csrf_token" // поле HTML формы, если нет заголовка CSRFHeader
+)
+
+// CSRFProtection middleware защищает запросы, аутентифицированные cookie, по схеме double-submit:
+// для небезопасных методов заголовок X-CSRF-Token должен совпадать с cookie csrf_token.
+// Чужой сайт может заставить браузер отправить cookie, но не может прочитать их и выставить заголовок.
+// HTML формы (вход по ссылке из письма) передают токен полем csrf_token вместо заголовка.
+// Запросы с заголовком Authorization не проверяются: браузер сам его не добавляет.
+func CSRFProtection(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+		return c.Next()
+	}
+
+	if c.Get(fiber.HeaderAuthorization) != "" {
+		return c.Next()
+	}
+	if c.Cookies(AccessTokenCookie) == "" && c.Cookies(RefreshTokenCookie) == "" {
+		return c.Next()
+	}
+
+	token := c.Get(CSRFHeader)
+	if token == "" {
+		token = c.FormValue(CSRFFormField)
+	}
+	if !CSRFTokenMatches(c, token) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Invalid CSRF token",
+			"code":  "csrf_token_invalid",
+		})
+	}
+
+	return c.Next()
+}
+
+// CSRFTokenMatches проверяет, что переданный токен совпадает с cookie csrf_token
+func CSRFTokenMatches(c *fiber.Ctx, token string) bool {
+	expected := c.Cookies(CSRFCookie)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	app := fiber.New()
+	app.Use(CSRFProtection)
+	app.All("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{"safe method with cookie", fiber.MethodGet, map[string]string{"Cookie": "access_token=a; csrf_token=x"}, "", fiber.StatusNoContent},
+		{"no auth cookies", fiber.MethodPost, nil, "", fiber.StatusNoContent},
+		{"authorization header", fiber.MethodPost, map[string]string{"Cookie": "access_token=a", "Authorization": "Bearer a"}, "", fiber.StatusNoContent},
+		{"missing header", fiber.MethodPost, map[string]string{"Cookie": "access_token=a; csrf_token=x"}, "", fiber.StatusForbidden},
+		{"mismatched header", fiber.MethodDelete, map[string]string{"Cookie": "access_token=a; csrf_token=x", CSRFHeader: "y"}, "", fiber.StatusForbidden},
+		{"refresh cookie only", fiber.MethodPost, map[string]string{"Cookie": "refresh_token=r; csrf_token=x"}, "", fiber.StatusForbidden},
+		{"missing csrf cookie", fiber.MethodPost, map[string]string{"Cookie": "access_token=a", CSRFHeader: ""}, "", fiber.StatusForbidden},
+		{"matching header", fiber.MethodPost, map[string]string{"Cookie": "access_token=a; csrf_token=x", CSRFHeader: "x"}, "", fiber.StatusNoContent},
+		{"matching form field", fiber.MethodPost, map[string]string{"Cookie": "access_token=a; csrf_token=x", "Content-Type": fiber.MIMEApplicationForm}, "csrf_token=x", fiber.StatusNoContent},
+		{"mismatched form field", fiber.MethodPost, map[string]string{"Cookie": "access_token=a; csrf_token=x", "Content-Type": fiber.MIMEApplicationForm}, "csrf_token=y", fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log"
+	"nodabackend/internal/auth/domain"
+	"sync"
+	"time"
+)
+
+// sessionActivityFlushInterval как часто время активности сессий записывается в базу
+const sessionActivityFlushInterval = time.Minute
+
+// sessionActivity накапливает время последнего запроса по сессиям и периодически
+// записывает его одной транзакцией, чтобы не делать запись в базу на каждый запрос
+type sessionActivity struct {
+	sessionRepo domain.SessionRepository
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+}
+
+// newSessionActivity создает накопитель и запускает периодическую запись
+func newSessionActivity(sessionRepo domain.SessionRepository, interval time.Duration) *sessionActivity {
+	activity := &sessionActivity{
+		sessionRepo: sessionRepo,
+		pending:     make(map[string]time.Time),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			activity.flush()
+		}
+	}()
+
+	return activity
+}
+
+// touch отмечает активность сессии; в базу попадет при следующей записи
+func (a *sessionActivity) touch(sessionID string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if at.After(a.pending[sessionID]) {
+		a.pending[sessionID] = at
+	}
+}
+
+// flush записывает накопленную активность
+func (a *sessionActivity) flush() {
+	a.mu.Lock()
+	if len(a.pending) == 0 {
+		a.mu.Unlock()
+		return
+	}
+	batch := a.pending
+	a.pending = make(map[string]time.Time, len(batch))
+	a.mu.Unlock()
+
+	if err := a.sessionRepo.TouchSessions(batch); err != nil {
+		// Время активности носит справочный характер, повторять запись не нужно
+		log.Printf("[AUTH] Failed to update last seen for %d sessions: %v", len(batch), err)
+	}
+}
@@ -0,0 +1,258 @@
+// Package oauthtest локальный OpenID Connect провайдер для тестов входа через OAuth2
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// ClientID клиент, зарегистрированный в MockIdP
+	ClientID = "test-client"
+	// ClientSecret секрет клиента
+	ClientSecret = "test-secret"
+	// keyID kid ключа подписи в JWKS
+	keyID = "mock-key"
+)
+
+// User пользователь провайдера
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// authorization выданный, но еще не обмененный authorization code
+type authorization struct {
+	user          User
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// MockIdP OpenID Connect провайдер на httptest.Server:
+// discovery, JWKS и token endpoint с проверкой PKCE (S256).
+// Страница авторизации не нужна — Authorize сразу выдает code, как после согласия пользователя.
+type MockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// ClaimsHook позволяет изменить claims ID токена перед подписью (например, подменить nonce)
+	ClaimsHook func(claims jwt.MapClaims)
+	// SignWithForeignKey подписывает ID токен ключом, которого нет в JWKS
+	SignWithForeignKey bool
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+// NewMockIdP запускает провайдер; его нужно остановить через Close
+func NewMockIdP() (*MockIdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	idp := &MockIdP{key: key, codes: make(map[string]*authorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("/jwks", idp.handleJWKS)
+	mux.HandleFunc("/token", idp.handleToken)
+	idp.server = httptest.NewServer(mux)
+
+	return idp, nil
+}
+
+// Issuer адрес провайдера для OIDC discovery
+func (idp *MockIdP) Issuer() string {
+	return idp.server.URL
+}
+
+// Close останавливает провайдер
+func (idp *MockIdP) Close() {
+	idp.server.Close()
+}
+
+// Authorize имитирует вход пользователя на странице authCodeURL
+// и возвращает code и state, с которыми провайдер перенаправил бы на redirect_uri
+func (idp *MockIdP) Authorize(authCodeURL string, user User) (code, state string, err error) {
+	parsed, err := url.Parse(authCodeURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := parsed.Query()
+
+	if query.Get("client_id") != ClientID {
+		return "", "", errors.New("unknown client")
+	}
+	if query.Get("response_type") != "code" {
+		return "", "", errors.New("unsupported response type")
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", "", errors.New("PKCE S256 challenge is required")
+	}
+
+	code, err = randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	idp.mu.Lock()
+	idp.codes[code] = &authorization{
+		user:          user,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURI:   query.Get("redirect_uri"),
+	}
+	idp.mu.Unlock()
+
+	return code, query.Get("state"), nil
+}
+
+// handleDiscovery отдает discovery документ
+func (idp *MockIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                idp.Issuer(),
+		"authorization_endpoint":                idp.Issuer() + "/authorize",
+		"token_endpoint":                        idp.Issuer() + "/token",
+		"jwks_uri":                              idp.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// handleJWKS отдает публичный ключ подписи
+func (idp *MockIdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+// handleToken обменивает authorization code на токены
+func (idp *MockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != ClientID || clientSecret != ClientSecret {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	// Code одноразовый
+	idp.mu.Lock()
+	auth, found := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	if !found || auth.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	idToken, err := idp.signIDToken(auth)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	accessToken, err := randomString()
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// signIDToken выпускает ID токен для пользователя
+func (idp *MockIdP) signIDToken(auth *authorization) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            idp.Issuer(),
+		"sub":            auth.user.Subject,
+		"aud":            ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.user.Email,
+		"email_verified": auth.user.EmailVerified,
+		"name":           auth.user.Name,
+	}
+	if idp.ClaimsHook != nil {
+		idp.ClaimsHook(claims)
+	}
+
+	key := idp.key
+	if idp.SignWithForeignKey {
+		foreign, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return "", err
+		}
+		key = foreign
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(key)
+}
+
+// randomString генерирует случайное значение для code и access token
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// writeOAuthError отвечает ошибкой в формате RFC 6749, раздел 5.2
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+// writeJSON отвечает JSON документом
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
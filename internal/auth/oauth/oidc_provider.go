@@ -0,0 +1,115 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig настройки OpenID Connect провайдера
+type OIDCConfig struct {
+	Name         string // имя в маршрутах: /auth/oauth/<name>/...
+	IssuerURL    string // из него через discovery получаются endpoints и JWKS
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // должен вести на /api/v1/auth/oauth/<name>/callback
+	Scopes       []string // openid добавляется всегда
+}
+
+// OIDCProvider универсальный OpenID Connect провайдер (Google, Keycloak, Auth0, ...)
+type OIDCProvider struct {
+	name     string
+	config   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// idTokenClaims claims ID токена, которые нужны для входа
+type idTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// NewOIDCProvider создает провайдер, загружая discovery документ издателя
+func NewOIDCProvider(ctx context.Context, config OIDCConfig) (*OIDCProvider, error) {
+	if config.Name == "" || config.IssuerURL == "" || config.ClientID == "" {
+		return nil, errors.New("provider name, issuer URL and client ID are required")
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider %s: %w", config.Name, err)
+	}
+
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range config.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return &OIDCProvider{
+		name: config.Name,
+		config: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		// Проверяет подпись по JWKS издателя, iss, aud и exp
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+// Name возвращает имя провайдера
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL формирует адрес авторизации с nonce и PKCE challenge (S256)
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return p.config.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(codeVerifier),
+	)
+}
+
+// Exchange обменивает code на токены и проверяет ID токен
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*domain.ExternalIdentity, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("provider did not return an ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid ID token nonce")
+	}
+
+	var claims idTokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse ID token claims: %w", err)
+	}
+
+	return &domain.ExternalIdentity{
+		Provider:      p.name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
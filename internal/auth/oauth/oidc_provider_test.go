@@ -0,0 +1,132 @@
+package oauth
+
+import (
+	"context"
+	"nodabackend/internal/auth/oauth/oauthtest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const testRedirectURL = "http://localhost:3000/api/v1/auth/oauth/mock/callback"
+
+var testUser = oauthtest.User{
+	Subject:       "mock-user-1",
+	Email:         "user@example.com",
+	EmailVerified: true,
+	Name:          "Mock User",
+}
+
+// newTestProvider запускает MockIdP и настраивает на него OIDC провайдер
+func newTestProvider(t *testing.T) (*OIDCProvider, *oauthtest.MockIdP) {
+	t.Helper()
+
+	idp, err := oauthtest.NewMockIdP()
+	if err != nil {
+		t.Fatalf("NewMockIdP: %v", err)
+	}
+	t.Cleanup(idp.Close)
+
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		Name:         "mock",
+		IssuerURL:    idp.Issuer(),
+		ClientID:     oauthtest.ClientID,
+		ClientSecret: oauthtest.ClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+
+	return provider, idp
+}
+
+// authorize проходит авторизацию у MockIdP и возвращает code
+func authorize(t *testing.T, provider *OIDCProvider, idp *oauthtest.MockIdP, nonce, verifier string) string {
+	t.Helper()
+
+	code, state, err := idp.Authorize(provider.AuthCodeURL("state-1", nonce, verifier), testUser)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+	return code
+}
+
+func TestOIDCProvider_Exchange(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	verifier := oauth2.GenerateVerifier()
+	code := authorize(t, provider, idp, "nonce-1", verifier)
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if identity.Provider != "mock" || identity.Subject != testUser.Subject {
+		t.Fatalf("identity = %s/%s, want mock/%s", identity.Provider, identity.Subject, testUser.Subject)
+	}
+	if identity.Email != testUser.Email || !identity.EmailVerified || identity.Name != testUser.Name {
+		t.Fatalf("unexpected identity claims: %+v", identity)
+	}
+}
+
+func TestOIDCProvider_RejectsWrongNonce(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	verifier := oauth2.GenerateVerifier()
+	code := authorize(t, provider, idp, "nonce-1", verifier)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-2"); err == nil {
+		t.Fatal("expected error for mismatched nonce")
+	}
+}
+
+func TestOIDCProvider_RejectsWrongVerifier(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	code := authorize(t, provider, idp, "nonce-1", oauth2.GenerateVerifier())
+
+	if _, err := provider.Exchange(context.Background(), code, oauth2.GenerateVerifier(), "nonce-1"); err == nil {
+		t.Fatal("expected error for wrong PKCE verifier")
+	}
+}
+
+func TestOIDCProvider_RejectsForeignSignature(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	idp.SignWithForeignKey = true
+	verifier := oauth2.GenerateVerifier()
+	code := authorize(t, provider, idp, "nonce-1", verifier)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("expected error for ID token signed with unknown key")
+	}
+}
+
+func TestOIDCProvider_RejectsWrongAudience(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	idp.ClaimsHook = func(claims jwt.MapClaims) {
+		claims["aud"] = "another-client"
+	}
+	verifier := oauth2.GenerateVerifier()
+	code := authorize(t, provider, idp, "nonce-1", verifier)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("expected error for ID token issued to another client")
+	}
+}
+
+func TestOIDCProvider_CodeIsSingleUse(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	verifier := oauth2.GenerateVerifier()
+	code := authorize(t, provider, idp, "nonce-1", verifier)
+
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, verifier, "nonce-1"); err == nil {
+		t.Fatal("expected error for reused authorization code")
+	}
+}
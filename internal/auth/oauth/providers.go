@@ -0,0 +1,43 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/env"
+	"strings"
+)
+
+// NewProvidersFromEnv создает OIDC провайдеры, перечисленные в OAUTH_PROVIDERS.
+// Для каждого провайдера <NAME> читаются OAUTH_<NAME>_ISSUER, OAUTH_<NAME>_CLIENT_ID,
+// OAUTH_<NAME>_CLIENT_SECRET, OAUTH_<NAME>_REDIRECT_URL и OAUTH_<NAME>_SCOPES.
+// Провайдер, который не удалось настроить, пропускается, чтобы не блокировать запуск.
+func NewProvidersFromEnv(ctx context.Context) []domain.OAuthProvider {
+	var providers []domain.OAuthProvider
+
+	for _, name := range strings.Split(env.GetEnvOrDefault("OAUTH_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := fmt.Sprintf("OAUTH_%s_", strings.ToUpper(name))
+		provider, err := NewOIDCProvider(ctx, OIDCConfig{
+			Name:         name,
+			IssuerURL:    env.GetEnvOrDefault(prefix+"ISSUER", ""),
+			ClientID:     env.GetEnvOrDefault(prefix+"CLIENT_ID", ""),
+			ClientSecret: env.GetEnvOrDefault(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  env.GetEnvOrDefault(prefix+"REDIRECT_URL", ""),
+			Scopes:       strings.Fields(env.GetEnvOrDefault(prefix+"SCOPES", "email profile")),
+		})
+		if err != nil {
+			log.Printf("[AUTH] OAuth provider %s is disabled: %v", name, err)
+			continue
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
@@ -0,0 +1,152 @@
+// Package rbac проверяет роли и права пользователей с учетом иерархии ролей.
+package rbac
+
+import (
+	"nodabackend/internal/auth/domain"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL как долго роли и права хранятся в памяти процесса.
+// Изменения ролей в базе вступают в силу не позже, чем через это время.
+const DefaultCacheTTL = time.Minute
+
+// Authorizer отвечает на вопросы «есть ли у роли право» и «включает ли роль другую роль».
+// Роли загружаются из базы целиком и кешируются, чтобы не делать запрос на каждый вызов.
+type Authorizer struct {
+	roleRepo domain.RoleRepository
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	roles    map[domain.Role]*resolvedRole
+	loadedAt time.Time
+}
+
+// resolvedRole роль с развернутой иерархией
+type resolvedRole struct {
+	ancestors   map[domain.Role]struct{} // сама роль и все ее родители
+	permissions map[domain.Permission]struct{}
+}
+
+// NewAuthorizer создает новый Authorizer
+func NewAuthorizer(roleRepo domain.RoleRepository, ttl time.Duration) *Authorizer {
+	return &Authorizer{
+		roleRepo: roleRepo,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// HasPermission проверяет, есть ли у роли право — напрямую или через родительские роли
+func (a *Authorizer) HasPermission(role domain.Role, permission domain.Permission) (bool, error) {
+	resolved, err := a.resolve(role)
+	if err != nil || resolved == nil {
+		return false, err
+	}
+	_, ok := resolved.permissions[permission]
+	return ok, nil
+}
+
+// HasRole проверяет, совпадает ли роль с required или наследует ее.
+// Например, admin включает user, а user не включает admin.
+func (a *Authorizer) HasRole(role, required domain.Role) (bool, error) {
+	if role == required {
+		return true, nil
+	}
+	resolved, err := a.resolve(role)
+	if err != nil || resolved == nil {
+		return false, err
+	}
+	_, ok := resolved.ancestors[required]
+	return ok, nil
+}
+
+// Permissions возвращает все права роли с учетом наследования
+func (a *Authorizer) Permissions(role domain.Role) ([]domain.Permission, error) {
+	resolved, err := a.resolve(role)
+	if err != nil || resolved == nil {
+		return nil, err
+	}
+	permissions := make([]domain.Permission, 0, len(resolved.permissions))
+	for permission := range resolved.permissions {
+		permissions = append(permissions, permission)
+	}
+	return permissions, nil
+}
+
+// RoleExists проверяет, что роль есть в базе
+func (a *Authorizer) RoleExists(role domain.Role) (bool, error) {
+	resolved, err := a.resolve(role)
+	return resolved != nil, err
+}
+
+// Invalidate сбрасывает кеш; следующая проверка загрузит роли заново
+func (a *Authorizer) Invalidate() {
+	a.mu.Lock()
+	a.roles = nil
+	a.mu.Unlock()
+}
+
+// resolve возвращает роль из кеша, при необходимости перезагружая роли из базы.
+// Неизвестная роль возвращается как nil без ошибки.
+func (a *Authorizer) resolve(role domain.Role) (*resolvedRole, error) {
+	a.mu.RLock()
+	if a.roles != nil && a.now().Sub(a.loadedAt) < a.ttl {
+		resolved := a.roles[role]
+		a.mu.RUnlock()
+		return resolved, nil
+	}
+	a.mu.RUnlock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Пока ждали блокировку, кеш мог обновить другой запрос
+	if a.roles == nil || a.now().Sub(a.loadedAt) >= a.ttl {
+		definitions, err := a.roleRepo.GetRoles()
+		if err != nil {
+			return nil, err
+		}
+		a.roles = resolveRoles(definitions)
+		a.loadedAt = a.now()
+	}
+
+	return a.roles[role], nil
+}
+
+// resolveRoles разворачивает иерархию: каждая роль получает права всех своих родителей.
+// Цикл в иерархии обрывается на роли, которая уже встречалась.
+func resolveRoles(definitions []*domain.RoleDefinition) map[domain.Role]*resolvedRole {
+	byName := make(map[domain.Role]*domain.RoleDefinition, len(definitions))
+	for _, definition := range definitions {
+		byName[definition.Name] = definition
+	}
+
+	roles := make(map[domain.Role]*resolvedRole, len(definitions))
+	for name := range byName {
+		resolved := &resolvedRole{
+			ancestors:   make(map[domain.Role]struct{}),
+			permissions: make(map[domain.Permission]struct{}),
+		}
+
+		for current := byName[name]; current != nil; {
+			if _, seen := resolved.ancestors[current.Name]; seen {
+				break
+			}
+			resolved.ancestors[current.Name] = struct{}{}
+			for _, permission := range current.Permissions {
+				resolved.permissions[permission.Permission] = struct{}{}
+			}
+
+			if current.Parent == nil {
+				break
+			}
+			current = byName[*current.Parent]
+		}
+
+		roles[name] = resolved
+	}
+
+	return roles
+}
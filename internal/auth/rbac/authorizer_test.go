@@ -0,0 +1,98 @@
+package rbac
+
+import (
+	"nodabackend/internal/auth/domain"
+	"testing"
+	"time"
+)
+
+// fakeRoleRepository отдает фиксированный набор ролей и считает загрузки
+type fakeRoleRepository struct {
+	roles []*domain.RoleDefinition
+	loads int
+}
+
+func (r *fakeRoleRepository) GetRoles() ([]*domain.RoleDefinition, error) {
+	r.loads++
+	return r.roles, nil
+}
+
+func TestAuthorizer_HierarchyInheritsRolesAndPermissions(t *testing.T) {
+	authorizer := NewAuthorizer(&fakeRoleRepository{roles: domain.DefaultRoles()}, time.Minute)
+
+	cases := []struct {
+		role, required domain.Role
+		want           bool
+	}{
+		{domain.AdminRole, domain.UserRole, true},
+		{domain.AdminRole, domain.ModeratorRole, true},
+		{domain.ModeratorRole, domain.UserRole, true},
+		{domain.UserRole, domain.AdminRole, false},
+		{domain.ModeratorRole, domain.AdminRole, false},
+		{"unknown", domain.UserRole, false},
+	}
+	for _, tc := range cases {
+		got, err := authorizer.HasRole(tc.role, tc.required)
+		if err != nil {
+			t.Fatalf("HasRole(%s, %s): %v", tc.role, tc.required, err)
+		}
+		if got != tc.want {
+			t.Errorf("HasRole(%s, %s) = %v, want %v", tc.role, tc.required, got, tc.want)
+		}
+	}
+
+	if ok, _ := authorizer.HasPermission(domain.AdminRole, domain.PermissionProfileWrite); !ok {
+		t.Error("admin must inherit profile:write from user")
+	}
+	if ok, _ := authorizer.HasPermission(domain.ModeratorRole, domain.PermissionUsersWrite); ok {
+		t.Error("moderator must not have users:write")
+	}
+	if ok, _ := authorizer.HasPermission("unknown", domain.PermissionProfileRead); ok {
+		t.Error("unknown role must not have permissions")
+	}
+}
+
+func TestAuthorizer_CachesRolesUntilTTL(t *testing.T) {
+	repo := &fakeRoleRepository{roles: domain.DefaultRoles()}
+	authorizer := NewAuthorizer(repo, time.Minute)
+	now := time.Now()
+	authorizer.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := authorizer.HasPermission(domain.UserRole, domain.PermissionProfileRead); err != nil {
+			t.Fatalf("HasPermission: %v", err)
+		}
+	}
+	if repo.loads != 1 {
+		t.Fatalf("roles loaded %d times, want 1", repo.loads)
+	}
+
+	now = now.Add(time.Minute)
+	authorizer.HasPermission(domain.UserRole, domain.PermissionProfileRead)
+	if repo.loads != 2 {
+		t.Fatalf("roles loaded %d times after TTL, want 2", repo.loads)
+	}
+
+	authorizer.Invalidate()
+	authorizer.HasPermission(domain.UserRole, domain.PermissionProfileRead)
+	if repo.loads != 3 {
+		t.Fatalf("roles loaded %d times after Invalidate, want 3", repo.loads)
+	}
+}
+
+func TestAuthorizer_CycleInHierarchyTerminates(t *testing.T) {
+	a, b := domain.Role("a"), domain.Role("b")
+	repo := &fakeRoleRepository{roles: []*domain.RoleDefinition{
+		{Name: a, Parent: &b, Permissions: []*domain.RolePermission{{RoleName: a, Permission: "x:read"}}},
+		{Name: b, Parent: &a, Permissions: []*domain.RolePermission{{RoleName: b, Permission: "y:read"}}},
+	}}
+	authorizer := NewAuthorizer(repo, time.Minute)
+
+	permissions, err := authorizer.Permissions(a)
+	if err != nil {
+		t.Fatalf("Permissions: %v", err)
+	}
+	if len(permissions) != 2 {
+		t.Fatalf("permissions = %v, want x:read and y:read", permissions)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository реализация APIKeyRepository для PostgreSQL
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository создает новый репозиторий API ключей
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// CreateAPIKey сохраняет новый ключ
+func (r *APIKeyRepository) CreateAPIKey(key *domain.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// GetAPIKeyByHash получает ключ по хешу
+func (r *APIKeyRepository) GetAPIKeyByHash(hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeysByUserID получает неотозванные ключи пользователя, последние созданные — первыми
+func (r *APIKeyRepository) GetAPIKeysByUserID(userID uint) ([]*domain.APIKey, error) {
+	var keys []*domain.APIKey
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey атомарно отзывает ключ, если он принадлежит пользователю
+func (r *APIKeyRepository) RevokeAPIKey(userID, id uint) (bool, error) {
+	result := r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// TouchAPIKey обновляет время последнего использования ключа
+func (r *APIKeyRepository) TouchAPIKey(id uint, usedAt time.Time) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+
+	"gorm.io/gorm"
+)
+
+// LinkedIdentityRepository реализация LinkedIdentityRepository для PostgreSQL
+type LinkedIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewLinkedIdentityRepository создает новый репозиторий связанных аккаунтов
+func NewLinkedIdentityRepository(db *gorm.DB) *LinkedIdentityRepository {
+	return &LinkedIdentityRepository{db: db}
+}
+
+// CreateLinkedIdentity сохраняет связь с внешним аккаунтом
+func (r *LinkedIdentityRepository) CreateLinkedIdentity(identity *domain.LinkedIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// GetLinkedIdentity получает связь по провайдеру и subject
+func (r *LinkedIdentityRepository) GetLinkedIdentity(provider, subject string) (*domain.LinkedIdentity, error) {
+	var identity domain.LinkedIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetLinkedIdentitiesByUserID получает все связанные аккаунты пользователя
+func (r *LinkedIdentityRepository) GetLinkedIdentitiesByUserID(userID uint) ([]*domain.LinkedIdentity, error) {
+	var identities []*domain.LinkedIdentity
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	if err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// DeleteLinkedIdentity отвязывает аккаунт провайдера от пользователя
+func (r *LinkedIdentityRepository) DeleteLinkedIdentity(userID uint, provider string) error {
+	return r.db.Where("user_id = ? AND provider = ?", userID, provider).Delete(&domain.LinkedIdentity{}).Error
+}
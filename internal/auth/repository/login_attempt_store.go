@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginAttemptStore реализация LoginAttemptStore на Redis
+type LoginAttemptStore struct {
+	redisClient *redis.Client
+}
+
+// NewLoginAttemptStore создает новое хранилище попыток входа
+func NewLoginAttemptStore(redisClient *redis.Client) *LoginAttemptStore {
+	return &LoginAttemptStore{redisClient: redisClient}
+}
+
+// makeFailuresKey создает ключ счетчика неудачных попыток
+func (s *LoginAttemptStore) makeFailuresKey(key string) string {
+	return fmt.Sprintf("auth:login:failures:%s", key)
+}
+
+// makeBlockKey создает ключ блокировки; блокировка действует, пока ключ существует
+func (s *LoginAttemptStore) makeBlockKey(key string) string {
+	return fmt.Sprintf("auth:login:block:%s", key)
+}
+
+// RegisterLoginFailure увеличивает счетчик; TTL выставляется только при первой неудаче (EXPIRE NX)
+func (s *LoginAttemptStore) RegisterLoginFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := s.redisClient.TxPipeline()
+	incr := pipe.Incr(ctx, s.makeFailuresKey(key))
+	pipe.ExpireNX(ctx, s.makeFailuresKey(key), window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to register login failure: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// ResetLoginFailures удаляет счетчик и блокировку
+func (s *LoginAttemptStore) ResetLoginFailures(ctx context.Context, key string) error {
+	return s.redisClient.Del(ctx, s.makeFailuresKey(key), s.makeBlockKey(key)).Err()
+}
+
+// BlockLogin блокирует попытки входа по ключу
+func (s *LoginAttemptStore) BlockLogin(ctx context.Context, key string, d time.Duration) error {
+	return s.redisClient.Set(ctx, s.makeBlockKey(key), 1, d).Err()
+}
+
+// LoginRetryAfter проверяет блокировки всех ключей за один round-trip
+func (s *LoginAttemptStore) LoginRetryAfter(ctx context.Context, keys ...string) (time.Duration, error) {
+	pipe := s.redisClient.Pipeline()
+	cmds := make([]*redis.DurationCmd, 0, len(keys))
+	for _, key := range keys {
+		cmds = append(cmds, pipe.PTTL(ctx, s.makeBlockKey(key)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to check login block: %w", err)
+	}
+
+	var retryAfter time.Duration
+	for _, cmd := range cmds {
+		// Для отсутствующего ключа PTTL возвращает отрицательное значение
+		if ttl := cmd.Val(); ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	return retryAfter, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MagicLinkStore реализация MagicLinkStore на Redis
+type MagicLinkStore struct {
+	redisClient *redis.Client
+}
+
+// NewMagicLinkStore создает новое хранилище ссылок для входа
+func NewMagicLinkStore(redisClient *redis.Client) *MagicLinkStore {
+	return &MagicLinkStore{redisClient: redisClient}
+}
+
+// makeKey создает ключ Redis для ссылки
+func (s *MagicLinkStore) makeKey(tokenID string) string {
+	return fmt.Sprintf("auth:magic:link:%s", tokenID)
+}
+
+// makeCooldownKey создает ключ Redis для ограничения частоты отправки
+func (s *MagicLinkStore) makeCooldownKey(userID uint) string {
+	return fmt.Sprintf("auth:magic:cooldown:%d", userID)
+}
+
+// SaveMagicLink сохраняет ссылку
+func (s *MagicLinkStore) SaveMagicLink(ctx context.Context, tokenID string, link *domain.MagicLink, ttl time.Duration) error {
+	payload, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, s.makeKey(tokenID), payload, ttl).Err()
+}
+
+// PopMagicLink атомарно читает и удаляет ссылку (GETDEL)
+func (s *MagicLinkStore) PopMagicLink(ctx context.Context, tokenID string) (*domain.MagicLink, error) {
+	payload, err := s.redisClient.GetDel(ctx, s.makeKey(tokenID)).Bytes()
+	if err == redis.Nil {
+		return nil, domain.ErrMagicLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var link domain.MagicLink
+	if err := json.Unmarshal(payload, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// AcquireMagicLinkCooldown занимает окно отправки через SET NX
+func (s *MagicLinkStore) AcquireMagicLinkCooldown(ctx context.Context, userID uint, cooldown time.Duration) (bool, error) {
+	return s.redisClient.SetNX(ctx, s.makeCooldownKey(userID), 1, cooldown).Result()
+}
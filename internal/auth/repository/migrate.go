@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+
+	"gorm.io/gorm"
+)
+
+// Migrate создает или обновляет таблицы модуля аутентификации
+// и заполняет роли по умолчанию
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&domain.RoleDefinition{},
+		&domain.RolePermission{},
+		&domain.User{},
+		&domain.RefreshToken{},
+		&domain.Session{},
+		&domain.RecoveryCode{},
+		&domain.WebAuthnCredential{},
+		&domain.LinkedIdentity{},
+		&domain.ServiceClient{},
+		&domain.APIKey{},
+	)
+	if err != nil {
+		return err
+	}
+
+	return seedRoles(db)
+}
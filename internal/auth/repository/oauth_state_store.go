@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OAuthStateStore реализация OAuthStateStore на Redis
+type OAuthStateStore struct {
+	redisClient *redis.Client
+}
+
+// NewOAuthStateStore создает новое хранилище OAuth state
+func NewOAuthStateStore(redisClient *redis.Client) *OAuthStateStore {
+	return &OAuthStateStore{redisClient: redisClient}
+}
+
+// makeKey создает ключ Redis для state
+func (s *OAuthStateStore) makeKey(state string) string {
+	return fmt.Sprintf("auth:oauth:state:%s", state)
+}
+
+// SaveOAuthState сохраняет данные авторизации
+func (s *OAuthStateStore) SaveOAuthState(ctx context.Context, state string, data *domain.OAuthState, ttl time.Duration) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, s.makeKey(state), payload, ttl).Err()
+}
+
+// PopOAuthState атомарно читает и удаляет данные авторизации (GETDEL)
+func (s *OAuthStateStore) PopOAuthState(ctx context.Context, state string) (*domain.OAuthState, error) {
+	payload, err := s.redisClient.GetDel(ctx, s.makeKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, domain.ErrOAuthStateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data domain.OAuthState
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
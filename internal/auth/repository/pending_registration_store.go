@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PendingRegistrationStore реализация PendingRegistrationStore на Redis
+type PendingRegistrationStore struct {
+	redisClient *redis.Client
+}
+
+// NewPendingRegistrationStore создает новое хранилище неподтвержденных регистраций
+func NewPendingRegistrationStore(redisClient *redis.Client) *PendingRegistrationStore {
+	return &PendingRegistrationStore{redisClient: redisClient}
+}
+
+// makeKey создает ключ Redis для регистрации
+func (s *PendingRegistrationStore) makeKey(id string) string {
+	return fmt.Sprintf("auth:registration:pending:%s", id)
+}
+
+// SavePendingRegistration сохраняет регистрацию до подтверждения телефона
+func (s *PendingRegistrationStore) SavePendingRegistration(ctx context.Context, id string, registration *domain.PendingRegistration, ttl time.Duration) error {
+	payload, err := json.Marshal(registration)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, s.makeKey(id), payload, ttl).Err()
+}
+
+// PopPendingRegistration атомарно читает и удаляет регистрацию (GETDEL)
+func (s *PendingRegistrationStore) PopPendingRegistration(ctx context.Context, id string) (*domain.PendingRegistration, error) {
+	payload, err := s.redisClient.GetDel(ctx, s.makeKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, domain.ErrPendingRegistrationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var registration domain.PendingRegistration
+	if err := json.Unmarshal(payload, &registration); err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository реализация RecoveryCodeRepository для PostgreSQL
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository создает новый репозиторий кодов восстановления
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// ReplaceRecoveryCodes заменяет коды пользователя в одной транзакции
+func (r *RecoveryCodeRepository) ReplaceRecoveryCodes(userID uint, codes []*domain.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode атомарно помечает код использованным
+func (r *RecoveryCodeRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountRemainingRecoveryCodes возвращает число неиспользованных кодов
+func (r *RecoveryCodeRepository) CountRemainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
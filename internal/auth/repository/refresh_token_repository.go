@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository реализация RefreshTokenRepository для PostgreSQL
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository создает новый репозиторий refresh токенов
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// CreateRefreshToken сохраняет новый refresh токен
+func (r *RefreshTokenRepository) CreateRefreshToken(token *domain.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetRefreshTokenByHash получает refresh токен по хешу
+func (r *RefreshTokenRepository) GetRefreshTokenByHash(hash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenRotated атомарно помечает токен использованным
+func (r *RefreshTokenRepository) MarkRefreshTokenRotated(id uint) (bool, error) {
+	result := r.db.Model(&domain.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeRefreshTokenFamily отзывает все токены семейства
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	return r.db.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserRefreshTokens отзывает все refresh токены пользователя
+func (r *RefreshTokenRepository) RevokeUserRefreshTokens(userID uint) error {
+	return r.db.Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleRepository реализация RoleRepository для PostgreSQL
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository создает новый репозиторий ролей
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// GetRoles возвращает все роли вместе с их собственными правами
+func (r *RoleRepository) GetRoles() ([]*domain.RoleDefinition, error) {
+	var roles []*domain.RoleDefinition
+	if err := r.db.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// seedRoles создает роли по умолчанию. Права записываются только вместе с новой ролью,
+// поэтому права, отозванные у существующей роли вручную, не возвращаются при перезапуске.
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, role := range domain.DefaultRoles() {
+			result := tx.Omit(clause.Associations).
+				Clauses(clause.OnConflict{DoNothing: true}).
+				Create(role)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 || len(role.Permissions) == 0 {
+				continue
+			}
+
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(role.Permissions).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ServiceClientRepository реализация ServiceClientRepository для PostgreSQL
+type ServiceClientRepository struct {
+	db *gorm.DB
+}
+
+// NewServiceClientRepository создает новый репозиторий сервисных клиентов
+func NewServiceClientRepository(db *gorm.DB) *ServiceClientRepository {
+	return &ServiceClientRepository{db: db}
+}
+
+// CreateServiceClient сохраняет нового клиента
+func (r *ServiceClientRepository) CreateServiceClient(client *domain.ServiceClient) error {
+	return r.db.Create(client).Error
+}
+
+// GetServiceClient получает клиента по ID
+func (r *ServiceClientRepository) GetServiceClient(id string) (*domain.ServiceClient, error) {
+	var client domain.ServiceClient
+	if err := r.db.Where("id = ?", id).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ListServiceClients получает всех клиентов, последние созданные — первыми
+func (r *ServiceClientRepository) ListServiceClients() ([]*domain.ServiceClient, error) {
+	var clients []*domain.ServiceClient
+	if err := r.db.Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// RevokeServiceClient атомарно отзывает клиента
+func (r *ServiceClientRepository) RevokeServiceClient(id string) (bool, error) {
+	result := r.db.Model(&domain.ServiceClient{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionRepository реализация SessionRepository для PostgreSQL
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository создает новый репозиторий сессий
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession сохраняет новую сессию
+func (r *SessionRepository) CreateSession(session *domain.Session) error {
+	return r.db.Create(session).Error
+}
+
+// GetActiveSessionsByUserID получает активные сессии, последние использованные — первыми
+func (r *SessionRepository) GetActiveSessionsByUserID(userID uint) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ExtendSession продлевает сессию и обновляет IP после ротации refresh токена
+func (r *SessionRepository) ExtendSession(id, ip string, lastSeenAt, expiresAt time.Time) error {
+	return r.db.Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"ip":           ip,
+			"last_seen_at": lastSeenAt,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// TouchSessions обновляет время активности в одной транзакции.
+// Время только увеличивается, поэтому запоздавшая запись не откатывает более свежую.
+func (r *SessionRepository) TouchSessions(lastSeen map[string]time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for id, seenAt := range lastSeen {
+			err := tx.Model(&domain.Session{}).
+				Where("id = ? AND last_seen_at < ?", id, seenAt).
+				Update("last_seen_at", seenAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RevokeSession атомарно отзывает сессию, если она принадлежит пользователю
+func (r *SessionRepository) RevokeSession(userID uint, id string) (bool, error) {
+	result := r.db.Model(&domain.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeUserSessions отзывает все сессии пользователя
+func (r *SessionRepository) RevokeUserSessions(userID uint) error {
+	return r.db.Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TokenRevocationStore реализация TokenRevocationStore на Redis
+type TokenRevocationStore struct {
+	redisClient *redis.Client
+}
+
+// NewTokenRevocationStore создает новое хранилище отозванных токенов
+func NewTokenRevocationStore(redisClient *redis.Client) *TokenRevocationStore {
+	return &TokenRevocationStore{redisClient: redisClient}
+}
+
+// makeTokenKey создает ключ denylist для jti
+func (s *TokenRevocationStore) makeTokenKey(jti string) string {
+	return fmt.Sprintf("auth:revoked:jti:%s", jti)
+}
+
+// makeUserKey создает ключ отметки времени отзыва для пользователя
+func (s *TokenRevocationStore) makeUserKey(userID uint) string {
+	return fmt.Sprintf("auth:revoked:user:%d", userID)
+}
+
+// makeSessionKey создает ключ denylist для сессии
+func (s *TokenRevocationStore) makeSessionKey(sessionID string) string {
+	return fmt.Sprintf("auth:revoked:session:%s", sessionID)
+}
+
+// RevokeToken добавляет jti в denylist
+func (s *TokenRevocationStore) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		// Токен уже истек, хранить его незачем
+		return nil
+	}
+	return s.redisClient.Set(ctx, s.makeTokenKey(jti), 1, ttl).Err()
+}
+
+// RevokeUserTokens сохраняет отметку времени, до которой токены пользователя недействительны.
+// ttl должен быть не меньше времени жизни access токена — после этого старые токены истекут сами.
+// Отметка хранится в наносекундах, чтобы отличать токены, выпущенные в ту же секунду (см. Claims.IssuedAtNano).
+func (s *TokenRevocationStore) RevokeUserTokens(ctx context.Context, userID uint, before time.Time, ttl time.Duration) error {
+	return s.redisClient.Set(ctx, s.makeUserKey(userID), before.UnixNano(), ttl).Err()
+}
+
+// RevokeSession добавляет сессию в denylist.
+// ttl должен быть не меньше времени жизни access токена; новые токены сессия уже не получит,
+// так как ее refresh токены отозваны.
+func (s *TokenRevocationStore) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return s.redisClient.Set(ctx, s.makeSessionKey(sessionID), 1, ttl).Err()
+}
+
+// IsTokenRevoked проверяет jti, сессию и отметку времени пользователя за один round-trip
+func (s *TokenRevocationStore) IsTokenRevoked(ctx context.Context, jti, sessionID string, userID uint, issuedAt time.Time) (bool, error) {
+	keys := []string{s.makeTokenKey(jti)}
+	if sessionID != "" {
+		keys = append(keys, s.makeSessionKey(sessionID))
+	}
+
+	pipe := s.redisClient.Pipeline()
+	deniedCmd := pipe.Exists(ctx, keys...)
+	userCmd := pipe.Get(ctx, s.makeUserKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	if deniedCmd.Val() > 0 {
+		return true, nil
+	}
+
+	value, err := userCmd.Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check user revocation: %w", err)
+	}
+
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid revocation timestamp: %w", err)
+	}
+
+	// Токены без iat_ns несут iat, усеченный до секунды, — выпущенные до отзыва тоже попадают сюда
+	return !issuedAt.After(time.Unix(0, revokedAt)), nil
+}
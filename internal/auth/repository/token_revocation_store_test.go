@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRevocationStore(t *testing.T) *TokenRevocationStore {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewTokenRevocationStore(client)
+}
+
+func TestRevokeUserTokens_SameSecond(t *testing.T) {
+	store := newTestRevocationStore(t)
+	ctx := context.Background()
+	const userID = 1
+
+	before := time.Now()
+	start := time.Now()
+	if err := store.RevokeUserTokens(ctx, userID, before, time.Minute); err != nil {
+		t.Fatalf("RevokeUserTokens: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("RevokeUserTokens must not block, took %v", elapsed)
+	}
+
+	tests := []struct {
+		name     string
+		userID   uint
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued just before", userID, before.Add(-time.Nanosecond), true},
+		{"issued at the revocation", userID, before, true},
+		{"issued just after", userID, before.Add(time.Nanosecond), false},
+		// Токен без iat_ns: iat усечен до секунды и не позже момента выпуска
+		{"second precision iat", userID, before.Truncate(time.Second), true},
+		{"other user", userID + 1, before.Add(-time.Nanosecond), false},
+	}
+	for _, tt := range tests {
+		revoked, err := store.IsTokenRevoked(ctx, "jti", "", tt.userID, tt.issuedAt)
+		if err != nil {
+			t.Fatalf("%s: IsTokenRevoked: %v", tt.name, err)
+		}
+		if revoked != tt.want {
+			t.Errorf("%s: revoked = %v; want %v", tt.name, revoked, tt.want)
+		}
+	}
+}
+
+func TestRevokeUserTokens_EarlierTokens(t *testing.T) {
+	store := newTestRevocationStore(t)
+	ctx := context.Background()
+
+	before := time.Now()
+	if err := store.RevokeUserTokens(ctx, 1, before, time.Minute); err != nil {
+		t.Fatalf("RevokeUserTokens: %v", err)
+	}
+
+	revoked, err := store.IsTokenRevoked(ctx, "jti", "", 1, before.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("IsTokenRevoked: %v", err)
+	}
+	if !revoked {
+		t.Fatal("token issued before the revocation must be revoked")
+	}
+}
+
+func TestRevokeToken_AndSession(t *testing.T) {
+	store := newTestRevocationStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := store.RevokeToken(ctx, "revoked-jti", time.Minute); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if err := store.RevokeSession(ctx, "revoked-session", time.Minute); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	tests := []struct {
+		jti, sessionID string
+		want           bool
+	}{
+		{"revoked-jti", "", true},
+		{"other-jti", "revoked-session", true},
+		{"other-jti", "other-session", false},
+		{"other-jti", "", false},
+	}
+	for _, tt := range tests {
+		revoked, err := store.IsTokenRevoked(ctx, tt.jti, tt.sessionID, 1, now)
+		if err != nil {
+			t.Fatalf("IsTokenRevoked: %v", err)
+		}
+		if revoked != tt.want {
+			t.Errorf("IsTokenRevoked(%q, %q) = %v; want %v", tt.jti, tt.sessionID, revoked, tt.want)
+		}
+	}
+}
@@ -1,43 +1,294 @@
-package repository
-
-import (
-	"nodabackend/internal/auth/domain"
-
-	"gorm.io/gorm"
-)
-
-// UserRepository реализация UserRepository для PostgreSQL
-type UserRepository struct {
-	db *gorm.DB
-}
-
-
-// NewUserRepository создает новый репозиторий с dependency injection
-func NewUserRepository(db *gorm.DB) *UserRepository {
-	return &UserRepository{db: db}
-}
-
-// CreateUser создает нового пользователя
-func (r *UserRepository) CreateUser(user *domain.User) error {
-	return r.db.Create(user).Error
-}
-
-// GetUserByPhone получает пользователя по телефону
-func (r *UserRepository) GetUserByPhone(phone string) (*domain.User, error) {
-	var user domain.User
-	err := r.db.Where("phone = ?", phone).First(&user).Error
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
-}
-
-// GetUserByID получает пользователя по ID
-func (r *UserRepository) GetUserByID(id uint) (*domain.User, error) {
-	var user domain.User
-	err := r.db.First(&user, id).Error
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
-}
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// pgUniqueViolation код ошибки PostgreSQL при нарушении уникального индекса
+const pgUniqueViolation = "23505"
+
+// UserRepository реализация UserRepository для PostgreSQL
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository создает новый репозиторий с dependency injection
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// CreateUser создает нового пользователя
+func (r *UserRepository) CreateUser(user *domain.User) error {
+	return translateUserError(r.db.Create(user).Error)
+}
+
+// GetUserByPhone получает пользователя по телефону
+func (r *UserRepository) GetUserByPhone(phone string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.Where("phone = ?", phone).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail получает пользователя по email
+func (r *UserRepository) GetUserByEmail(email string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// IsPhoneTaken проверяет, занят ли телефон. Мягко удаленные пользователи учитываются:
+// их телефон остается в уникальном индексе до обезличивания.
+func (r *UserRepository) IsPhoneTaken(phone string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&domain.User{}).Where("phone = ?", phone).Count(&count).Error
+	return count > 0, err
+}
+
+// IsEmailTaken проверяет, занят ли email, в том числе мягко удаленным пользователем
+func (r *UserRepository) IsEmailTaken(email string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&domain.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+// GetUserByID получает пользователя по ID
+func (r *UserRepository) GetUserByID(id uint) (*domain.User, error) {
+	var user domain.User
+	err := r.db.First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByIDWithDeleted получает пользователя по ID, в том числе мягко удаленного
+func (r *UserRepository) GetUserByIDWithDeleted(id uint) (*domain.User, error) {
+	var user domain.User
+	err := r.db.Unscoped().First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers возвращает страницу пользователей (новые — первыми) и общее число подходящих под фильтр
+func (r *UserRepository) ListUsers(filter domain.UserFilter) ([]*domain.User, int64, error) {
+	query := r.db.Model(&domain.User{})
+
+	switch filter.Status {
+	case domain.UserStatusActive:
+		query = query.Where("banned_at IS NULL")
+	case domain.UserStatusBanned:
+		query = query.Where("banned_at IS NOT NULL")
+	case domain.UserStatusDeleted:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		query = query.Where("phone ILIKE ? OR email ILIKE ? OR name ILIKE ?", pattern, pattern, pattern)
+	}
+
+	// Запрос используется дважды: для подсчета и для выборки страницы
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []*domain.User
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.PerPage).
+		Limit(filter.PerPage).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// UpdateUserRole меняет роль пользователя
+func (r *UserRepository) UpdateUserRole(id uint, role domain.Role) error {
+	return r.updateColumns(id, map[string]interface{}{"role": role})
+}
+
+// UpdateUserBan блокирует или разблокирует пользователя
+func (r *UserRepository) UpdateUserBan(id uint, bannedAt *time.Time, reason string) error {
+	return r.updateColumns(id, map[string]interface{}{"banned_at": bannedAt, "ban_reason": reason})
+}
+
+// UpdateUserLock блокирует вход по паролю до lockedUntil; nil снимает блокировку
+func (r *UserRepository) UpdateUserLock(id uint, lockedUntil *time.Time) error {
+	return r.updateColumns(id, map[string]interface{}{"locked_until": lockedUntil})
+}
+
+// RequirePasswordReset запрещает вход по паролю до сброса пароля
+func (r *UserRepository) RequirePasswordReset(id uint) error {
+	return r.updateColumns(id, map[string]interface{}{"password_reset_required": true})
+}
+
+// UpdateUserPassword сохраняет хеш нового пароля и снимает требование сброса
+func (r *UserRepository) UpdateUserPassword(id uint, passwordHash string) error {
+	return r.updateColumns(id, map[string]interface{}{"password": passwordHash, "password_reset_required": false})
+}
+
+// UpdateUserProfile сохраняет поля профиля, которые пользователь меняет сам
+func (r *UserRepository) UpdateUserProfile(user *domain.User) error {
+	return r.updateColumns(user.ID, map[string]interface{}{
+		"name":       user.Name,
+		"avatar_url": user.AvatarURL,
+		"locale":     user.Locale,
+		"timezone":   user.Timezone,
+	})
+}
+
+// UpdateUserPendingPhone сохраняет телефон, ожидающий подтверждения
+func (r *UserRepository) UpdateUserPendingPhone(id uint, phone *string) error {
+	return r.updateColumns(id, map[string]interface{}{"pending_phone": phone})
+}
+
+// UpdateUserPhone меняет подтвержденный телефон и сбрасывает ожидающий
+func (r *UserRepository) UpdateUserPhone(id uint, phone string, verifiedAt time.Time) error {
+	return r.updateColumns(id, map[string]interface{}{
+		"phone":             phone,
+		"phone_verified_at": verifiedAt,
+		"pending_phone":     nil,
+	})
+}
+
+// MarkPhoneVerified отмечает телефон подтвержденным
+func (r *UserRepository) MarkPhoneVerified(id uint, verifiedAt time.Time) error {
+	return r.updateColumns(id, map[string]interface{}{"phone_verified_at": verifiedAt})
+}
+
+// MarkEmailVerified отмечает email подтвержденным
+func (r *UserRepository) MarkEmailVerified(id uint, verifiedAt time.Time) error {
+	return r.updateColumns(id, map[string]interface{}{"email_verified_at": verifiedAt})
+}
+
+// UpdateTOTPSecret сохраняет новый TOTP секрет и сбрасывает последний принятый шаг
+func (r *UserRepository) UpdateTOTPSecret(id uint, secret string) error {
+	return r.updateColumns(id, map[string]interface{}{"totp_secret": secret, "totp_last_step": 0})
+}
+
+// EnableTOTP включает 2FA через приложение
+func (r *UserRepository) EnableTOTP(id uint, enabledAt time.Time) error {
+	return r.updateColumns(id, map[string]interface{}{"totp_enabled_at": enabledAt})
+}
+
+// updateColumns обновляет только перечисленные столбцы (и updated_at).
+// Save перезаписал бы всю строку и затер бы конкурентные изменения других полей.
+func (r *UserRepository) updateColumns(id uint, columns map[string]interface{}) error {
+	return translateUserError(r.db.Model(&domain.User{}).Where("id = ?", id).Updates(columns).Error)
+}
+
+// AdvanceTOTPStep атомарно сохраняет шаг TOTP условным UPDATE
+func (r *UserRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteUser мягко удаляет пользователя (заполняет deleted_at)
+func (r *UserRepository) DeleteUser(id uint) error {
+	return r.db.Delete(&domain.User{}, id).Error
+}
+
+// GetUsersDeletedBefore возвращает до limit удаленных раньше before и еще не обезличенных пользователей
+func (r *UserRepository) GetUsersDeletedBefore(before time.Time, limit int) ([]*domain.User, error) {
+	var users []*domain.User
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL", before).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// AnonymizeUser в одной транзакции стирает персональные данные пользователя и удаляет
+// его сессии, токены, ключи доступа, коды восстановления и привязки внешних аккаунтов.
+// Сама запись остается, чтобы не ломать ссылки на пользователя из других таблиц.
+func (r *UserRepository) AnonymizeUser(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&domain.Session{},
+			&domain.RefreshToken{},
+			&domain.RecoveryCode{},
+			&domain.WebAuthnCredential{},
+			&domain.LinkedIdentity{},
+			&domain.APIKey{},
+		} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// Телефон уникален и обязателен, поэтому заменяется заглушкой с ID
+		return tx.Unscoped().Model(&domain.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{
+				"phone":             fmt.Sprintf("deleted:%d", id),
+				"pending_phone":     nil,
+				"phone_verified_at": nil,
+				"email":             nil,
+				"email_verified_at": nil,
+				"password":          "",
+				"name":              "",
+				"avatar_url":        "",
+				"locale":            "",
+				"timezone":          "",
+				"totp_secret":       "",
+				"totp_enabled_at":   nil,
+				"ban_reason":        "",
+				"anonymized_at":     time.Now(),
+			}).Error
+	})
+}
+
+// translateUserError сводит нарушение уникальности телефона или email к ошибкам домена.
+// Проверки перед записью не спасают от гонки, поэтому последним судьей остается индекс.
+func translateUserError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgUniqueViolation {
+		return err
+	}
+
+	switch pgErr.ConstraintName {
+	case "idx_users_phone":
+		return domain.ErrPhoneTaken
+	case "idx_users_email":
+		return domain.ErrEmailTaken
+	default:
+		return err
+	}
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE, чтобы поиск шел по подстроке как есть
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
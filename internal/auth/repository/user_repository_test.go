@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestTranslateUserError(t *testing.T) {
+	other := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"phone index", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_users_phone"}, domain.ErrPhoneTaken},
+		{"email index", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_users_email"}, domain.ErrEmailTaken},
+		{"wrapped", fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_users_email"}), domain.ErrEmailTaken},
+		{"other error", other, other},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := translateUserError(tt.err); !errors.Is(got, tt.want) {
+			t.Errorf("%s: translateUserError = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Другие уникальные индексы не выдаются за занятый телефон или email
+	other23505 := &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_users_other"}
+	if got := translateUserError(other23505); got != error(other23505) {
+		t.Errorf("unknown constraint: translateUserError = %v", got)
+	}
+}
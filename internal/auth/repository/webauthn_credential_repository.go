@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"nodabackend/internal/auth/domain"
+
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredentialRepository реализация WebAuthnCredentialRepository для PostgreSQL
+type WebAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCredentialRepository создает новый репозиторий ключей доступа
+func NewWebAuthnCredentialRepository(db *gorm.DB) *WebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{db: db}
+}
+
+// CreateWebAuthnCredential сохраняет новый ключ доступа
+func (r *WebAuthnCredentialRepository) CreateWebAuthnCredential(credential *domain.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+// GetWebAuthnCredentialsByUserID получает все ключи доступа пользователя
+func (r *WebAuthnCredentialRepository) GetWebAuthnCredentialsByUserID(userID uint) ([]*domain.WebAuthnCredential, error) {
+	var credentials []*domain.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error
+	if err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// UpdateWebAuthnCredential обновляет ключ доступа (счетчик подписей, флаги, время использования)
+func (r *WebAuthnCredentialRepository) UpdateWebAuthnCredential(credential *domain.WebAuthnCredential) error {
+	return r.db.Save(credential).Error
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// WebAuthnSessionStore реализация WebAuthnSessionStore на Redis
+type WebAuthnSessionStore struct {
+	redisClient *redis.Client
+}
+
+// NewWebAuthnSessionStore создает новое хранилище WebAuthn церемоний
+func NewWebAuthnSessionStore(redisClient *redis.Client) *WebAuthnSessionStore {
+	return &WebAuthnSessionStore{redisClient: redisClient}
+}
+
+// makeKey создает ключ Redis для церемонии
+func (s *WebAuthnSessionStore) makeKey(key string) string {
+	return fmt.Sprintf("auth:webauthn:%s", key)
+}
+
+// SaveWebAuthnSession сохраняет данные церемонии
+func (s *WebAuthnSessionStore) SaveWebAuthnSession(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return s.redisClient.Set(ctx, s.makeKey(key), data, ttl).Err()
+}
+
+// PopWebAuthnSession атомарно читает и удаляет данные церемонии (GETDEL)
+func (s *WebAuthnSessionStore) PopWebAuthnSession(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.redisClient.GetDel(ctx, s.makeKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, domain.ErrWebAuthnSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
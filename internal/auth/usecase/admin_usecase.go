@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"time"
+)
+
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+	maxBanReasonLength  = 255
+)
+
+var (
+	// ErrUserNotFound пользователь не найден
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserBanned пользователь заблокирован администратором
+	ErrUserBanned = errors.New("account is banned")
+	// ErrPasswordResetRequired администратор потребовал сбросить пароль перед входом
+	ErrPasswordResetRequired = errors.New("password reset required")
+	// ErrCannotModifySelf администратор пытается применить действие к своему аккаунту
+	ErrCannotModifySelf = errors.New("cannot perform this action on your own account")
+)
+
+// UserPage страница списка пользователей
+type UserPage struct {
+	Users   []*domain.User `json:"users"`
+	Page    int            `json:"page"`
+	PerPage int            `json:"per_page"`
+	Total   int64          `json:"total"`
+}
+
+// ListUsers возвращает страницу пользователей по фильтру (для администратора)
+func (uc *AuthUseCase) ListUsers(filter domain.UserFilter) (*UserPage, error) {
+	switch filter.Status {
+	case "", domain.UserStatusActive, domain.UserStatusBanned, domain.UserStatusDeleted:
+	default:
+		return nil, errors.New("invalid status filter")
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultUsersPerPage
+	}
+	if filter.PerPage > maxUsersPerPage {
+		filter.PerPage = maxUsersPerPage
+	}
+
+	users, total, err := uc.userRepo.ListUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		user.Password = ""
+	}
+
+	return &UserPage{
+		Users:   users,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+		Total:   total,
+	}, nil
+}
+
+// GetUser возвращает пользователя для администратора, в том числе удаленного
+func (uc *AuthUseCase) GetUser(userID uint) (*domain.User, error) {
+	user, err := uc.userRepo.GetUserByIDWithDeleted(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// ChangeUserRole назначает пользователю роль из базы.
+// Выданные access токены отзываются, чтобы новая роль применилась при следующем обновлении токенов;
+// сессии при этом сохраняются.
+func (uc *AuthUseCase) ChangeUserRole(ctx context.Context, adminID, userID uint, role domain.Role) (*domain.User, error) {
+	if adminID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	if err := uc.validateRole(role); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.Role = role
+	if err := uc.userRepo.UpdateUserRole(user.ID, role); err != nil {
+		return nil, err
+	}
+
+	if err := uc.revocationStore.RevokeUserTokens(ctx, user.ID, time.Now(), uc.accessRevocationTTL()); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] Admin %d changed role of user %d to %s", adminID, user.ID, role)
+
+	user.Password = ""
+	return user, nil
+}
+
+// BanUser блокирует пользователя и завершает все его сессии.
+// Заблокированный пользователь не может войти никаким способом.
+func (uc *AuthUseCase) BanUser(ctx context.Context, adminID, userID uint, reason string) (*domain.User, error) {
+	if adminID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	now := time.Now()
+	user.BannedAt = &now
+	user.BanReason = truncate(reason, maxBanReasonLength)
+	if err := uc.userRepo.UpdateUserBan(user.ID, user.BannedAt, user.BanReason); err != nil {
+		return nil, err
+	}
+
+	if err := uc.revokeAllSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] Admin %d banned user %d", adminID, user.ID)
+
+	user.Password = ""
+	return user, nil
+}
+
+// UnbanUser снимает блокировку, установленную администратором
+func (uc *AuthUseCase) UnbanUser(adminID, userID uint) (*domain.User, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.BannedAt = nil
+	user.BanReason = ""
+	if err := uc.userRepo.UpdateUserBan(user.ID, nil, ""); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] Admin %d unbanned user %d", adminID, user.ID)
+
+	user.Password = ""
+	return user, nil
+}
+
+// ForcePasswordReset завершает все сессии пользователя и запрещает вход по паролю,
+// пока пароль не будет сброшен через /auth/password/forgot
+func (uc *AuthUseCase) ForcePasswordReset(ctx context.Context, adminID, userID uint) (*domain.User, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.PasswordResetRequired = true
+	if err := uc.userRepo.RequirePasswordReset(user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.revokeAllSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] Admin %d forced password reset for user %d", adminID, user.ID)
+
+	user.Password = ""
+	return user, nil
+}
+
+// RevokeUserSessions завершает все сессии пользователя на всех устройствах
+func (uc *AuthUseCase) RevokeUserSessions(ctx context.Context, adminID, userID uint) error {
+	if _, err := uc.userRepo.GetUserByID(userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := uc.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	log.Printf("[AUTH] Admin %d revoked all sessions of user %d", adminID, userID)
+	return nil
+}
+
+// DeleteUser мягко удаляет пользователя и завершает все его сессии.
+// Запись остается в базе и доступна администратору через GetUser.
+func (uc *AuthUseCase) DeleteUser(ctx context.Context, adminID, userID uint) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+
+	if _, err := uc.userRepo.GetUserByID(userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if err := uc.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.DeleteUser(userID); err != nil {
+		return err
+	}
+
+	log.Printf("[AUTH] Admin %d deleted user %d", adminID, userID)
+	return nil
+}
+
+// validateRole проверяет, что роль существует в базе
+func (uc *AuthUseCase) validateRole(role domain.Role) error {
+	if role == "" {
+		return errors.New("role is required")
+	}
+
+	roles, err := uc.roleRepo.GetRoles()
+	if err != nil {
+		return err
+	}
+	for _, definition := range roles {
+		if definition.Name == role {
+			return nil
+		}
+	}
+	return errors.New("role not found")
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const adminTestID uint = 1000
+
+// newAdminTestUseCase создает usecase с пользователем, denylist и счетчиками попыток в miniredis
+func newAdminTestUseCase(t *testing.T) (*AuthUseCase, *repository.TokenRevocationStore, *domain.User) {
+	t.Helper()
+
+	uc, store, user, _ := newRevocationTestUseCase(t)
+	return uc, store, user
+}
+
+// newRevocationTestUseCase как newAdminTestUseCase, но возвращает и miniredis для управления временем
+func newRevocationTestUseCase(t *testing.T) (*AuthUseCase, *repository.TokenRevocationStore, *domain.User, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginTestPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Password: string(hash), Role: domain.UserRole}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	revocationStore := repository.NewTokenRevocationStore(client)
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		RoleRepo:         fakeRoleRepository{},
+		RevocationStore:  revocationStore,
+		LoginAttempts:    repository.NewLoginAttemptStore(client),
+		JWTHelper:        testJWTHelper,
+	}, DefaultConfig())
+
+	return uc, revocationStore, user, mr
+}
+
+// isAccessTokenRevoked проверяет access токен так же, как AuthMiddleware
+func isAccessTokenRevoked(t *testing.T, store *repository.TokenRevocationStore, token string) bool {
+	t.Helper()
+
+	claims, err := testJWTHelper.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	revoked, err := store.IsTokenRevoked(context.Background(), claims.ID, claims.SessionID, claims.UserID, claims.IssuedAtTime())
+	if err != nil {
+		t.Fatalf("IsTokenRevoked: %v", err)
+	}
+	return revoked
+}
+
+func TestAdmin_BanBlocksLoginAndRefresh(t *testing.T) {
+	uc, store, user := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	tokens, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+
+	if _, err := uc.BanUser(ctx, adminTestID, user.ID, "spam"); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+
+	if !isAccessTokenRevoked(t, store, tokens.Token) {
+		t.Fatal("access token must be revoked after ban")
+	}
+	// Бан отзывает refresh токены, так что обновить сессию нельзя
+	if _, err := uc.RefreshTokens(ctx, tokens.RefreshToken); err == nil {
+		t.Fatal("refresh of banned user must fail")
+	}
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1"); !errors.Is(err, ErrUserBanned) {
+		t.Fatalf("login of banned user: expected ErrUserBanned, got %v", err)
+	}
+
+	if _, err := uc.UnbanUser(adminTestID, user.ID); err != nil {
+		t.Fatalf("UnbanUser: %v", err)
+	}
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1"); err != nil {
+		t.Fatalf("login after unban: %v", err)
+	}
+}
+
+func TestAdmin_ChangeRole(t *testing.T) {
+	uc, store, user := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	if _, err := uc.ChangeUserRole(ctx, adminTestID, user.ID, "superuser"); err == nil {
+		t.Fatal("unknown role must be rejected")
+	}
+	if _, err := uc.ChangeUserRole(ctx, user.ID, user.ID, domain.AdminRole); !errors.Is(err, ErrCannotModifySelf) {
+		t.Fatalf("expected ErrCannotModifySelf, got %v", err)
+	}
+
+	tokens, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+
+	if _, err := uc.ChangeUserRole(ctx, adminTestID, user.ID, domain.ModeratorRole); err != nil {
+		t.Fatalf("ChangeUserRole: %v", err)
+	}
+	if !isAccessTokenRevoked(t, store, tokens.Token) {
+		t.Fatal("access token with the old role must be revoked")
+	}
+
+	// Сессия сохраняется: новый access токен получает новую роль
+	refreshed, err := uc.RefreshTokens(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	claims, err := testJWTHelper.ValidateToken(refreshed.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Role != string(domain.ModeratorRole) {
+		t.Fatalf("role = %q, want %q", claims.Role, domain.ModeratorRole)
+	}
+	if isAccessTokenRevoked(t, store, refreshed.Token) {
+		t.Fatal("access token issued after the role change must stay valid")
+	}
+}
+
+func TestAdmin_ForcePasswordReset(t *testing.T) {
+	uc, _, user := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	if _, err := uc.ForcePasswordReset(ctx, adminTestID, user.ID); err != nil {
+		t.Fatalf("ForcePasswordReset: %v", err)
+	}
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1"); !errors.Is(err, ErrPasswordResetRequired) {
+		t.Fatalf("expected ErrPasswordResetRequired, got %v", err)
+	}
+
+	stored, _ := uc.userRepo.GetUserByID(user.ID)
+	if err := uc.setPassword(stored, "new-password"); err != nil {
+		t.Fatalf("setPassword: %v", err)
+	}
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, "new-password", "10.0.0.1"); err != nil {
+		t.Fatalf("login after reset: %v", err)
+	}
+}
+
+func TestAdmin_DeleteUser(t *testing.T) {
+	uc, _, user := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	if err := uc.DeleteUser(ctx, adminTestID, user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1"); err == nil {
+		t.Fatal("deleted user must not be able to log in")
+	}
+
+	deleted, err := uc.GetUser(user.ID)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if !deleted.DeletedAt.Valid {
+		t.Fatal("deleted user must be returned with deleted_at")
+	}
+
+	page, err := uc.ListUsers(domain.UserFilter{})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if page.Total != 0 || page.Page != 1 || page.PerPage != defaultUsersPerPage {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+}
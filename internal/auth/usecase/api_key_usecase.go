@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"errors"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"strings"
+	"time"
+)
+
+const (
+	maxAPIKeyNameLength = 100
+	maxAPIKeysPerUser   = 25
+	apiKeyPrefixLength  = 8 // символов ключа после APIKeyPrefix, сохраняемых для отображения
+)
+
+// ErrAPIKeyNotFound ключ не найден или уже отозван
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyCredentials новый ключ вместе с его значением. Значение показывается только один раз.
+type APIKeyCredentials struct {
+	APIKey *domain.APIKey `json:"api_key"`
+	Key    string         `json:"key"`
+}
+
+// CreateAPIKey создает персональный API ключ пользователя.
+// Scopes — права роли, которые разрешены ключу; хотя бы одно обязательно.
+// nil expiresAt — бессрочный ключ.
+func (uc *AuthUseCase) CreateAPIKey(userID uint, name string, scopes []string, expiresAt *time.Time) (*APIKeyCredentials, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return nil, errors.New("name is too long")
+	}
+
+	scope, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if scope == "" {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("expiration must be in the future")
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsBanned() {
+		return nil, ErrUserBanned
+	}
+
+	existing, err := uc.apiKeyRepo.GetAPIKeysByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAPIKeysPerUser {
+		return nil, errors.New("too many api keys, revoke unused ones first")
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	key := domain.APIKeyPrefix + secret
+
+	apiKey := &domain.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:len(domain.APIKeyPrefix)+apiKeyPrefixLength],
+		KeyHash:   domain.HashAPIKey(key),
+		Scope:     scope,
+		ExpiresAt: expiresAt,
+	}
+	if err := uc.apiKeyRepo.CreateAPIKey(apiKey); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] API key %d (%s) created for user %d", apiKey.ID, apiKey.Prefix, userID)
+	return &APIKeyCredentials{APIKey: apiKey, Key: key}, nil
+}
+
+// GetAPIKeys возвращает неотозванные ключи пользователя
+func (uc *AuthUseCase) GetAPIKeys(userID uint) ([]*domain.APIKey, error) {
+	return uc.apiKeyRepo.GetAPIKeysByUserID(userID)
+}
+
+// RevokeAPIKey отзывает ключ пользователя. Ключ перестает приниматься сразу,
+// так как AuthMiddleware проверяет ключ по базе на каждом запросе.
+func (uc *AuthUseCase) RevokeAPIKey(userID, keyID uint) error {
+	revoked, err := uc.apiKeyRepo.RevokeAPIKey(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	log.Printf("[AUTH] API key %d revoked by user %d", keyID, userID)
+	return nil
+}
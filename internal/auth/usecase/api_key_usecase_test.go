@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAPIKeyTestUseCase создает usecase с пользователем и API ключами в памяти
+func newAPIKeyTestUseCase(t *testing.T) (*AuthUseCase, *fakeAPIKeyRepository, *domain.User) {
+	t.Helper()
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Role: domain.UserRole}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	keys := &fakeAPIKeyRepository{}
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:   users,
+		APIKeyRepo: keys,
+		JWTHelper:  testJWTHelper,
+	}, DefaultConfig())
+
+	return uc, keys, user
+}
+
+func TestAPIKey_CreateStoresHashOnly(t *testing.T) {
+	uc, keys, user := newAPIKeyTestUseCase(t)
+
+	if _, err := uc.CreateAPIKey(user.ID, "ci", nil, nil); err == nil {
+		t.Fatal("expected error for a key without scopes")
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, err := uc.CreateAPIKey(user.ID, "ci", []string{"profile:read"}, &past); err == nil {
+		t.Fatal("expected error for an expiration in the past")
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	credentials, err := uc.CreateAPIKey(user.ID, "ci", []string{"profile:read", "profile:write"}, &expiresAt)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	key := credentials.Key
+	if !strings.HasPrefix(key, domain.APIKeyPrefix) || !strings.HasPrefix(key, credentials.APIKey.Prefix) {
+		t.Fatalf("unexpected key %q with prefix %q", key, credentials.APIKey.Prefix)
+	}
+
+	stored, err := keys.GetAPIKeyByHash(domain.HashAPIKey(key))
+	if err != nil {
+		t.Fatalf("key is not found by its hash: %v", err)
+	}
+	if stored.KeyHash == key || stored.Scope != "profile:read profile:write" || !stored.IsActive(time.Now()) {
+		t.Fatalf("unexpected stored key: %+v", stored)
+	}
+	if stored.IsActive(expiresAt.Add(time.Second)) {
+		t.Fatal("key must expire")
+	}
+}
+
+func TestAPIKey_Revoke(t *testing.T) {
+	uc, keys, user := newAPIKeyTestUseCase(t)
+
+	credentials, err := uc.CreateAPIKey(user.ID, "script", []string{"profile:read"}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	keyID := credentials.APIKey.ID
+
+	// Чужой ключ отозвать нельзя
+	if err := uc.RevokeAPIKey(user.ID+1, keyID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected not found for another user, got %v", err)
+	}
+
+	if err := uc.RevokeAPIKey(user.ID, keyID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if err := uc.RevokeAPIKey(user.ID, keyID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected not found on second revoke, got %v", err)
+	}
+
+	stored, _ := keys.GetAPIKeyByHash(domain.HashAPIKey(credentials.Key))
+	if stored.IsActive(time.Now()) {
+		t.Fatal("revoked key must be inactive")
+	}
+	list, err := uc.GetAPIKeys(user.ID)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("revoked key must not be listed: %v, %d keys", err, len(list))
+	}
+}
@@ -1,50 +1,169 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/encryption"
 	"nodabackend/pkg/jwthelper"
 	"nodabackend/pkg/mailer"
+	"nodabackend/pkg/otp"
+	"nodabackend/pkg/sms"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthResponse ответ при успешной аутентификации
 type AuthResponse struct {
-	User  *domain.User `json:"user"`
-	Token string       `json:"token"`
+	User                 *domain.User `json:"user,omitempty"`
+	Token                string       `json:"token,omitempty"`
+	RefreshToken         string       `json:"refresh_token,omitempty"`
+	ExpiresIn            int64        `json:"expires_in,omitempty"`            // время жизни access токена в секундах
+	RefreshExpiresIn     int64        `json:"refresh_expires_in,omitempty"`    // время жизни refresh токена в секундах
+	VerificationRequired bool         `json:"verification_required,omitempty"` // токены будут выданы после подтверждения телефона
+	MFARequired          bool         `json:"mfa_required,omitempty"`          // токены будут выданы после проверки второго фактора
+	MFAToken             string       `json:"mfa_token,omitempty"`             // challenge токен для /auth/2fa/verify
+	RegistrationID       string       `json:"registration_id,omitempty"`       // передается в /auth/register/confirm вместе с кодом
+}
+
+// ErrPhoneVerificationRequired вход невозможен, пока не подтвержден телефон.
+// Код подтверждения отправляется повторно, токены выдаются в ConfirmRegistration.
+var ErrPhoneVerificationRequired = errors.New("phone verification required")
+
+// pendingRegistrationTTL время, в течение которого можно подтвердить регистрацию
+const pendingRegistrationTTL = 30 * time.Minute
+
+// phoneRegex базовая проверка формата телефона (E.164)
+var phoneRegex = regexp.MustCompile(`^\+?[1-9]\d{1,14}$`)
+
+// Dependencies зависимости AuthUseCase
+type Dependencies struct {
+	UserRepo           domain.UserRepository
+	RefreshTokenRepo   domain.RefreshTokenRepository
+	SessionRepo        domain.SessionRepository
+	RoleRepo           domain.RoleRepository
+	RecoveryCodeRepo   domain.RecoveryCodeRepository
+	RevocationStore    domain.TokenRevocationStore
+	WebAuthnRepo       domain.WebAuthnCredentialRepository
+	WebAuthnSessions   domain.WebAuthnSessionStore
+	LinkedIdentityRepo domain.LinkedIdentityRepository
+	OAuthStates        domain.OAuthStateStore
+	OAuthProviders     []domain.OAuthProvider
+	MagicLinks         domain.MagicLinkStore
+	LoginAttempts      domain.LoginAttemptStore
+	Registrations      domain.PendingRegistrationStore
+	ServiceClientRepo  domain.ServiceClientRepository
+	APIKeyRepo         domain.APIKeyRepository
+	JWTHelper          *jwthelper.JWTHelper
+	Mailer             mailer.Mailer
+	OTPService         otp.OTPService
+	SMSService         sms.SMSService
+	Encryptor          *encryption.Encryptor
 }
 
 // AuthUseCase бизнес-логика аутентификации
 type AuthUseCase struct {
-	userRepo  domain.UserRepository
-	jwtHelper *jwthelper.JWTHelper
-	mailer    mailer.Mailer
+	userRepo           domain.UserRepository
+	refreshTokenRepo   domain.RefreshTokenRepository
+	sessionRepo        domain.SessionRepository
+	roleRepo           domain.RoleRepository
+	recoveryCodeRepo   domain.RecoveryCodeRepository
+	revocationStore    domain.TokenRevocationStore
+	webAuthnRepo       domain.WebAuthnCredentialRepository
+	webAuthnSessions   domain.WebAuthnSessionStore
+	webAuthn           *webauthn.WebAuthn
+	linkedIdentityRepo domain.LinkedIdentityRepository
+	oauthStates        domain.OAuthStateStore
+	oauthProviders     map[string]domain.OAuthProvider
+	magicLinks         domain.MagicLinkStore
+	loginAttempts      domain.LoginAttemptStore
+	registrations      domain.PendingRegistrationStore
+	serviceClientRepo  domain.ServiceClientRepository
+	apiKeyRepo         domain.APIKeyRepository
+	jwtHelper          *jwthelper.JWTHelper
+	mailer             mailer.Mailer
+	otpService         otp.OTPService
+	smsService         sms.SMSService
+	encryptor          *encryption.Encryptor
+	config             *Config
+	background         sync.WaitGroup // фоновые отправки кодов (см. runInBackground)
 }
 
 // NewAuthUseCase создает новый usecase
-func NewAuthUseCase(repo domain.UserRepository, jwtHelper *jwthelper.JWTHelper, mailer mailer.Mailer) *AuthUseCase {
+func NewAuthUseCase(deps Dependencies, config *Config) *AuthUseCase {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	// Без корректной конфигурации WebAuthn остальная аутентификация продолжает работать
+	webAuthn, err := newWebAuthn(config)
+	if err != nil {
+		log.Printf("[AUTH] Passkeys are disabled: %v", err)
+	}
+
+	oauthProviders := make(map[string]domain.OAuthProvider, len(deps.OAuthProviders))
+	for _, provider := range deps.OAuthProviders {
+		oauthProviders[provider.Name()] = provider
+	}
+
 	return &AuthUseCase{
-		userRepo:  repo,
-		jwtHelper: jwtHelper,
-		mailer:    mailer,
+		userRepo:           deps.UserRepo,
+		refreshTokenRepo:   deps.RefreshTokenRepo,
+		sessionRepo:        deps.SessionRepo,
+		roleRepo:           deps.RoleRepo,
+		recoveryCodeRepo:   deps.RecoveryCodeRepo,
+		revocationStore:    deps.RevocationStore,
+		webAuthnRepo:       deps.WebAuthnRepo,
+		webAuthnSessions:   deps.WebAuthnSessions,
+		webAuthn:           webAuthn,
+		linkedIdentityRepo: deps.LinkedIdentityRepo,
+		oauthStates:        deps.OAuthStates,
+		oauthProviders:     oauthProviders,
+		magicLinks:         deps.MagicLinks,
+		loginAttempts:      deps.LoginAttempts,
+		registrations:      deps.Registrations,
+		serviceClientRepo:  deps.ServiceClientRepo,
+		apiKeyRepo:         deps.APIKeyRepo,
+		jwtHelper:          deps.JWTHelper,
+		mailer:             deps.Mailer,
+		otpService:         deps.OTPService,
+		smsService:         deps.SMSService,
+		encryptor:          deps.Encryptor,
+		config:             config,
 	}
 }
 
-// RegisterUser регистрирует нового пользователя
-func (uc *AuthUseCase) RegisterUser(phone, password, name string) (*AuthResponse, error) {
+// runInBackground выполняет fn в отдельной горутине, не задерживая ответ клиенту
+func (uc *AuthUseCase) runInBackground(fn func()) {
+	uc.background.Add(1)
+	go func() {
+		defer uc.background.Done()
+		fn()
+	}()
+}
+
+// RegisterUser регистрирует нового пользователя.
+// Email необязателен; если он указан, на него отправляется код подтверждения.
+// Если включено подтверждение телефона, регистрация сохраняется до ввода кода из SMS:
+// пользователь создается и получает токены только в ConfirmRegistration.
+func (uc *AuthUseCase) RegisterUser(ctx context.Context, phone, email, password, name string) (*AuthResponse, error) {
 	// Валидация входных данных
 	if err := uc.validateRegisterData(phone, password, name); err != nil {
 		return nil, err
 	}
 
-	// Проверяем, существует ли пользователь
-	existingUser, _ := uc.userRepo.GetUserByPhone(phone)
-	if existingUser != nil {
-		return nil, errors.New("user already exists")
+	email = normalizeEmail(email)
+	if email != "" {
+		if err := validateEmail(email); err != nil {
+			return nil, err
+		}
 	}
 
 	// Хешируем пароль
@@ -53,64 +172,242 @@ func (uc *AuthUseCase) RegisterUser(phone, password, name string) (*AuthResponse
 		return nil, err
 	}
 
-	// Создаем пользователя
+	registration := &domain.PendingRegistration{
+		Phone:        phone,
+		Email:        email,
+		PasswordHash: string(hashedPassword),
+		Name:         name,
+	}
+	if err := uc.checkRegistrationConflicts(registration); err != nil {
+		return nil, err
+	}
+
+	if uc.config.RequirePhoneVerification {
+		return uc.startPendingRegistration(ctx, registration)
+	}
+
+	user, err := uc.createRegisteredUser(ctx, registration, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Выдаем пару access/refresh токенов
+	return uc.issueTokens(ctx, user, "")
+}
+
+// ConfirmRegistration подтверждает телефон кодом из SMS и выдает токены.
+// С registrationID создается пользователь из неподтвержденной регистрации.
+// Без него подтверждается телефон существующего пользователя, которому отказано во входе
+// с ErrPhoneVerificationRequired (например, зарегистрированного до включения подтверждения).
+func (uc *AuthUseCase) ConfirmRegistration(ctx context.Context, phone, code, registrationID string) (*AuthResponse, error) {
+	if err := validatePhone(phone); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("code is required")
+	}
+
+	valid, err := uc.otpService.ValidateOTP(ctx, phone, otp.OTPTypeVerifyPhone, code)
+	if err != nil || !valid {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	if registrationID == "" {
+		user, err := uc.userRepo.GetUserByPhone(phone)
+		if err != nil {
+			return nil, errors.New("invalid or expired code")
+		}
+		if err := uc.markPhoneVerified(user); err != nil {
+			return nil, err
+		}
+		// Код подтверждает только телефон, 2FA по-прежнему требуется
+		return uc.completeLogin(ctx, user)
+	}
+
+	registration, err := uc.registrations.PopPendingRegistration(ctx, registrationID)
+	if errors.Is(err, domain.ErrPendingRegistrationNotFound) {
+		return nil, errors.New("invalid or expired registration")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if registration.Phone != phone {
+		return nil, errors.New("invalid or expired registration")
+	}
+
+	// Телефон или email могли занять, пока регистрация ждала подтверждения
+	if err := uc.checkRegistrationConflicts(registration); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user, err := uc.createRegisteredUser(ctx, registration, &now)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.issueTokens(ctx, user, "")
+}
+
+// checkRegistrationConflicts проверяет, что телефон и email еще не заняты,
+// в том числе удаленными пользователями, чьи данные еще не обезличены
+func (uc *AuthUseCase) checkRegistrationConflicts(registration *domain.PendingRegistration) error {
+	taken, err := uc.userRepo.IsPhoneTaken(registration.Phone)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.New("user already exists")
+	}
+
+	if registration.Email != "" {
+		taken, err := uc.userRepo.IsEmailTaken(registration.Email)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return domain.ErrEmailTaken
+		}
+	}
+
+	return nil
+}
+
+// createRegisteredUser создает пользователя и отправляет код подтверждения на email
+func (uc *AuthUseCase) createRegisteredUser(ctx context.Context, registration *domain.PendingRegistration, phoneVerifiedAt *time.Time) (*domain.User, error) {
 	user := &domain.User{
-		Phone:    phone,
-		Password: string(hashedPassword),
-		Name:     name,
+		Phone:           registration.Phone,
+		Password:        registration.PasswordHash,
+		Name:            registration.Name,
+		PhoneVerifiedAt: phoneVerifiedAt,
 	}
+	setUserEmail(user, registration.Email)
 
 	if err := uc.userRepo.CreateUser(user); err != nil {
 		return nil, err
 	}
 
-	// Генерируем JWT токен
-	token, err := uc.jwtHelper.GenerateToken(user.ID, user.Phone, string(user.Role))
+	uc.sendEmailVerification(ctx, user)
+	return user, nil
+}
+
+// startPendingRegistration сохраняет регистрацию и отправляет код подтверждения телефона.
+// Каждая регистрация получает свой ID: повторная регистрация того же номера
+// не может подменить данные уже начатой.
+func (uc *AuthUseCase) startPendingRegistration(ctx context.Context, registration *domain.PendingRegistration) (*AuthResponse, error) {
+	registrationID, err := randomToken(24)
 	if err != nil {
 		return nil, err
 	}
 
-	// Очищаем пароль перед возвратом
-	user.Password = ""
+	if err := uc.sendPhoneVerificationCode(ctx, registration.Phone); err != nil {
+		return nil, err
+	}
+
+	if err := uc.registrations.SavePendingRegistration(ctx, registrationID, registration, pendingRegistrationTTL); err != nil {
+		return nil, err
+	}
 
 	return &AuthResponse{
-		User:  user,
-		Token: token,
+		VerificationRequired: true,
+		RegistrationID:       registrationID,
 	}, nil
 }
 
-// AuthenticateUser аутентифицирует пользователя
-func (uc *AuthUseCase) AuthenticateUser(phone, password string) (*AuthResponse, error) {
+// requireVerifiedPhone запрещает вход с неподтвержденным телефоном, пока подтверждение обязательно.
+// Пользователю отправляется код, с которым он завершает вход через ConfirmRegistration.
+func (uc *AuthUseCase) requireVerifiedPhone(ctx context.Context, user *domain.User) error {
+	if !uc.config.RequirePhoneVerification || user.IsPhoneVerified() {
+		return nil
+	}
+
+	if err := uc.sendPhoneVerificationCode(ctx, user.Phone); err != nil {
+		log.Printf("[AUTH] Failed to send phone verification code to user %d: %v", user.ID, err)
+	}
+	return ErrPhoneVerificationRequired
+}
+
+// sendPhoneVerificationCode отправляет код подтверждения телефона
+func (uc *AuthUseCase) sendPhoneVerificationCode(ctx context.Context, phone string) error {
+	code, err := uc.otpService.GenerateOTP(ctx, phone, otp.OTPTypeVerifyPhone)
+	if err != nil {
+		return err
+	}
+
+	return uc.smsService.SendSMS(&sms.SMSMessage{
+		To:   phone,
+		Body: fmt.Sprintf("Ваш код подтверждения: %s", code),
+	})
+}
+
+// markPhoneVerified отмечает телефон пользователя подтвержденным
+func (uc *AuthUseCase) markPhoneVerified(user *domain.User) error {
+	if user.IsPhoneVerified() {
+		return nil
+	}
+	now := time.Now()
+	user.PhoneVerifiedAt = &now
+	return uc.userRepo.MarkPhoneVerified(user.ID, now)
+}
+
+// AuthenticateUser аутентифицирует пользователя по телефону или email.
+// Если у пользователя включена 2FA, вместо токенов возвращается MFA challenge.
+// Неудачные попытки считаются по аккаунту и по IP клиента: после нескольких неудач
+// следующая попытка откладывается, затем вход временно блокируется (LoginLockedError).
+func (uc *AuthUseCase) AuthenticateUser(ctx context.Context, login, password, ip string) (*AuthResponse, error) {
 	// Валидация входных данных
-	if err := uc.validateLoginData(phone, password); err != nil {
+	if err := uc.validateLoginData(login, password); err != nil {
 		return nil, err
 	}
 
-	// Получаем пользователя
-	user, err := uc.userRepo.GetUserByPhone(phone)
+	// Получаем пользователя; неизвестный логин проходит те же проверки, что и существующий
+	user, err := uc.findUserByLogin(login)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		user = nil
+	}
+
+	keys := loginAttemptKeys(user, login, ip)
+	if err := uc.checkLoginAllowed(ctx, keys, user); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, uc.registerLoginFailure(ctx, keys, nil)
 	}
 
 	// Проверяем пароль
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, uc.registerLoginFailure(ctx, keys, user)
 	}
 
-	// Генерируем JWT токен
-	token, err := uc.jwtHelper.GenerateToken(user.ID, user.Phone, string(user.Role))
-	if err != nil {
-		return nil, err
+	uc.resetLoginFailures(ctx, keys)
+
+	if user.PasswordResetRequired {
+		return nil, ErrPasswordResetRequired
 	}
 
-	// Очищаем пароль перед возвратом
-	user.Password = ""
+	// Выдаем токены или MFA challenge, если включена 2FA
+	return uc.completeLogin(ctx, user)
+}
 
-	return &AuthResponse{
-		User:  user,
-		Token: token,
-	}, nil
+// findUserByLogin ищет пользователя по телефону или email.
+// По email находятся только пользователи с подтвержденным адресом.
+func (uc *AuthUseCase) findUserByLogin(login string) (*domain.User, error) {
+	if !strings.Contains(login, "@") {
+		return uc.userRepo.GetUserByPhone(login)
+	}
+
+	user, err := uc.userRepo.GetUserByEmail(normalizeEmail(login))
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsEmailVerified() {
+		return nil, errors.New("email is not verified")
+	}
+	return user, nil
 }
 
 // GetUserByID получает пользователя по ID (для использования в middleware)
@@ -119,7 +416,7 @@ func (uc *AuthUseCase) GetUserByID(userID uint) (*domain.User, error) {
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
-	
+
 	// Очищаем пароль
 	user.Password = ""
 	return user, nil
@@ -130,35 +427,29 @@ func (uc *AuthUseCase) validateRegisterData(phone, password, name string) error
 	if strings.TrimSpace(phone) == "" {
 		return errors.New("phone is required")
 	}
-	
+
 	if strings.TrimSpace(password) == "" {
 		return errors.New("password is required")
 	}
-	
+
 	if strings.TrimSpace(name) == "" {
 		return errors.New("name is required")
 	}
 
 	// Валидация формата телефона (базовая)
-	phoneRegex := regexp.MustCompile(`^\+?[1-9]\d{1,14}$`)
 	if !phoneRegex.MatchString(phone) {
 		return errors.New("invalid phone format")
 	}
 
-	// Валидация пароля (минимум 6 символов)
-	if len(password) < 6 {
-		return errors.New("password must be at least 6 characters long")
-	}
-
-	return nil
+	return validatePassword(password)
 }
 
 // validateLoginData валидирует данные для входа
-func (uc *AuthUseCase) validateLoginData(phone, password string) error {
-	if strings.TrimSpace(phone) == "" {
-		return errors.New("phone is required")
+func (uc *AuthUseCase) validateLoginData(login, password string) error {
+	if strings.TrimSpace(login) == "" {
+		return errors.New("phone or email is required")
 	}
-	
+
 	if strings.TrimSpace(password) == "" {
 		return errors.New("password is required")
 	}
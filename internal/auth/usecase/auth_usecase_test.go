@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"nodabackend/pkg/otp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newRegistrationTestUseCase создает usecase с обязательным подтверждением телефона
+// и неподтвержденным пользователем loginTestPhone
+func newRegistrationTestUseCase(t *testing.T) (*AuthUseCase, *fakeUserRepository, *fakeSMSService, *miniredis.Miniredis, *domain.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginTestPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Password: string(hash)}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	config := DefaultConfig()
+	config.RequirePhoneVerification = true
+
+	smsService := &fakeSMSService{}
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		RevocationStore:  repository.NewTokenRevocationStore(client),
+		LoginAttempts:    repository.NewLoginAttemptStore(client),
+		Registrations:    repository.NewPendingRegistrationStore(client),
+		JWTHelper:        testJWTHelper,
+		Mailer:           &fakeMailer{},
+		OTPService:       otp.NewOTPService(client, otp.DefaultConfig()),
+		SMSService:       smsService,
+	}, config)
+
+	return uc, users, smsService, mr, user
+}
+
+// lastSMSCode возвращает код из последней отправленной SMS
+func lastSMSCode(t *testing.T, smsService *fakeSMSService) string {
+	t.Helper()
+
+	messages := smsService.sent()
+	if len(messages) == 0 {
+		t.Fatal("no SMS sent")
+	}
+	return smsCodeRegex.FindString(messages[len(messages)-1].Body)
+}
+
+func TestRegister_CreatesUserOnlyAfterConfirmation(t *testing.T) {
+	uc, users, smsService, _, _ := newRegistrationTestUseCase(t)
+	ctx := context.Background()
+	const phone = "+77009876543"
+
+	response, err := uc.RegisterUser(ctx, phone, "", "new-password", "New User")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	if !response.VerificationRequired || response.RegistrationID == "" || response.Token != "" {
+		t.Fatalf("registration must wait for confirmation, got %+v", response)
+	}
+	if user, _ := users.GetUserByPhone(phone); user != nil {
+		t.Fatal("user must not be created before confirmation")
+	}
+
+	if _, err := uc.ConfirmRegistration(ctx, phone, lastSMSCode(t, smsService), "unknown"); err == nil {
+		t.Fatal("unknown registration must be rejected")
+	}
+	if user, _ := users.GetUserByPhone(phone); user != nil {
+		t.Fatal("user must not be created for an unknown registration")
+	}
+}
+
+func TestRegister_ConfirmAppliesPendingRegistration(t *testing.T) {
+	uc, users, smsService, _, _ := newRegistrationTestUseCase(t)
+	ctx := context.Background()
+	const phone = "+77009876543"
+
+	response, err := uc.RegisterUser(ctx, phone, "new@example.com", "new-password", "New User")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	confirmed, err := uc.ConfirmRegistration(ctx, phone, lastSMSCode(t, smsService), response.RegistrationID)
+	if err != nil {
+		t.Fatalf("ConfirmRegistration: %v", err)
+	}
+	if confirmed.Token == "" {
+		t.Fatal("tokens must be issued after confirmation")
+	}
+
+	user, err := users.GetUserByPhone(phone)
+	if err != nil {
+		t.Fatalf("user must be created after confirmation: %v", err)
+	}
+	if user.Name != "New User" || !user.IsPhoneVerified() {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("new-password")) != nil {
+		t.Fatal("password from registration must be applied")
+	}
+}
+
+func TestRegister_DoesNotOverwriteUnverifiedUser(t *testing.T) {
+	uc, users, _, _, user := newRegistrationTestUseCase(t)
+	ctx := context.Background()
+
+	if _, err := uc.RegisterUser(ctx, loginTestPhone, "", "attacker-password", "Attacker"); err == nil {
+		t.Fatal("registration of an existing phone must be rejected")
+	}
+
+	stored, _ := users.GetUserByID(user.ID)
+	if stored.Name != "Test User" || bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte(loginTestPassword)) != nil {
+		t.Fatal("existing user must not be modified")
+	}
+}
+
+func TestLogin_UnverifiedPhoneRequiresVerification(t *testing.T) {
+	uc, users, smsService, _, user := newRegistrationTestUseCase(t)
+	ctx := context.Background()
+
+	_, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1")
+	if !errors.Is(err, ErrPhoneVerificationRequired) {
+		t.Fatalf("expected ErrPhoneVerificationRequired, got %v", err)
+	}
+
+	response, err := uc.ConfirmRegistration(ctx, loginTestPhone, lastSMSCode(t, smsService), "")
+	if err != nil {
+		t.Fatalf("ConfirmRegistration: %v", err)
+	}
+	if response.Token == "" {
+		t.Fatal("tokens must be issued after the phone is verified")
+	}
+
+	if stored, _ := users.GetUserByID(user.ID); !stored.IsPhoneVerified() {
+		t.Fatal("phone must be marked verified")
+	}
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1"); err != nil {
+		t.Fatalf("login after verification: %v", err)
+	}
+}
+
+func TestRegister_LaterRegistrationCannotReplacePendingOne(t *testing.T) {
+	uc, users, smsService, mr, _ := newRegistrationTestUseCase(t)
+	ctx := context.Background()
+	const phone = "+77009876543"
+
+	owner, err := uc.RegisterUser(ctx, phone, "", "owner-password", "Owner")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	mr.FastForward(time.Minute)
+	if _, err := uc.RegisterUser(ctx, phone, "", "attacker-password", "Attacker"); err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	// Владелец номера получает последний код и подтверждает свою регистрацию
+	if _, err := uc.ConfirmRegistration(ctx, phone, lastSMSCode(t, smsService), owner.RegistrationID); err != nil {
+		t.Fatalf("ConfirmRegistration: %v", err)
+	}
+
+	user, _ := users.GetUserByPhone(phone)
+	if user == nil || user.Name != "Owner" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("owner-password")) != nil {
+		t.Fatalf("owner's registration must be applied, got %+v", user)
+	}
+}
+
+func TestRegister_DeletedUserKeepsPhoneAndEmail(t *testing.T) {
+	uc, users, _, _, _ := newRegistrationTestUseCase(t)
+	ctx := context.Background()
+
+	email := "deleted@example.com"
+	deleted := &domain.User{Phone: "+77000000002", Email: &email}
+	users.CreateUser(deleted)
+	users.DeleteUser(deleted.ID)
+
+	if _, err := uc.RegisterUser(ctx, deleted.Phone, "", "new-password", "New User"); err == nil {
+		t.Fatal("phone of a deleted user must stay taken until purge")
+	}
+	if _, err := uc.RegisterUser(ctx, "+77009876543", email, "new-password", "New User"); !errors.Is(err, domain.ErrEmailTaken) {
+		t.Fatalf("email of a deleted user must stay taken until purge, got %v", err)
+	}
+}
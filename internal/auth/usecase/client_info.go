@@ -0,0 +1,24 @@
+package usecase
+
+import "context"
+
+// ClientInfo данные клиента, с которого выполняется вход; сохраняются в сессии
+type ClientInfo struct {
+	IP         string
+	UserAgent  string
+	DeviceName string // название устройства, которое передает клиент (X-Device-Name)
+}
+
+// clientInfoKey ключ ClientInfo в context.Context
+type clientInfoKey struct{}
+
+// WithClientInfo добавляет данные клиента в контекст запроса
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// clientInfoFromContext возвращает данные клиента; без них сессия сохраняется без сведений об устройстве
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
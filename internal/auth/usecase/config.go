@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"nodabackend/pkg/env"
+	"strings"
+	"time"
+)
+
+// Config настройки бизнес-логики аутентификации
+type Config struct {
+	// OTPAutoRegister создает пользователя при первом входе по SMS коду
+	OTPAutoRegister bool
+	// RequirePhoneVerification требует подтверждения телефона кодом: при регистрации
+	// пользователь создается только после ввода кода, вход без подтвержденного телефона запрещен
+	RequirePhoneVerification bool
+	// TOTPIssuer название сервиса, которое показывает приложение-аутентификатор
+	TOTPIssuer string
+	// WebAuthnRPID домен, к которому привязываются ключи доступа (passkeys)
+	WebAuthnRPID string
+	// WebAuthnRPName название сервиса, которое показывает браузер при создании ключа
+	WebAuthnRPName string
+	// WebAuthnRPOrigins origin клиентов, с которых разрешены WebAuthn церемонии
+	WebAuthnRPOrigins []string
+	// MagicLinkURL адрес страницы, на которую ведет ссылка из письма; токен добавляется параметром token.
+	// Страница должна отправить токен POST запросом на /auth/magic-link/consume
+	MagicLinkURL string
+	// MagicLinkTTL время жизни ссылки для входа по email
+	MagicLinkTTL time.Duration
+	// MagicLinkBindIP ссылка действует только с IP, с которого ее запросили
+	MagicLinkBindIP bool
+	// LoginFreeAttempts сколько неудачных попыток входа по паролю допускается без задержки
+	LoginFreeAttempts int
+	// LoginMaxAttempts после стольких неудач подряд вход по логину временно блокируется
+	LoginMaxAttempts int
+	// LoginMaxAttemptsPerIP то же для IP клиента; выше, так как за одним IP может быть много пользователей
+	LoginMaxAttemptsPerIP int
+	// LoginLockoutDuration длительность временной блокировки и окно подсчета неудач
+	LoginLockoutDuration time.Duration
+	// LoginMaxLockouts после стольких временных блокировок за сутки аккаунт блокируется в базе (LockedUntil)
+	LoginMaxLockouts int
+	// LoginPersistentLockout длительность блокировки аккаунта в базе; снять раньше может администратор
+	LoginPersistentLockout time.Duration
+	// AccountDeletionGracePeriod через сколько после удаления аккаунта персональные данные стираются безвозвратно
+	AccountDeletionGracePeriod time.Duration
+}
+
+// DefaultConfig возвращает конфигурацию по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		OTPAutoRegister:            false,
+		RequirePhoneVerification:   false,
+		TOTPIssuer:                 "Noda",
+		WebAuthnRPID:               "localhost",
+		WebAuthnRPName:             "Noda",
+		WebAuthnRPOrigins:          []string{"http://localhost:3000"},
+		MagicLinkURL:               "http://localhost:3000/api/v1/auth/magic-link/consume",
+		MagicLinkTTL:               15 * time.Minute,
+		MagicLinkBindIP:            false,
+		LoginFreeAttempts:          3,
+		LoginMaxAttempts:           10,
+		LoginMaxAttemptsPerIP:      50,
+		LoginLockoutDuration:       15 * time.Minute,
+		LoginMaxLockouts:           3,
+		LoginPersistentLockout:     24 * time.Hour,
+		AccountDeletionGracePeriod: 30 * 24 * time.Hour,
+	}
+}
+
+// NewConfigFromEnv создает конфигурацию из переменных окружения
+func NewConfigFromEnv() *Config {
+	config := DefaultConfig()
+	config.OTPAutoRegister = env.GetEnvOrDefault("AUTH_OTP_AUTO_REGISTER", "false") == "true"
+	config.RequirePhoneVerification = env.GetEnvOrDefault("AUTH_REQUIRE_PHONE_VERIFICATION", "false") == "true"
+	config.TOTPIssuer = env.GetEnvOrDefault("AUTH_TOTP_ISSUER", config.TOTPIssuer)
+	config.WebAuthnRPID = env.GetEnvOrDefault("WEBAUTHN_RP_ID", config.WebAuthnRPID)
+	config.WebAuthnRPName = env.GetEnvOrDefault("WEBAUTHN_RP_NAME", config.WebAuthnRPName)
+	if origins := env.GetEnvOrDefault("WEBAUTHN_RP_ORIGINS", ""); origins != "" {
+		config.WebAuthnRPOrigins = strings.Split(origins, ",")
+	}
+	config.MagicLinkURL = env.GetEnvOrDefault("AUTH_MAGIC_LINK_URL", config.MagicLinkURL)
+	config.MagicLinkTTL = time.Duration(env.GetEnvIntOrDefault("AUTH_MAGIC_LINK_TTL_MINUTES", 15)) * time.Minute
+	config.MagicLinkBindIP = env.GetEnvOrDefault("AUTH_MAGIC_LINK_BIND_IP", "false") == "true"
+	config.LoginFreeAttempts = env.GetEnvIntOrDefault("AUTH_LOGIN_FREE_ATTEMPTS", config.LoginFreeAttempts)
+	config.LoginMaxAttempts = env.GetEnvIntOrDefault("AUTH_LOGIN_MAX_ATTEMPTS", config.LoginMaxAttempts)
+	config.LoginMaxAttemptsPerIP = env.GetEnvIntOrDefault("AUTH_LOGIN_MAX_ATTEMPTS_PER_IP", config.LoginMaxAttemptsPerIP)
+	config.LoginLockoutDuration = time.Duration(env.GetEnvIntOrDefault("AUTH_LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute
+	config.LoginMaxLockouts = env.GetEnvIntOrDefault("AUTH_LOGIN_MAX_LOCKOUTS", config.LoginMaxLockouts)
+	config.LoginPersistentLockout = time.Duration(env.GetEnvIntOrDefault("AUTH_LOGIN_PERSISTENT_LOCKOUT_HOURS", 24)) * time.Hour
+	config.AccountDeletionGracePeriod = time.Duration(env.GetEnvIntOrDefault("AUTH_ACCOUNT_DELETION_GRACE_DAYS", 30)) * 24 * time.Hour
+	return config
+}
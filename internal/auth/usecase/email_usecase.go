@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/mail"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/otp"
+	"strings"
+	"time"
+)
+
+// VerifyEmail подтверждает email пользователя кодом из письма и отправляет приветственное письмо
+func (uc *AuthUseCase) VerifyEmail(ctx context.Context, userID uint, code string) (*domain.User, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("code is required")
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.Email == nil {
+		return nil, errors.New("email is not set")
+	}
+
+	if user.IsEmailVerified() {
+		return nil, errors.New("email is already verified")
+	}
+
+	valid, err := uc.otpService.ValidateOTP(ctx, userOTPKey(user), otp.OTPTypeVerifyEmail, code)
+	if err != nil || !valid {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := uc.userRepo.MarkEmailVerified(user.ID, now); err != nil {
+		return nil, err
+	}
+
+	if err := uc.SendWelcomeEmail(*user.Email, user.Name); err != nil {
+		log.Printf("[AUTH] Failed to send welcome email to user %d: %v", user.ID, err)
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// sendEmailVerification отправляет код подтверждения на email пользователя.
+// Ошибки доставки не прерывают регистрацию и только логируются.
+func (uc *AuthUseCase) sendEmailVerification(ctx context.Context, user *domain.User) {
+	if user.Email == nil || user.IsEmailVerified() {
+		return
+	}
+
+	code, err := uc.otpService.GenerateOTP(ctx, userOTPKey(user), otp.OTPTypeVerifyEmail)
+	if err != nil {
+		log.Printf("[AUTH] Failed to generate email verification code for user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := uc.SendVerificationEmail(*user.Email, code); err != nil {
+		log.Printf("[AUTH] Failed to send verification email to user %d: %v", user.ID, err)
+	}
+}
+
+// setUserEmail устанавливает email пользователя; при смене адреса подтверждение сбрасывается
+func setUserEmail(user *domain.User, email string) {
+	if email == "" {
+		return
+	}
+	if user.Email != nil && *user.Email == email {
+		return
+	}
+	user.Email = &email
+	user.EmailVerifiedAt = nil
+}
+
+// normalizeEmail приводит email к каноническому виду
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateEmail проверяет формат email
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("invalid email format")
+	}
+	return nil
+}
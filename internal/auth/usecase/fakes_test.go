@@ -0,0 +1,700 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/jwthelper"
+	"nodabackend/pkg/mailer"
+	"nodabackend/pkg/sms"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// testJWTHelper подписывает токены в тестах фиксированным HMAC ключом
+var testJWTHelper = newTestJWTHelper()
+
+func newTestJWTHelper() *jwthelper.JWTHelper {
+	keyring, err := jwthelper.NewKeyring(jwthelper.NewHMACKey([]byte("test-secret")))
+	if err != nil {
+		panic(err)
+	}
+	helper, err := jwthelper.NewJWTHelper(keyring, nil)
+	if err != nil {
+		panic(err)
+	}
+	return helper
+}
+
+// fakeUserRepository хранит пользователей в памяти
+type fakeUserRepository struct {
+	mu     sync.Mutex
+	users  map[uint]*domain.User
+	nextID uint
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{users: make(map[uint]*domain.User)}
+}
+
+func (r *fakeUserRepository) CreateUser(user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+	r.nextID++
+	user.ID = r.nextID
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+// find ищет пользователя среди неудаленных, как и GORM с мягким удалением
+func (r *fakeUserRepository) find(match func(*domain.User) bool) (*domain.User, error) {
+	return r.findWithDeleted(func(u *domain.User) bool { return !u.DeletedAt.Valid && match(u) })
+}
+
+func (r *fakeUserRepository) findWithDeleted(match func(*domain.User) bool) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if match(user) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) GetUserByPhone(phone string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Phone == phone })
+}
+
+func (r *fakeUserRepository) GetUserByEmail(email string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Email != nil && *u.Email == email })
+}
+
+func (r *fakeUserRepository) IsPhoneTaken(phone string) (bool, error) {
+	_, err := r.findWithDeleted(func(u *domain.User) bool { return u.Phone == phone })
+	return err == nil, nil
+}
+
+func (r *fakeUserRepository) IsEmailTaken(email string) (bool, error) {
+	_, err := r.findWithDeleted(func(u *domain.User) bool { return u.Email != nil && *u.Email == email })
+	return err == nil, nil
+}
+
+// checkUnique повторяет уникальные индексы телефона и email, которые видят и удаленные записи
+func (r *fakeUserRepository) checkUnique(user *domain.User) error {
+	for id, other := range r.users {
+		if id == user.ID {
+			continue
+		}
+		if other.Phone == user.Phone {
+			return domain.ErrPhoneTaken
+		}
+		if user.Email != nil && other.Email != nil && *other.Email == *user.Email {
+			return domain.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) GetUserByID(id uint) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.ID == id })
+}
+
+func (r *fakeUserRepository) GetUserByIDWithDeleted(id uint) (*domain.User, error) {
+	return r.findWithDeleted(func(u *domain.User) bool { return u.ID == id })
+}
+
+// ListUsers поддерживает только фильтр по роли, этого достаточно для тестов
+func (r *fakeUserRepository) ListUsers(filter domain.UserFilter) ([]*domain.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*domain.User
+	for id := uint(1); id <= r.nextID; id++ {
+		user, ok := r.users[id]
+		if !ok || user.DeletedAt.Valid || (filter.Role != "" && user.Role != filter.Role) {
+			continue
+		}
+		copied := *user
+		result = append(result, &copied)
+	}
+	total := int64(len(result))
+	start := (filter.Page - 1) * filter.PerPage
+	if start > len(result) {
+		start = len(result)
+	}
+	end := start + filter.PerPage
+	if end > len(result) {
+		end = len(result)
+	}
+	return result[start:end], total, nil
+}
+
+// save перезаписывает пользователя целиком; используется тестами для подготовки данных
+func (r *fakeUserRepository) save(user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+// update меняет поля сохраненного пользователя, как UPDATE отдельных столбцов
+func (r *fakeUserRepository) update(id uint, apply func(*domain.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	updated := *user
+	apply(&updated)
+	if err := r.checkUnique(&updated); err != nil {
+		return err
+	}
+	r.users[id] = &updated
+	return nil
+}
+
+func (r *fakeUserRepository) UpdateUserRole(id uint, role domain.Role) error {
+	return r.update(id, func(u *domain.User) { u.Role = role })
+}
+
+func (r *fakeUserRepository) UpdateUserBan(id uint, bannedAt *time.Time, reason string) error {
+	return r.update(id, func(u *domain.User) { u.BannedAt, u.BanReason = bannedAt, reason })
+}
+
+func (r *fakeUserRepository) UpdateUserLock(id uint, lockedUntil *time.Time) error {
+	return r.update(id, func(u *domain.User) { u.LockedUntil = lockedUntil })
+}
+
+func (r *fakeUserRepository) RequirePasswordReset(id uint) error {
+	return r.update(id, func(u *domain.User) { u.PasswordResetRequired = true })
+}
+
+func (r *fakeUserRepository) UpdateUserPassword(id uint, passwordHash string) error {
+	return r.update(id, func(u *domain.User) { u.Password, u.PasswordResetRequired = passwordHash, false })
+}
+
+func (r *fakeUserRepository) UpdateUserProfile(user *domain.User) error {
+	return r.update(user.ID, func(u *domain.User) {
+		u.Name, u.AvatarURL, u.Locale, u.Timezone = user.Name, user.AvatarURL, user.Locale, user.Timezone
+	})
+}
+
+func (r *fakeUserRepository) UpdateUserPendingPhone(id uint, phone *string) error {
+	return r.update(id, func(u *domain.User) { u.PendingPhone = phone })
+}
+
+func (r *fakeUserRepository) UpdateUserPhone(id uint, phone string, verifiedAt time.Time) error {
+	return r.update(id, func(u *domain.User) { u.Phone, u.PhoneVerifiedAt, u.PendingPhone = phone, &verifiedAt, nil })
+}
+
+func (r *fakeUserRepository) MarkPhoneVerified(id uint, verifiedAt time.Time) error {
+	return r.update(id, func(u *domain.User) { u.PhoneVerifiedAt = &verifiedAt })
+}
+
+func (r *fakeUserRepository) MarkEmailVerified(id uint, verifiedAt time.Time) error {
+	return r.update(id, func(u *domain.User) { u.EmailVerifiedAt = &verifiedAt })
+}
+
+func (r *fakeUserRepository) UpdateTOTPSecret(id uint, secret string) error {
+	return r.update(id, func(u *domain.User) { u.TOTPSecret, u.TOTPLastStep = secret, 0 })
+}
+
+func (r *fakeUserRepository) EnableTOTP(id uint, enabledAt time.Time) error {
+	return r.update(id, func(u *domain.User) { u.TOTPEnabledAt = &enabledAt })
+}
+
+func (r *fakeUserRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+func (r *fakeUserRepository) DeleteUser(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.users[id]; ok {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) GetUsersDeletedBefore(before time.Time, limit int) ([]*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*domain.User
+	for _, user := range r.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) && user.AnonymizedAt == nil && len(result) < limit {
+			copied := *user
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeUserRepository) AnonymizeUser(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return errors.New("record not found")
+	}
+	now := time.Now()
+	*user = domain.User{
+		ID:           user.ID,
+		Phone:        fmt.Sprintf("deleted:%d", id),
+		Role:         user.Role,
+		CreatedAt:    user.CreatedAt,
+		DeletedAt:    user.DeletedAt,
+		AnonymizedAt: &now,
+	}
+	return nil
+}
+
+// fakeRoleRepository отдает роли по умолчанию
+type fakeRoleRepository struct{}
+
+func (fakeRoleRepository) GetRoles() ([]*domain.RoleDefinition, error) {
+	return domain.DefaultRoles(), nil
+}
+
+// fakeRefreshTokenRepository хранит refresh токены в памяти
+type fakeRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*domain.RefreshToken
+}
+
+func (r *fakeRefreshTokenRepository) CreateRefreshToken(token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *fakeRefreshTokenRepository) GetRefreshTokenByHash(hash string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeRefreshTokenRepository) MarkRefreshTokenRotated(id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.ID == id && token.RotatedAt == nil && token.RevokedAt == nil {
+			now := time.Now()
+			token.RotatedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRefreshTokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	return r.revoke(func(token *domain.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+func (r *fakeRefreshTokenRepository) RevokeUserRefreshTokens(userID uint) error {
+	return r.revoke(func(token *domain.RefreshToken) bool { return token.UserID == userID })
+}
+
+func (r *fakeRefreshTokenRepository) revoke(match func(*domain.RefreshToken) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, token := range r.tokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// fakeRecoveryCodeRepository хранит коды восстановления в памяти
+type fakeRecoveryCodeRepository struct {
+	mu    sync.Mutex
+	codes []*domain.RecoveryCode
+}
+
+func (r *fakeRecoveryCodeRepository) ReplaceRecoveryCodes(userID uint, codes []*domain.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.codes[:0]
+	for _, code := range r.codes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	r.codes = append(kept, codes...)
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, code := range r.codes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			now := time.Now()
+			code.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRecoveryCodeRepository) CountRemainingRecoveryCodes(userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, code := range r.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// fakeWebAuthnCredentialRepository хранит ключи доступа в памяти
+type fakeWebAuthnCredentialRepository struct {
+	mu          sync.Mutex
+	credentials []*domain.WebAuthnCredential
+}
+
+func (r *fakeWebAuthnCredentialRepository) CreateWebAuthnCredential(credential *domain.WebAuthnCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	credential.ID = uint(len(r.credentials) + 1)
+	copied := *credential
+	r.credentials = append(r.credentials, &copied)
+	return nil
+}
+
+func (r *fakeWebAuthnCredentialRepository) GetWebAuthnCredentialsByUserID(userID uint) ([]*domain.WebAuthnCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*domain.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			copied := *credential
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebAuthnCredentialRepository) UpdateWebAuthnCredential(credential *domain.WebAuthnCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.credentials {
+		if stored.ID == credential.ID {
+			copied := *credential
+			r.credentials[i] = &copied
+			return nil
+		}
+	}
+	return errors.New("record not found")
+}
+
+// fakeLinkedIdentityRepository хранит связанные аккаунты в памяти
+type fakeLinkedIdentityRepository struct {
+	mu         sync.Mutex
+	identities []*domain.LinkedIdentity
+}
+
+func (r *fakeLinkedIdentityRepository) CreateLinkedIdentity(identity *domain.LinkedIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.identities {
+		if stored.Provider == identity.Provider && stored.Subject == identity.Subject {
+			return errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	identity.ID = uint(len(r.identities) + 1)
+	copied := *identity
+	r.identities = append(r.identities, &copied)
+	return nil
+}
+
+func (r *fakeLinkedIdentityRepository) GetLinkedIdentity(provider, subject string) (*domain.LinkedIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeLinkedIdentityRepository) GetLinkedIdentitiesByUserID(userID uint) ([]*domain.LinkedIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*domain.LinkedIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			copied := *identity
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeLinkedIdentityRepository) DeleteLinkedIdentity(userID uint, provider string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.identities[:0]
+	for _, identity := range r.identities {
+		if identity.UserID != userID || identity.Provider != provider {
+			kept = append(kept, identity)
+		}
+	}
+	r.identities = kept
+	return nil
+}
+
+// fakeMailer запоминает отправленные письма
+type fakeMailer struct {
+	mu       sync.Mutex
+	messages []*mailer.EmailMessage
+}
+
+func (m *fakeMailer) SendEmail(msg *mailer.EmailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *fakeMailer) sent() []*mailer.EmailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*mailer.EmailMessage(nil), m.messages...)
+}
+
+// fakeSMSService запоминает отправленные SMS
+type fakeSMSService struct {
+	mu       sync.Mutex
+	messages []*sms.SMSMessage
+}
+
+func (s *fakeSMSService) SendSMS(msg *sms.SMSMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *fakeSMSService) sent() []*sms.SMSMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*sms.SMSMessage(nil), s.messages...)
+}
+
+// fakeSessionRepository хранит сессии в памяти
+type fakeSessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.Session
+}
+
+func newFakeSessionRepository() *fakeSessionRepository {
+	return &fakeSessionRepository{sessions: make(map[string]*domain.Session)}
+}
+
+func (r *fakeSessionRepository) CreateSession(session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return nil
+}
+
+func (r *fakeSessionRepository) GetActiveSessionsByUserID(userID uint) ([]*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*domain.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(time.Now()) {
+			copied := *session
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeSessionRepository) ExtendSession(id, ip string, lastSeenAt, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok && session.RevokedAt == nil {
+		session.IP = ip
+		session.LastSeenAt = lastSeenAt
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (r *fakeSessionRepository) TouchSessions(lastSeen map[string]time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, seenAt := range lastSeen {
+		if session, ok := r.sessions[id]; ok && session.LastSeenAt.Before(seenAt) {
+			session.LastSeenAt = seenAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepository) RevokeSession(userID uint, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return true, nil
+}
+
+func (r *fakeSessionRepository) RevokeUserSessions(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// fakeServiceClientRepository хранит сервисных клиентов в памяти
+type fakeServiceClientRepository struct {
+	mu      sync.Mutex
+	clients map[string]*domain.ServiceClient
+}
+
+func newFakeServiceClientRepository() *fakeServiceClientRepository {
+	return &fakeServiceClientRepository{clients: make(map[string]*domain.ServiceClient)}
+}
+
+func (r *fakeServiceClientRepository) CreateServiceClient(client *domain.ServiceClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client.CreatedAt = time.Now()
+	r.clients[client.ID] = client
+	return nil
+}
+
+func (r *fakeServiceClientRepository) GetServiceClient(id string) (*domain.ServiceClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return client, nil
+}
+
+func (r *fakeServiceClientRepository) ListServiceClients() ([]*domain.ServiceClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clients := make([]*domain.ServiceClient, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+func (r *fakeServiceClientRepository) RevokeServiceClient(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[id]
+	if !ok || client.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	client.RevokedAt = &now
+	return true, nil
+}
+
+// fakeAPIKeyRepository хранит API ключи в памяти
+type fakeAPIKeyRepository struct {
+	mu   sync.Mutex
+	keys []*domain.APIKey
+}
+
+func (r *fakeAPIKeyRepository) CreateAPIKey(key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = uint(len(r.keys) + 1)
+	key.CreatedAt = time.Now()
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) GetAPIKeyByHash(hash string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeAPIKeyRepository) GetAPIKeysByUserID(userID uint) ([]*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []*domain.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepository) RevokeAPIKey(userID, id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.ID == id && key.UserID == userID && key.RevokedAt == nil {
+			now := time.Now()
+			key.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeAPIKeyRepository) TouchAPIKey(id uint, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
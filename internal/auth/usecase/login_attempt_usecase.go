@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// loginDelayBase задержка после первой неудачи сверх LoginFreeAttempts; удваивается с каждой следующей
+	loginDelayBase = time.Second
+	// loginDelayMax предельная задержка между попытками до блокировки
+	loginDelayMax = 30 * time.Second
+	// loginLockoutWindow окно, в котором считаются временные блокировки логина
+	loginLockoutWindow = 24 * time.Hour
+)
+
+// ErrInvalidCredentials неверный логин или пароль
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// LoginLockedError вход временно запрещен из-за неудачных попыток
+type LoginLockedError struct {
+	RetryAfter time.Duration // сколько осталось ждать
+	Persistent bool          // аккаунт заблокирован в базе (LockedUntil), а не только в Redis
+}
+
+func (e *LoginLockedError) Error() string {
+	if e.Persistent {
+		return "account is locked due to too many failed login attempts"
+	}
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// loginAttemptKey ключ счетчика попыток с порогом блокировки
+type loginAttemptKey struct {
+	key     string
+	account bool // ключ аккаунта или неизвестного логина: его повторные блокировки ведут к долгой блокировке
+}
+
+// loginAttemptKeys ключи, по которым считаются попытки входа. Неудачи существующего аккаунта
+// считаются по его ID, чтобы вход по телефону и по email расходовал общий лимит,
+// неудачи неизвестного логина — по самому логину.
+func loginAttemptKeys(user *domain.User, login, ip string) []loginAttemptKey {
+	key := "login:" + normalizeEmail(login)
+	if user != nil {
+		key = accountAttemptKey(user.ID)
+	}
+
+	keys := []loginAttemptKey{{key: key, account: true}}
+	if ip != "" {
+		keys = append(keys, loginAttemptKey{key: "ip:" + ip})
+	}
+	return keys
+}
+
+// accountAttemptKey ключ счетчика попыток входа в аккаунт
+func accountAttemptKey(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// dummyPasswordHash хеш для сравнения пароля неизвестного логина: ответ занимает столько же времени,
+// сколько проверка пароля существующего аккаунта
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("[AUTH] Failed to generate dummy password hash: %v", err)
+	}
+	return hash
+})
+
+// UnlockUser снимает блокировку входа: LockedUntil в базе и счетчики в Redis
+func (uc *AuthUseCase) UnlockUser(ctx context.Context, userID uint) (*domain.User, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	user.LockedUntil = nil
+	if err := uc.userRepo.UpdateUserLock(user.ID, nil); err != nil {
+		return nil, err
+	}
+
+	key := accountAttemptKey(user.ID)
+	for _, name := range []string{key, "lockouts:" + key, "locked:" + key} {
+		if err := uc.loginAttempts.ResetLoginFailures(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// checkLoginAllowed проверяет, не действует ли долгая блокировка, задержка или блокировка по одному из ключей.
+// Долгая блокировка проверяется до пароля одинаково для существующих и неизвестных логинов,
+// чтобы по ней нельзя было отличить существующий аккаунт.
+func (uc *AuthUseCase) checkLoginAllowed(ctx context.Context, keys []loginAttemptKey, user *domain.User) error {
+	names := make([]string, 0, len(keys))
+	var lockedNames []string
+	for _, key := range keys {
+		names = append(names, key.key)
+		if key.account {
+			lockedNames = append(lockedNames, "locked:"+key.key)
+		}
+	}
+
+	lockedFor, err := uc.loginAttempts.LoginRetryAfter(ctx, lockedNames...)
+	if err != nil {
+		return err
+	}
+	// Аккаунт может быть заблокирован в базе и без записи в Redis
+	if user != nil && user.IsLocked(time.Now()) {
+		if until := time.Until(*user.LockedUntil); until > lockedFor {
+			lockedFor = until
+		}
+	}
+	if lockedFor > 0 {
+		return &LoginLockedError{RetryAfter: lockedFor, Persistent: true}
+	}
+
+	retryAfter, err := uc.loginAttempts.LoginRetryAfter(ctx, names...)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// registerLoginFailure учитывает неудачную попытку и назначает задержку или блокировку.
+// Для несуществующего логина поведение такое же, чтобы по ответам нельзя было перебрать пользователей.
+func (uc *AuthUseCase) registerLoginFailure(ctx context.Context, keys []loginAttemptKey, user *domain.User) error {
+	var locked *LoginLockedError
+
+	for _, key := range keys {
+		maxAttempts := uc.config.LoginMaxAttemptsPerIP
+		if key.account {
+			maxAttempts = uc.config.LoginMaxAttempts
+		}
+
+		failures, err := uc.loginAttempts.RegisterLoginFailure(ctx, key.key, uc.config.LoginLockoutDuration)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case failures >= int64(maxAttempts):
+			if err := uc.lockLogin(ctx, key, user); err != nil {
+				return err
+			}
+			locked = &LoginLockedError{RetryAfter: uc.config.LoginLockoutDuration}
+		case failures > int64(uc.config.LoginFreeAttempts):
+			delay := loginDelay(failures - int64(uc.config.LoginFreeAttempts))
+			if err := uc.loginAttempts.BlockLogin(ctx, key.key, delay); err != nil {
+				return err
+			}
+		}
+	}
+
+	if locked != nil {
+		return locked
+	}
+	return ErrInvalidCredentials
+}
+
+// lockLogin временно блокирует ключ. Повторяющиеся блокировки ключа аккаунта включают долгую блокировку:
+// для существующего аккаунта она сохраняется и в базе, для неизвестного логина действует так же, но только в Redis.
+func (uc *AuthUseCase) lockLogin(ctx context.Context, key loginAttemptKey, user *domain.User) error {
+	// Счетчик сбрасывается, чтобы после блокировки снова действовали обычные задержки
+	if err := uc.loginAttempts.ResetLoginFailures(ctx, key.key); err != nil {
+		return err
+	}
+	if err := uc.loginAttempts.BlockLogin(ctx, key.key, uc.config.LoginLockoutDuration); err != nil {
+		return err
+	}
+
+	if !key.account {
+		return nil
+	}
+
+	lockouts, err := uc.loginAttempts.RegisterLoginFailure(ctx, "lockouts:"+key.key, loginLockoutWindow)
+	if err != nil {
+		return err
+	}
+	if lockouts < int64(uc.config.LoginMaxLockouts) {
+		return nil
+	}
+
+	if err := uc.loginAttempts.BlockLogin(ctx, "locked:"+key.key, uc.config.LoginPersistentLockout); err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	lockedUntil := time.Now().Add(uc.config.LoginPersistentLockout)
+	user.LockedUntil = &lockedUntil
+	if err := uc.userRepo.UpdateUserLock(user.ID, &lockedUntil); err != nil {
+		return err
+	}
+	log.Printf("[AUTH] User %d is locked until %s after repeated failed login attempts", user.ID, lockedUntil.Format(time.RFC3339))
+	return nil
+}
+
+// resetLoginFailures сбрасывает счетчик логина после успешного входа.
+// Счетчик IP не сбрасывается: иначе подбор паролей к чужим аккаунтам можно было бы
+// «разбавлять» входами в свой.
+func (uc *AuthUseCase) resetLoginFailures(ctx context.Context, keys []loginAttemptKey) {
+	for _, key := range keys {
+		if !key.account {
+			continue
+		}
+		if err := uc.loginAttempts.ResetLoginFailures(ctx, key.key); err != nil {
+			log.Printf("[AUTH] Failed to reset login failures: %v", err)
+		}
+	}
+}
+
+// loginDelay задержка перед следующей попыткой: 1s, 2s, 4s, ... до loginDelayMax
+func loginDelay(excess int64) time.Duration {
+	delay := loginDelayBase
+	for i := int64(1); i < excess && delay < loginDelayMax; i++ {
+		delay *= 2
+	}
+	if delay > loginDelayMax {
+		delay = loginDelayMax
+	}
+	return delay
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	loginTestPhone    = "+77001234567"
+	loginTestPassword = "correct-password"
+)
+
+// newLoginTestUseCase создает usecase с пользователем и счетчиками попыток в miniredis
+func newLoginTestUseCase(t *testing.T) (*AuthUseCase, *fakeUserRepository, *miniredis.Miniredis, *domain.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginTestPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Password: string(hash)}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		LoginAttempts:    repository.NewLoginAttemptStore(client),
+		JWTHelper:        testJWTHelper,
+	}, DefaultConfig())
+
+	return uc, users, mr, user
+}
+
+// failLogin делает попытку с неверным паролем, дожидаясь окончания задержки
+func failLogin(t *testing.T, uc *AuthUseCase, mr *miniredis.Miniredis, login, ip string) error {
+	t.Helper()
+
+	_, err := uc.AuthenticateUser(context.Background(), login, "wrong-password", ip)
+	var locked *LoginLockedError
+	if errors.As(err, &locked) && !locked.Persistent {
+		mr.FastForward(locked.RetryAfter)
+	}
+	return err
+}
+
+func TestLogin_ProgressiveDelay(t *testing.T) {
+	uc, _, _, _ := newLoginTestUseCase(t)
+	ctx := context.Background()
+	config := DefaultConfig()
+
+	for i := 0; i < config.LoginFreeAttempts; i++ {
+		if _, err := uc.AuthenticateUser(ctx, loginTestPhone, "wrong-password", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+
+	// Неудача сверх бесплатных попыток назначает задержку
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, "wrong-password", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+
+	// Во время задержки даже правильный пароль не проверяется
+	_, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.2")
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("expected login delay, got %v", err)
+	}
+}
+
+func TestLogin_LockoutAndUnlock(t *testing.T) {
+	uc, users, mr, user := newLoginTestUseCase(t)
+	ctx := context.Background()
+	config := DefaultConfig()
+
+	// Несколько серий неудач подряд приводят к блокировке аккаунта в базе.
+	// Каждый раз новый IP, чтобы сработал именно счетчик логина.
+	lockouts := 0
+	for i := 0; lockouts < config.LoginMaxLockouts; i++ {
+		if i > 100 {
+			t.Fatalf("account was not locked, %d temporary lockouts", lockouts)
+		}
+
+		err := failLogin(t, uc, mr, loginTestPhone, fmt.Sprintf("10.0.%d.%d", i/250, i%250))
+		var locked *LoginLockedError
+		if errors.As(err, &locked) && locked.RetryAfter == config.LoginLockoutDuration {
+			lockouts++
+		}
+	}
+
+	stored, err := users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if stored.LockedUntil == nil {
+		t.Fatal("expected persistent lockout")
+	}
+
+	_, err = uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.9")
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) || !locked.Persistent {
+		t.Fatalf("expected persistent lockout error, got %v", err)
+	}
+
+	if _, err := uc.UnlockUser(ctx, user.ID); err != nil {
+		t.Fatalf("UnlockUser: %v", err)
+	}
+
+	response, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.9")
+	if err != nil {
+		t.Fatalf("login after unlock: %v", err)
+	}
+	if response.Token == "" {
+		t.Fatal("expected access token after unlock")
+	}
+}
+
+func TestLogin_LockoutPerIP(t *testing.T) {
+	uc, users, mr, _ := newLoginTestUseCase(t)
+	ctx := context.Background()
+	config := DefaultConfig()
+
+	// Подбор по разным логинам с одного IP
+	for i := 0; i < config.LoginMaxAttemptsPerIP; i++ {
+		login := fmt.Sprintf("+770010%04d", i)
+		_, err := uc.AuthenticateUser(ctx, login, "wrong-password", "10.0.0.1")
+		var locked *LoginLockedError
+		if errors.As(err, &locked) {
+			mr.FastForward(locked.RetryAfter)
+		}
+	}
+
+	_, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1")
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected IP lockout, got %v", err)
+	}
+
+	// Блокировка IP не блокирует сам аккаунт
+	stored, _ := users.GetUserByPhone(loginTestPhone)
+	if stored.LockedUntil != nil {
+		t.Fatal("IP lockout must not lock the account")
+	}
+	if _, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.2"); err != nil {
+		t.Fatalf("login from another IP: %v", err)
+	}
+}
+
+func TestLogin_PhoneAndEmailShareFailures(t *testing.T) {
+	uc, users, _, user := newLoginTestUseCase(t)
+	ctx := context.Background()
+	config := DefaultConfig()
+
+	email := "user@example.com"
+	if err := users.update(user.ID, func(u *domain.User) {
+		verifiedAt := time.Now()
+		u.Email = &email
+		u.EmailVerifiedAt = &verifiedAt
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// Бесплатные попытки расходуются по телефону, следующая по email уже назначает задержку
+	for i := 0; i < config.LoginFreeAttempts; i++ {
+		if _, err := uc.AuthenticateUser(ctx, loginTestPhone, "wrong-password", ""); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+	if _, err := uc.AuthenticateUser(ctx, email, "wrong-password", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+
+	_, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "")
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected login delay shared by phone and email, got %v", err)
+	}
+}
+
+func TestLogin_UnknownLoginLockedLikeAccount(t *testing.T) {
+	uc, _, mr, _ := newLoginTestUseCase(t)
+	ctx := context.Background()
+	config := DefaultConfig()
+
+	// lockedError доводит логин до долгой блокировки и возвращает ответ на следующую попытку
+	lockedError := func(login string) *LoginLockedError {
+		lockouts := 0
+		for i := 0; lockouts < config.LoginMaxLockouts; i++ {
+			if i > 100 {
+				t.Fatalf("%s was not locked, %d temporary lockouts", login, lockouts)
+			}
+
+			err := failLogin(t, uc, mr, login, "")
+			var locked *LoginLockedError
+			if errors.As(err, &locked) && locked.RetryAfter == config.LoginLockoutDuration {
+				lockouts++
+			}
+		}
+
+		_, err := uc.AuthenticateUser(ctx, login, "wrong-password", "")
+		var locked *LoginLockedError
+		if !errors.As(err, &locked) {
+			t.Fatalf("%s: expected lockout, got %v", login, err)
+		}
+		return locked
+	}
+
+	existing := lockedError(loginTestPhone)
+	unknown := lockedError("+77009999999")
+	if !existing.Persistent || !unknown.Persistent || existing.Error() != unknown.Error() {
+		t.Fatalf("lockout of an unknown login must look the same: %v and %v", existing, unknown)
+	}
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"log"
+	"net/url"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/mailer"
+	"strings"
+	"time"
+)
+
+// magicLinkCooldown как часто можно запрашивать новую ссылку для одного пользователя
+const magicLinkCooldown = time.Minute
+
+// magicLinkTemplate письмо со ссылкой для входа.
+// html/template экранирует ссылку, поэтому токен нельзя использовать для внедрения разметки.
+var magicLinkTemplate = template.Must(template.New("magic_link").Parse(`
+		<h2>Вход в аккаунт</h2>
+		<p>Здравствуйте{{if .Name}}, {{.Name}}{{end}}!</p>
+		<p>Чтобы войти, перейдите по ссылке: <a href="{{.URL}}">войти</a></p>
+		<p>Ссылка действует {{.TTLMinutes}} мин. и может быть использована только один раз.</p>
+		<br>
+		<p>Если вы не запрашивали вход, просто проигнорируйте это письмо.</p>
+	`))
+
+// magicLinkEmail данные для шаблона письма
+type magicLinkEmail struct {
+	Name       string
+	URL        string
+	TTLMinutes int
+}
+
+// RequestMagicLink отправляет на email ссылку для входа.
+// Ответ не зависит от того, существует ли аккаунт: ошибки только логируются,
+// чтобы по ответу нельзя было перебрать пользователей.
+// Если передан deviceID, ссылка сработает только с тем же идентификатором устройства.
+func (uc *AuthUseCase) RequestMagicLink(ctx context.Context, email, ip, deviceID string) error {
+	email = normalizeEmail(email)
+	if email == "" {
+		return errors.New("email is required")
+	}
+	if err := validateEmail(email); err != nil {
+		return err
+	}
+
+	// Ссылка отправляется только на подтвержденный адрес
+	user, err := uc.findUserByLogin(email)
+	if err != nil {
+		return nil
+	}
+
+	allowed, err := uc.magicLinks.AcquireMagicLinkCooldown(ctx, user.ID, magicLinkCooldown)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		log.Printf("[AUTH] Magic link for user %d was requested too often", user.ID)
+		return nil
+	}
+
+	token, tokenID, err := uc.jwtHelper.GenerateMagicLinkToken(user.ID, uc.config.MagicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link := &domain.MagicLink{
+		UserID:   user.ID,
+		DeviceID: strings.TrimSpace(deviceID),
+	}
+	if uc.config.MagicLinkBindIP {
+		link.IP = ip
+	}
+	if err := uc.magicLinks.SaveMagicLink(ctx, tokenID, link, uc.config.MagicLinkTTL); err != nil {
+		return err
+	}
+
+	if err := uc.sendMagicLinkEmail(*user.Email, user.Name, token); err != nil {
+		log.Printf("[AUTH] Failed to send magic link to user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+// ConsumeMagicLink обменивает токен из ссылки на пару токенов (или MFA challenge, если включена 2FA).
+// Ссылка одноразовая: после первой попытки она удаляется, даже если проверка привязки не прошла.
+func (uc *AuthUseCase) ConsumeMagicLink(ctx context.Context, token, ip, deviceID string) (*AuthResponse, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, errors.New("token is required")
+	}
+
+	claims, err := uc.jwtHelper.ValidateMagicLinkToken(token)
+	if err != nil {
+		return nil, errors.New("invalid or expired link")
+	}
+
+	link, err := uc.magicLinks.PopMagicLink(ctx, claims.ID)
+	if errors.Is(err, domain.ErrMagicLinkNotFound) {
+		return nil, errors.New("invalid or expired link")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if link.UserID != claims.UserID {
+		return nil, errors.New("invalid or expired link")
+	}
+	if link.IP != "" && link.IP != ip {
+		return nil, errors.New("link must be opened from the device it was requested on")
+	}
+	if link.DeviceID != "" && link.DeviceID != strings.TrimSpace(deviceID) {
+		return nil, errors.New("link must be opened from the device it was requested on")
+	}
+
+	user, err := uc.userRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Ссылка заменяет пароль, 2FA по-прежнему требуется
+	return uc.completeLogin(ctx, user)
+}
+
+// sendMagicLinkEmail отправляет письмо со ссылкой для входа
+func (uc *AuthUseCase) sendMagicLinkEmail(email, name, token string) error {
+	linkURL, err := url.Parse(uc.config.MagicLinkURL)
+	if err != nil {
+		return err
+	}
+	query := linkURL.Query()
+	query.Set("token", token)
+	linkURL.RawQuery = query.Encode()
+
+	var body bytes.Buffer
+	err = magicLinkTemplate.Execute(&body, magicLinkEmail{
+		Name:       name,
+		URL:        linkURL.String(),
+		TTLMinutes: int(uc.config.MagicLinkTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.mailer.SendEmail(&mailer.EmailMessage{
+		To:      []string{email},
+		Subject: "Вход в аккаунт",
+		Body:    body.String(),
+		IsHTML:  true,
+	})
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const magicLinkTestEmail = "user@example.com"
+
+// magicLinkHref находит ссылку в письме
+var magicLinkHref = regexp.MustCompile(`href="([^"]+)"`)
+
+// newMagicLinkTestUseCase создает usecase с пользователем, у которого подтвержден email
+func newMagicLinkTestUseCase(t *testing.T, config *Config) (*AuthUseCase, *fakeMailer, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	users := newFakeUserRepository()
+	mail := &fakeMailer{}
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		MagicLinks:       repository.NewMagicLinkStore(client),
+		JWTHelper:        testJWTHelper,
+		Mailer:           mail,
+	}, config)
+
+	email := magicLinkTestEmail
+	now := time.Now()
+	user := &domain.User{Phone: "+77001234567", Name: "Test User", Email: &email, EmailVerifiedAt: &now}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return uc, mail, mr
+}
+
+// tokenFromMail извлекает токен из последнего письма
+func tokenFromMail(t *testing.T, mail *fakeMailer) string {
+	t.Helper()
+
+	sent := mail.sent()
+	if len(sent) == 0 {
+		t.Fatal("no email was sent")
+	}
+
+	match := magicLinkHref.FindStringSubmatch(sent[len(sent)-1].Body)
+	if match == nil {
+		t.Fatal("email does not contain a link")
+	}
+
+	link, err := url.Parse(html.UnescapeString(match[1]))
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func TestMagicLink_LoginIsSingleUse(t *testing.T) {
+	uc, mail, _ := newMagicLinkTestUseCase(t, nil)
+	ctx := context.Background()
+
+	if err := uc.RequestMagicLink(ctx, magicLinkTestEmail, "10.0.0.1", ""); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	token := tokenFromMail(t, mail)
+
+	response, err := uc.ConsumeMagicLink(ctx, token, "10.0.0.2", "")
+	if err != nil {
+		t.Fatalf("ConsumeMagicLink: %v", err)
+	}
+	if response.Token == "" || response.RefreshToken == "" {
+		t.Fatalf("expected token pair, got %+v", response)
+	}
+
+	if _, err := uc.ConsumeMagicLink(ctx, token, "10.0.0.2", ""); err == nil {
+		t.Fatal("expected error for replayed link")
+	}
+}
+
+func TestMagicLink_UnknownEmailSendsNothing(t *testing.T) {
+	uc, mail, _ := newMagicLinkTestUseCase(t, nil)
+
+	if err := uc.RequestMagicLink(context.Background(), "nobody@example.com", "10.0.0.1", ""); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	if len(mail.sent()) != 0 {
+		t.Fatal("email must not be sent for unknown address")
+	}
+}
+
+func TestMagicLink_Cooldown(t *testing.T) {
+	uc, mail, mr := newMagicLinkTestUseCase(t, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := uc.RequestMagicLink(ctx, magicLinkTestEmail, "10.0.0.1", ""); err != nil {
+			t.Fatalf("RequestMagicLink: %v", err)
+		}
+	}
+	if got := len(mail.sent()); got != 1 {
+		t.Fatalf("sent %d emails during cooldown, want 1", got)
+	}
+
+	mr.FastForward(magicLinkCooldown)
+	if err := uc.RequestMagicLink(ctx, magicLinkTestEmail, "10.0.0.1", ""); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	if got := len(mail.sent()); got != 2 {
+		t.Fatalf("sent %d emails after cooldown, want 2", got)
+	}
+}
+
+func TestMagicLink_Expires(t *testing.T) {
+	uc, mail, mr := newMagicLinkTestUseCase(t, nil)
+	ctx := context.Background()
+
+	if err := uc.RequestMagicLink(ctx, magicLinkTestEmail, "10.0.0.1", ""); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	token := tokenFromMail(t, mail)
+
+	mr.FastForward(DefaultConfig().MagicLinkTTL)
+	if _, err := uc.ConsumeMagicLink(ctx, token, "10.0.0.1", ""); err == nil {
+		t.Fatal("expected error for expired link")
+	}
+}
+
+func TestMagicLink_DeviceBinding(t *testing.T) {
+	uc, mail, _ := newMagicLinkTestUseCase(t, nil)
+	ctx := context.Background()
+
+	if err := uc.RequestMagicLink(ctx, magicLinkTestEmail, "10.0.0.1", "device-a"); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	token := tokenFromMail(t, mail)
+
+	if _, err := uc.ConsumeMagicLink(ctx, token, "10.0.0.1", "device-b"); err == nil {
+		t.Fatal("expected error for another device")
+	}
+	// Неудачная попытка сжигает ссылку
+	if _, err := uc.ConsumeMagicLink(ctx, token, "10.0.0.1", "device-a"); err == nil {
+		t.Fatal("expected link to be consumed by the failed attempt")
+	}
+}
+
+func TestMagicLink_IPBinding(t *testing.T) {
+	config := DefaultConfig()
+	config.MagicLinkBindIP = true
+	uc, mail, _ := newMagicLinkTestUseCase(t, config)
+	ctx := context.Background()
+
+	if err := uc.RequestMagicLink(ctx, magicLinkTestEmail, "10.0.0.1", ""); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	token := tokenFromMail(t, mail)
+
+	if _, err := uc.ConsumeMagicLink(ctx, token, "10.0.0.2", ""); err == nil {
+		t.Fatal("expected error for another IP")
+	}
+}
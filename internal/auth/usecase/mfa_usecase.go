@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/jwthelper"
+	"nodabackend/pkg/totp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// mfaMaxAttemptsPerChallenge после стольких неверных кодов challenge отзывается и нужно войти заново
+	mfaMaxAttemptsPerChallenge = 5
+	// mfaMaxAttemptsPerUser после стольких неверных кодов по всем challenge проверка 2FA
+	// пользователя блокируется на LoginLockoutDuration
+	mfaMaxAttemptsPerUser = 10
+)
+
+var (
+	// errInvalidTOTPCode неверный или уже использованный код из приложения
+	errInvalidTOTPCode = errors.New("invalid code")
+	// errInvalidRecoveryCode неверный или уже использованный код восстановления
+	errInvalidRecoveryCode = errors.New("invalid recovery code")
+	// ErrMFAChallengeExhausted превышено число попыток для MFA challenge
+	ErrMFAChallengeExhausted = errors.New("too many invalid codes, log in again")
+)
+
+// TOTPSetup данные для подключения приложения-аутентификатора
+type TOTPSetup struct {
+	Secret string `json:"secret"` // для ручного ввода
+	URI    string `json:"uri"`    // otpauth:// URI для QR кода
+}
+
+// SetupTOTP генерирует новый TOTP секрет для пользователя.
+// 2FA включается только после подтверждения кодом из приложения (ConfirmTOTP).
+func (uc *AuthUseCase) SetupTOTP(userID uint) (*TOTPSetup, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.IsTOTPEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := uc.encryptor.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Повторный setup заменяет неподтвержденный секрет
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+	if err := uc.userRepo.UpdateTOTPSecret(user.ID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &TOTPSetup{
+		Secret: secret,
+		URI:    totp.KeyURI(uc.config.TOTPIssuer, accountName(user), secret),
+	}, nil
+}
+
+// TOTPConfirmation результат включения 2FA
+type TOTPConfirmation struct {
+	User          *domain.User `json:"user"`
+	RecoveryCodes []string     `json:"recovery_codes"` // показываются один раз
+}
+
+// ConfirmTOTP включает 2FA после проверки первого кода из приложения
+// и выдает коды восстановления
+func (uc *AuthUseCase) ConfirmTOTP(userID uint, code string) (*TOTPConfirmation, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("code is required")
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.IsTOTPEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, errors.New("TOTP setup has not been started")
+	}
+
+	if err := uc.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := uc.generateRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	if err := uc.userRepo.EnableTOTP(user.ID, now); err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return &TOTPConfirmation{
+		User:          user,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// VerifyMFA обменивает MFA challenge токен и код из приложения на пару токенов.
+// Вместо кода из приложения можно передать код восстановления.
+// Неверные коды считаются для challenge и для пользователя: после mfaMaxAttemptsPerChallenge
+// challenge отзывается, после mfaMaxAttemptsPerUser проверка временно блокируется (LoginLockedError).
+func (uc *AuthUseCase) VerifyMFA(ctx context.Context, mfaToken, code, recoveryCode string) (*AuthResponse, error) {
+	if strings.TrimSpace(mfaToken) == "" {
+		return nil, errors.New("MFA token is required")
+	}
+
+	if strings.TrimSpace(code) == "" && strings.TrimSpace(recoveryCode) == "" {
+		return nil, errors.New("code or recovery code is required")
+	}
+
+	claims, err := uc.jwtHelper.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	// Challenge одноразовый и отзывается вместе со всеми сессиями пользователя
+	revoked, err := uc.revocationStore.IsTokenRevoked(ctx, claims.ID, "", claims.UserID, claims.IssuedAtTime())
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	user, err := uc.userRepo.GetUserByID(claims.UserID)
+	if err != nil || !user.IsTOTPEnabled() {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	userKey := mfaUserAttemptKey(user.ID)
+	retryAfter, err := uc.loginAttempts.LoginRetryAfter(ctx, userKey)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	if strings.TrimSpace(code) != "" {
+		err = uc.verifyTOTP(user, code)
+	} else {
+		err = uc.useRecoveryCode(user.ID, recoveryCode)
+	}
+	if errors.Is(err, errInvalidTOTPCode) || errors.Is(err, errInvalidRecoveryCode) {
+		return nil, uc.registerMFAFailure(ctx, claims, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.loginAttempts.ResetLoginFailures(ctx, userKey); err != nil {
+		return nil, err
+	}
+
+	if err := uc.revocationStore.RevokeToken(ctx, claims.ID, uc.tokenRevocationTTL(claims)); err != nil {
+		return nil, err
+	}
+
+	return uc.issueTokens(ctx, user, "")
+}
+
+// registerMFAFailure учитывает неверный код второго фактора.
+// Исчерпанный challenge отзывается, чтобы перебор кодов требовал снова пройти первый фактор.
+func (uc *AuthUseCase) registerMFAFailure(ctx context.Context, claims *jwthelper.Claims, codeErr error) error {
+	ttl := uc.tokenRevocationTTL(claims)
+
+	challengeFailures, err := uc.loginAttempts.RegisterLoginFailure(ctx, "mfa:challenge:"+claims.ID, ttl)
+	if err != nil {
+		return err
+	}
+	userKey := mfaUserAttemptKey(claims.UserID)
+	userFailures, err := uc.loginAttempts.RegisterLoginFailure(ctx, userKey, uc.config.LoginLockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if userFailures >= mfaMaxAttemptsPerUser {
+		if err := uc.loginAttempts.ResetLoginFailures(ctx, userKey); err != nil {
+			return err
+		}
+		if err := uc.loginAttempts.BlockLogin(ctx, userKey, uc.config.LoginLockoutDuration); err != nil {
+			return err
+		}
+		if err := uc.revocationStore.RevokeToken(ctx, claims.ID, ttl); err != nil {
+			return err
+		}
+		log.Printf("[AUTH] Two-factor verification for user %d is locked after repeated invalid codes", claims.UserID)
+		return &LoginLockedError{RetryAfter: uc.config.LoginLockoutDuration}
+	}
+
+	if challengeFailures >= mfaMaxAttemptsPerChallenge {
+		if err := uc.revocationStore.RevokeToken(ctx, claims.ID, ttl); err != nil {
+			return err
+		}
+		return ErrMFAChallengeExhausted
+	}
+
+	return codeErr
+}
+
+// mfaUserAttemptKey ключ счетчика неверных кодов второго фактора пользователя
+func mfaUserAttemptKey(userID uint) string {
+	return "mfa:user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// completeLogin завершает вход после проверки первого фактора:
+// выдает токены или, если включена 2FA, MFA challenge токен
+func (uc *AuthUseCase) completeLogin(ctx context.Context, user *domain.User) (*AuthResponse, error) {
+	if user.IsBanned() {
+		return nil, ErrUserBanned
+	}
+
+	if err := uc.requireVerifiedPhone(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if !user.IsTOTPEnabled() {
+		return uc.issueTokens(ctx, user, "")
+	}
+
+	mfaToken, err := uc.jwtHelper.GenerateMFAToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	}, nil
+}
+
+// verifyTOTP проверяет код из приложения и запоминает его шаг,
+// чтобы тот же код нельзя было использовать повторно
+func (uc *AuthUseCase) verifyTOTP(user *domain.User, code string) error {
+	secret, err := uc.encryptor.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return errInvalidTOTPCode
+	}
+
+	// Условное обновление: из двух конкурентных запросов с одним кодом пройдет только один
+	advanced, err := uc.userRepo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return errInvalidTOTPCode
+	}
+
+	user.TOTPLastStep = step
+	return nil
+}
+
+// accountName имя аккаунта, которое видит пользователь в аутентификаторе
+func accountName(user *domain.User) string {
+	if user.IsEmailVerified() {
+		return *user.Email
+	}
+	return user.Phone
+}
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"nodabackend/pkg/encryption"
+	"nodabackend/pkg/totp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mfaTestUser пользователь с включенной 2FA
+type mfaTestUser struct {
+	user          *domain.User
+	secret        string
+	recoveryCodes []string
+}
+
+// newMFATestUseCase создает usecase с Redis хранилищами в miniredis
+// и пользователя, у которого включена 2FA
+func newMFATestUseCase(t *testing.T) (*AuthUseCase, *fakeUserRepository, *mfaTestUser) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginTestPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Password: string(hash)}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	encryptor, err := encryption.NewEncryptor("test-encryption-key")
+	if err != nil {
+		t.Fatalf("NewEncryptor: %v", err)
+	}
+
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		RecoveryCodeRepo: &fakeRecoveryCodeRepository{},
+		RevocationStore:  repository.NewTokenRevocationStore(client),
+		LoginAttempts:    repository.NewLoginAttemptStore(client),
+		JWTHelper:        testJWTHelper,
+		Encryptor:        encryptor,
+	}, DefaultConfig())
+
+	setup, err := uc.SetupTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("SetupTOTP: %v", err)
+	}
+	confirmation, err := uc.ConfirmTOTP(user.ID, totpCode(t, setup.Secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+
+	return uc, users, &mfaTestUser{user: user, secret: setup.Secret, recoveryCodes: confirmation.RecoveryCodes}
+}
+
+// totpCode возвращает код приложения со сдвигом на steps шагов от текущего
+func totpCode(t *testing.T, secret string, steps int) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, time.Now().Add(time.Duration(steps)*totp.Period))
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	return code
+}
+
+// wrongTOTPCode возвращает код, который не подходит ни к одному из допустимых шагов
+func wrongTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	for _, code := range []string{"000000", "111111", "222222", "333333"} {
+		if _, ok := totp.Validate(secret, code, time.Now()); !ok {
+			return code
+		}
+	}
+	t.Fatal("no invalid code found")
+	return ""
+}
+
+// mfaChallenge выполняет вход по паролю и возвращает MFA challenge токен
+func mfaChallenge(t *testing.T, uc *AuthUseCase) string {
+	t.Helper()
+
+	response, err := uc.AuthenticateUser(context.Background(), loginTestPhone, loginTestPassword, "")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	if !response.MFARequired || response.MFAToken == "" || response.Token != "" {
+		t.Fatalf("expected MFA challenge instead of tokens, got %+v", response)
+	}
+	return response.MFAToken
+}
+
+func TestMFA_LoginIssuesChallenge(t *testing.T) {
+	uc, _, mfa := newMFATestUseCase(t)
+	ctx := context.Background()
+
+	challenge := mfaChallenge(t, uc)
+
+	response, err := uc.VerifyMFA(ctx, challenge, totpCode(t, mfa.secret, 1), "")
+	if err != nil {
+		t.Fatalf("VerifyMFA: %v", err)
+	}
+	if response.Token == "" || response.RefreshToken == "" {
+		t.Fatal("tokens must be issued after the second factor")
+	}
+
+	// Challenge одноразовый
+	if _, err := uc.VerifyMFA(ctx, challenge, totpCode(t, mfa.secret, 1), ""); err == nil {
+		t.Fatal("used challenge must be rejected")
+	}
+}
+
+func TestMFA_WrongCodeRejected(t *testing.T) {
+	uc, _, mfa := newMFATestUseCase(t)
+	ctx := context.Background()
+
+	challenge := mfaChallenge(t, uc)
+	if _, err := uc.VerifyMFA(ctx, challenge, wrongTOTPCode(t, mfa.secret), ""); err == nil {
+		t.Fatal("wrong code must be rejected")
+	}
+
+	// Одна ошибка не сжигает challenge
+	if _, err := uc.VerifyMFA(ctx, challenge, totpCode(t, mfa.secret, 1), ""); err != nil {
+		t.Fatalf("VerifyMFA after a typo: %v", err)
+	}
+}
+
+func TestMFA_ReplayedStepRejected(t *testing.T) {
+	uc, _, mfa := newMFATestUseCase(t)
+	ctx := context.Background()
+
+	// Код, которым 2FA была подтверждена, повторно не принимается
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), totpCode(t, mfa.secret, 0), ""); err == nil {
+		t.Fatal("code used for confirmation must be rejected")
+	}
+
+	code := totpCode(t, mfa.secret, 1)
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), code, ""); err != nil {
+		t.Fatalf("VerifyMFA: %v", err)
+	}
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), code, ""); err == nil {
+		t.Fatal("replayed code must be rejected")
+	}
+}
+
+func TestMFA_RecoveryCodeUsedOnce(t *testing.T) {
+	uc, _, mfa := newMFATestUseCase(t)
+	ctx := context.Background()
+
+	if len(mfa.recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes; want %d", len(mfa.recoveryCodes), recoveryCodeCount)
+	}
+	recoveryCode := mfa.recoveryCodes[0]
+
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), "", recoveryCode); err != nil {
+		t.Fatalf("VerifyMFA with recovery code: %v", err)
+	}
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), "", recoveryCode); err == nil {
+		t.Fatal("recovery code must be accepted only once")
+	}
+
+	profile, err := uc.GetProfile(mfa.user.ID)
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	if *profile.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Fatalf("remaining recovery codes = %d; want %d", *profile.RecoveryCodesRemaining, recoveryCodeCount-1)
+	}
+}
+
+func TestMFA_ChallengeRevokedAfterFailedAttempts(t *testing.T) {
+	uc, _, mfa := newMFATestUseCase(t)
+	ctx := context.Background()
+
+	challenge := mfaChallenge(t, uc)
+	wrong := wrongTOTPCode(t, mfa.secret)
+
+	var err error
+	for i := 0; i < mfaMaxAttemptsPerChallenge; i++ {
+		_, err = uc.VerifyMFA(ctx, challenge, wrong, "")
+	}
+	if !errors.Is(err, ErrMFAChallengeExhausted) {
+		t.Fatalf("expected ErrMFAChallengeExhausted, got %v", err)
+	}
+
+	if _, err := uc.VerifyMFA(ctx, challenge, totpCode(t, mfa.secret, 1), ""); err == nil {
+		t.Fatal("exhausted challenge must be rejected even with a valid code")
+	}
+
+	// Новый вход по паролю дает новый challenge
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), totpCode(t, mfa.secret, 1), ""); err != nil {
+		t.Fatalf("VerifyMFA with a new challenge: %v", err)
+	}
+}
+
+func TestMFA_UserLockedAfterFailedAttempts(t *testing.T) {
+	uc, _, mfa := newMFATestUseCase(t)
+	ctx := context.Background()
+	wrong := wrongTOTPCode(t, mfa.secret)
+
+	// Ошибки считаются по всем challenge пользователя, новый вход счетчик не сбрасывает
+	var err error
+	for i := 0; i < mfaMaxAttemptsPerUser; i++ {
+		_, err = uc.VerifyMFA(ctx, mfaChallenge(t, uc), wrong, "")
+	}
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected LoginLockedError, got %v", err)
+	}
+
+	if _, err := uc.VerifyMFA(ctx, mfaChallenge(t, uc), totpCode(t, mfa.secret, 1), ""); !errors.As(err, &locked) {
+		t.Fatalf("valid code must be rejected while locked, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// oauthStateTTL сколько ждем возврата пользователя от провайдера
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthAuthorization адрес авторизации у провайдера и секрет, привязывающий авторизацию к браузеру.
+// Binding сохраняется в HttpOnly cookie и передается в HandleOAuthCallback: без него чужой
+// callback с действительным state нельзя завершить в браузере жертвы (login CSRF).
+type OAuthAuthorization struct {
+	URL     string
+	Binding string
+}
+
+// OAuthCallbackResult результат возврата от провайдера:
+// токены (или MFA challenge) при входе, либо новая связь при привязке аккаунта
+type OAuthCallbackResult struct {
+	*AuthResponse
+	LinkedIdentity *domain.LinkedIdentity `json:"linked_identity,omitempty"`
+}
+
+// BeginOAuthLogin начинает вход через внешнего провайдера и возвращает адрес авторизации
+func (uc *AuthUseCase) BeginOAuthLogin(ctx context.Context, provider string) (*OAuthAuthorization, error) {
+	return uc.beginOAuth(ctx, provider, 0)
+}
+
+// BeginOAuthLink начинает привязку внешнего аккаунта к вошедшему пользователю
+func (uc *AuthUseCase) BeginOAuthLink(ctx context.Context, userID uint, provider string) (*OAuthAuthorization, error) {
+	if _, err := uc.userRepo.GetUserByID(userID); err != nil {
+		return nil, errors.New("user not found")
+	}
+	return uc.beginOAuth(ctx, provider, userID)
+}
+
+// HandleOAuthCallback завершает авторизацию у провайдера: проверяет state и его привязку к браузеру
+// (binding из OAuthAuthorization), обменивает code на ID токен и выполняет вход или привязку аккаунта
+func (uc *AuthUseCase) HandleOAuthCallback(ctx context.Context, providerName, code, state, binding string) (*OAuthCallbackResult, error) {
+	if strings.TrimSpace(code) == "" || strings.TrimSpace(state) == "" {
+		return nil, errors.New("code and state are required")
+	}
+
+	provider, err := uc.oauthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	// State одноразовый: повторный запрос с тем же state отклоняется
+	data, err := uc.oauthStates.PopOAuthState(ctx, state)
+	if errors.Is(err, domain.ErrOAuthStateNotFound) {
+		return nil, errors.New("invalid or expired OAuth state")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Provider != provider.Name() || !data.MatchesBinding(binding) {
+		return nil, errors.New("invalid or expired OAuth state")
+	}
+
+	identity, err := provider.Exchange(ctx, code, data.CodeVerifier, data.Nonce)
+	if err != nil {
+		return nil, errors.New("OAuth authorization failed")
+	}
+
+	if data.LinkUserID != 0 {
+		linked, err := uc.linkOAuthIdentity(data.LinkUserID, identity)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthCallbackResult{LinkedIdentity: linked}, nil
+	}
+
+	authResponse, err := uc.loginWithOAuthIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthCallbackResult{AuthResponse: authResponse}, nil
+}
+
+// GetLinkedIdentities возвращает внешние аккаунты, привязанные к пользователю
+func (uc *AuthUseCase) GetLinkedIdentities(userID uint) ([]*domain.LinkedIdentity, error) {
+	return uc.linkedIdentityRepo.GetLinkedIdentitiesByUserID(userID)
+}
+
+// UnlinkOAuthIdentity отвязывает аккаунт провайдера от пользователя
+func (uc *AuthUseCase) UnlinkOAuthIdentity(userID uint, provider string) error {
+	identities, err := uc.linkedIdentityRepo.GetLinkedIdentitiesByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			return uc.linkedIdentityRepo.DeleteLinkedIdentity(userID, provider)
+		}
+	}
+	return errors.New("provider is not linked")
+}
+
+// beginOAuth сохраняет state, nonce, PKCE verifier и хеш привязки к браузеру и формирует адрес авторизации
+func (uc *AuthUseCase) beginOAuth(ctx context.Context, providerName string, linkUserID uint) (*OAuthAuthorization, error) {
+	provider, err := uc.oauthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := generateOAuthValue()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateOAuthValue()
+	if err != nil {
+		return nil, err
+	}
+	binding, err := generateOAuthValue()
+	if err != nil {
+		return nil, err
+	}
+
+	data := &domain.OAuthState{
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		BindingHash:  domain.HashOAuthBinding(binding),
+		LinkUserID:   linkUserID,
+	}
+	if err := uc.oauthStates.SaveOAuthState(ctx, state, data, oauthStateTTL); err != nil {
+		return nil, err
+	}
+
+	return &OAuthAuthorization{
+		URL:     provider.AuthCodeURL(state, data.Nonce, data.CodeVerifier),
+		Binding: binding,
+	}, nil
+}
+
+// loginWithOAuthIdentity выполняет вход по привязанному внешнему аккаунту.
+// Аккаунт не создается и не связывается автоматически по email:
+// иначе владелец внешнего аккаунта с чужим адресом получил бы доступ к пользователю.
+// Ответ не зависит от того, есть ли пользователь с тем же email, чтобы не раскрывать аккаунты.
+func (uc *AuthUseCase) loginWithOAuthIdentity(ctx context.Context, identity *domain.ExternalIdentity) (*AuthResponse, error) {
+	linked, err := uc.linkedIdentityRepo.GetLinkedIdentity(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, errors.New("no account is linked to this provider")
+	}
+
+	user, err := uc.userRepo.GetUserByID(linked.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Вход через провайдера — первый фактор, 2FA по-прежнему требуется
+	return uc.completeLogin(ctx, user)
+}
+
+// linkOAuthIdentity привязывает внешний аккаунт к пользователю
+func (uc *AuthUseCase) linkOAuthIdentity(userID uint, identity *domain.ExternalIdentity) (*domain.LinkedIdentity, error) {
+	if existing, err := uc.linkedIdentityRepo.GetLinkedIdentity(identity.Provider, identity.Subject); err == nil {
+		if existing.UserID != userID {
+			return nil, errors.New("provider account is linked to another user")
+		}
+		return existing, nil
+	}
+
+	identities, err := uc.linkedIdentityRepo.GetLinkedIdentitiesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, linked := range identities {
+		if linked.Provider == identity.Provider {
+			return nil, errors.New("another account of this provider is already linked")
+		}
+	}
+
+	linked := &domain.LinkedIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := uc.linkedIdentityRepo.CreateLinkedIdentity(linked); err != nil {
+		return nil, err
+	}
+	return linked, nil
+}
+
+// oauthProvider находит настроенного провайдера по имени
+func (uc *AuthUseCase) oauthProvider(name string) (domain.OAuthProvider, error) {
+	provider, ok := uc.oauthProviders[strings.ToLower(name)]
+	if !ok {
+		return nil, errors.New("unknown OAuth provider")
+	}
+	return provider, nil
+}
+
+// generateOAuthValue генерирует случайный state или nonce
+func generateOAuthValue() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
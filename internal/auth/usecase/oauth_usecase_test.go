@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"context"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/oauth"
+	"nodabackend/internal/auth/oauth/oauthtest"
+	"nodabackend/internal/auth/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+var testIdPUser = oauthtest.User{
+	Subject:       "idp-user-1",
+	Email:         "user@example.com",
+	EmailVerified: true,
+	Name:          "Test User",
+}
+
+// oauthTestEnv usecase, подключенный к MockIdP
+type oauthTestEnv struct {
+	uc    *AuthUseCase
+	idp   *oauthtest.MockIdP
+	users *fakeUserRepository
+	user  *domain.User
+}
+
+// newOAuthTestUseCase создает usecase с провайдером "mock", state хранится в miniredis
+func newOAuthTestUseCase(t *testing.T) *oauthTestEnv {
+	t.Helper()
+
+	idp, err := oauthtest.NewMockIdP()
+	if err != nil {
+		t.Fatalf("NewMockIdP: %v", err)
+	}
+	t.Cleanup(idp.Close)
+
+	provider, err := oauth.NewOIDCProvider(context.Background(), oauth.OIDCConfig{
+		Name:         "mock",
+		IssuerURL:    idp.Issuer(),
+		ClientID:     oauthtest.ClientID,
+		ClientSecret: oauthtest.ClientSecret,
+		RedirectURL:  "http://localhost:3000/api/v1/auth/oauth/mock/callback",
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	users := newFakeUserRepository()
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:           users,
+		RefreshTokenRepo:   &fakeRefreshTokenRepository{},
+		SessionRepo:        newFakeSessionRepository(),
+		LinkedIdentityRepo: &fakeLinkedIdentityRepository{},
+		OAuthStates:        repository.NewOAuthStateStore(client),
+		OAuthProviders:     []domain.OAuthProvider{provider},
+		JWTHelper:          testJWTHelper,
+	}, DefaultConfig())
+
+	user := &domain.User{Phone: "+77001234567", Name: "Test User"}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return &oauthTestEnv{uc: uc, idp: idp, users: users, user: user}
+}
+
+// authorize проходит авторизацию у MockIdP по адресу, который выдал usecase
+func (e *oauthTestEnv) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+
+	code, state, err := e.idp.Authorize(authURL, testIdPUser)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return code, state
+}
+
+// link привязывает аккаунт MockIdP к тестовому пользователю
+func (e *oauthTestEnv) link(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+
+	authorization, err := e.uc.BeginOAuthLink(ctx, e.user.ID, "mock")
+	if err != nil {
+		t.Fatalf("BeginOAuthLink: %v", err)
+	}
+
+	code, state := e.authorize(t, authorization.URL)
+	result, err := e.uc.HandleOAuthCallback(ctx, "mock", code, state, authorization.Binding)
+	if err != nil {
+		t.Fatalf("HandleOAuthCallback (link): %v", err)
+	}
+	if result.LinkedIdentity == nil || result.AuthResponse != nil {
+		t.Fatalf("expected linked identity without tokens, got %+v", result)
+	}
+}
+
+// login проходит вход через MockIdP
+func (e *oauthTestEnv) login(t *testing.T) (*OAuthCallbackResult, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authorization, err := e.uc.BeginOAuthLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginOAuthLogin: %v", err)
+	}
+
+	code, state := e.authorize(t, authorization.URL)
+	return e.uc.HandleOAuthCallback(ctx, "mock", code, state, authorization.Binding)
+}
+
+func TestOAuth_LinkAndLogin(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+
+	result, err := env.login(t)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if result.AuthResponse == nil || result.Token == "" || result.RefreshToken == "" {
+		t.Fatalf("expected token pair, got %+v", result)
+	}
+	if result.User.ID != env.user.ID {
+		t.Fatalf("logged in as user %d, want %d", result.User.ID, env.user.ID)
+	}
+}
+
+func TestOAuth_RejectsUnlinkedIdentity(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+
+	if _, err := env.login(t); err == nil {
+		t.Fatal("expected error for identity that is not linked")
+	}
+}
+
+func TestOAuth_DoesNotLinkByEmail(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+
+	// Пользователь с тем же email не должен получить вход без явной привязки
+	email := testIdPUser.Email
+	now := time.Now()
+	env.user.Email = &email
+	env.user.EmailVerifiedAt = &now
+	if err := env.users.save(env.user); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// Ответ такой же, как без пользователя с этим email, чтобы не раскрывать аккаунт
+	_, err := env.login(t)
+	if err == nil || err.Error() != "no account is linked to this provider" {
+		t.Fatalf("expected generic error, got %v", err)
+	}
+}
+
+func TestOAuth_StateIsSingleUse(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+	ctx := context.Background()
+
+	authorization, err := env.uc.BeginOAuthLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginOAuthLogin: %v", err)
+	}
+	code, state := env.authorize(t, authorization.URL)
+
+	if _, err := env.uc.HandleOAuthCallback(ctx, "mock", code, state, authorization.Binding); err != nil {
+		t.Fatalf("first callback: %v", err)
+	}
+	if _, err := env.uc.HandleOAuthCallback(ctx, "mock", code, state, authorization.Binding); err == nil {
+		t.Fatal("expected error for reused state")
+	}
+}
+
+func TestOAuth_RejectsUnknownState(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+
+	authorization, err := env.uc.BeginOAuthLogin(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("BeginOAuthLogin: %v", err)
+	}
+	code, _ := env.authorize(t, authorization.URL)
+
+	if _, err := env.uc.HandleOAuthCallback(context.Background(), "mock", code, "forged-state", authorization.Binding); err == nil {
+		t.Fatal("expected error for unknown state")
+	}
+}
+
+func TestOAuth_RejectsCallbackFromAnotherBrowser(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+	ctx := context.Background()
+
+	victim, err := env.uc.BeginOAuthLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginOAuthLogin: %v", err)
+	}
+
+	// Злоумышленник начинает вход в свой аккаунт и подсовывает жертве callback со своими code и state
+	for _, binding := range []string{"", victim.Binding} {
+		attacker, err := env.uc.BeginOAuthLogin(ctx, "mock")
+		if err != nil {
+			t.Fatalf("BeginOAuthLogin: %v", err)
+		}
+		code, state := env.authorize(t, attacker.URL)
+
+		if _, err := env.uc.HandleOAuthCallback(ctx, "mock", code, state, binding); err == nil {
+			t.Fatalf("expected error for callback with binding %q", binding)
+		}
+	}
+}
+
+func TestOAuth_RejectsTamperedNonce(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+	env.idp.ClaimsHook = func(claims jwt.MapClaims) {
+		claims["nonce"] = "replayed-nonce"
+	}
+
+	if _, err := env.login(t); err == nil {
+		t.Fatal("expected error for ID token with foreign nonce")
+	}
+}
+
+func TestOAuth_RejectsIdentityLinkedToAnotherUser(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+
+	other := &domain.User{Phone: "+77007654321", Name: "Other User"}
+	if err := env.users.CreateUser(other); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	ctx := context.Background()
+	authorization, err := env.uc.BeginOAuthLink(ctx, other.ID, "mock")
+	if err != nil {
+		t.Fatalf("BeginOAuthLink: %v", err)
+	}
+	code, state := env.authorize(t, authorization.URL)
+
+	if _, err := env.uc.HandleOAuthCallback(ctx, "mock", code, state, authorization.Binding); err == nil {
+		t.Fatal("expected error when identity is linked to another user")
+	}
+}
+
+func TestOAuth_RequiresSecondFactor(t *testing.T) {
+	env := newOAuthTestUseCase(t)
+	env.link(t)
+
+	now := time.Now()
+	env.user.TOTPSecret = "encrypted-secret"
+	env.user.TOTPEnabledAt = &now
+	if err := env.users.save(env.user); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	result, err := env.login(t)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !result.MFARequired || result.MFAToken == "" || result.Token != "" {
+		t.Fatalf("expected MFA challenge, got %+v", result.AuthResponse)
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/otp"
+	"nodabackend/pkg/sms"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RequestLoginOTP отправляет на телефон код для входа без пароля.
+// Ответ не зависит от того, зарегистрирован ли номер: если пользователь не найден
+// и автоматическая регистрация выключена, код не отправляется, а ошибки лимитов
+// и доставки только логируются — чтобы по ответу нельзя было перебрать номера.
+func (uc *AuthUseCase) RequestLoginOTP(ctx context.Context, phone string) error {
+	if err := validatePhone(phone); err != nil {
+		return err
+	}
+
+	if !uc.config.OTPAutoRegister {
+		if user, _ := uc.userRepo.GetUserByPhone(phone); user == nil {
+			return nil
+		}
+	}
+
+	code, err := uc.otpService.GenerateOTP(ctx, phone, otp.OTPTypeLogin)
+	if err != nil {
+		log.Printf("[AUTH] Failed to generate login code: %v", err)
+		return nil
+	}
+
+	err = uc.smsService.SendSMS(&sms.SMSMessage{
+		To:   phone,
+		Body: fmt.Sprintf("Ваш код для входа: %s", code),
+	})
+	if err != nil {
+		log.Printf("[AUTH] Failed to send login code: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyLoginOTP проверяет код из SMS и выдает токены
+func (uc *AuthUseCase) VerifyLoginOTP(ctx context.Context, phone, code string) (*AuthResponse, error) {
+	if err := validatePhone(phone); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("code is required")
+	}
+
+	valid, err := uc.otpService.ValidateOTP(ctx, phone, otp.OTPTypeLogin, code)
+	if err != nil || !valid {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	user, err := uc.userRepo.GetUserByPhone(phone)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !uc.config.OTPAutoRegister {
+			return nil, errors.New("invalid or expired code")
+		}
+
+		// Номер удаленного аккаунта остается занят до обезличивания
+		taken, err := uc.userRepo.IsPhoneTaken(phone)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, domain.ErrPhoneTaken
+		}
+
+		// Первый вход по SMS — создаем пользователя без пароля
+		user = &domain.User{Phone: phone}
+		if err := uc.userRepo.CreateUser(user); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		// Сбой базы не должен приводить к созданию второго аккаунта с тем же номером
+		return nil, err
+	}
+
+	// Код из SMS доказывает владение номером
+	if err := uc.markPhoneVerified(user); err != nil {
+		return nil, err
+	}
+
+	// SMS код — только один фактор, при включенной 2FA нужен еще код из приложения
+	return uc.completeLogin(ctx, user)
+}
+
+// userOTPKey возвращает ключ OTP, привязанный к ID пользователя, а не к каналу доставки
+func userOTPKey(user *domain.User) string {
+	return strconv.FormatUint(uint64(user.ID), 10)
+}
+
+// validatePhone проверяет наличие и формат телефона
+func validatePhone(phone string) error {
+	if strings.TrimSpace(phone) == "" {
+		return errors.New("phone is required")
+	}
+
+	if !phoneRegex.MatchString(phone) {
+		return errors.New("invalid phone format")
+	}
+
+	return nil
+}
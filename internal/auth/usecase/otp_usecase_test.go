@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"nodabackend/pkg/otp"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// unavailableUserRepository имитирует сбой базы при поиске по телефону
+type unavailableUserRepository struct {
+	*fakeUserRepository
+}
+
+func (r *unavailableUserRepository) GetUserByPhone(phone string) (*domain.User, error) {
+	return nil, errors.New("connection refused")
+}
+
+// newOTPLoginTestUseCase создает usecase с автоматической регистрацией при входе по SMS
+func newOTPLoginTestUseCase(t *testing.T, users domain.UserRepository) *AuthUseCase {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	config := DefaultConfig()
+	config.OTPAutoRegister = true
+
+	return NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		RevocationStore:  repository.NewTokenRevocationStore(client),
+		JWTHelper:        testJWTHelper,
+		OTPService:       otp.NewOTPService(client, otp.DefaultConfig()),
+		SMSService:       &fakeSMSService{},
+	}, config)
+}
+
+func TestLoginOTP_RequestDoesNotRevealRegisteredPhones(t *testing.T) {
+	uc, _, smsService, _ := newProfileTestUseCase(t)
+	ctx := context.Background()
+
+	if err := uc.RequestLoginOTP(ctx, "+77009876543"); err != nil {
+		t.Fatalf("unknown phone: %v", err)
+	}
+	if len(smsService.sent()) != 0 {
+		t.Fatal("code must not be sent to an unknown phone")
+	}
+
+	if err := uc.RequestLoginOTP(ctx, loginTestPhone); err != nil {
+		t.Fatalf("registered phone: %v", err)
+	}
+	if len(smsService.sent()) != 1 {
+		t.Fatal("code must be sent to a registered phone")
+	}
+
+	// Ограничение частоты не отличает зарегистрированный номер от незарегистрированного
+	if err := uc.RequestLoginOTP(ctx, loginTestPhone); err != nil {
+		t.Fatalf("rate limited request must look like a successful one, got %v", err)
+	}
+	if len(smsService.sent()) != 1 {
+		t.Fatal("code must not be resent during the cooldown")
+	}
+
+	if err := uc.RequestLoginOTP(ctx, "not-a-phone"); err == nil {
+		t.Fatal("invalid phone format must be rejected")
+	}
+}
+
+func TestLoginOTP_AutoRegisterCreatesUserForUnknownPhone(t *testing.T) {
+	users := newFakeUserRepository()
+	uc := newOTPLoginTestUseCase(t, users)
+	ctx := context.Background()
+
+	code, err := uc.otpService.GenerateOTP(ctx, loginTestPhone, otp.OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	response, err := uc.VerifyLoginOTP(ctx, loginTestPhone, code)
+	if err != nil {
+		t.Fatalf("VerifyLoginOTP: %v", err)
+	}
+	if response.Token == "" {
+		t.Fatal("tokens must be issued")
+	}
+
+	user, err := users.GetUserByPhone(loginTestPhone)
+	if err != nil || !user.IsPhoneVerified() {
+		t.Fatalf("user with a verified phone must be created, got %+v, %v", user, err)
+	}
+}
+
+func TestLoginOTP_AutoRegisterSkippedOnRepositoryError(t *testing.T) {
+	users := newFakeUserRepository()
+	uc := newOTPLoginTestUseCase(t, &unavailableUserRepository{fakeUserRepository: users})
+	ctx := context.Background()
+
+	code, err := uc.otpService.GenerateOTP(ctx, loginTestPhone, otp.OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	if _, err := uc.VerifyLoginOTP(ctx, loginTestPhone, code); err == nil {
+		t.Fatal("repository error must be returned")
+	}
+
+	if len(users.users) != 0 {
+		t.Fatal("user must not be created when the lookup fails")
+	}
+}
+
+func TestLoginOTP_AutoRegisterRejectsPhoneOfDeletedUser(t *testing.T) {
+	users := newFakeUserRepository()
+	deleted := &domain.User{Phone: loginTestPhone}
+	users.CreateUser(deleted)
+	users.DeleteUser(deleted.ID)
+	uc := newOTPLoginTestUseCase(t, users)
+	ctx := context.Background()
+
+	code, err := uc.otpService.GenerateOTP(ctx, loginTestPhone, otp.OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	if _, err := uc.VerifyLoginOTP(ctx, loginTestPhone, code); !errors.Is(err, domain.ErrPhoneTaken) {
+		t.Fatalf("phone of a deleted user must stay taken until purge, got %v", err)
+	}
+	if len(users.users) != 1 {
+		t.Fatal("second user with the same phone must not be created")
+	}
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/mailer"
+	"nodabackend/pkg/otp"
+	"nodabackend/pkg/sms"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ForgotPassword отправляет код для сброса пароля по SMS или на email,
+// в зависимости от того, что указано в login.
+// Ответ не зависит от того, существует ли аккаунт: код генерируется и отправляется в фоне,
+// поэтому ответ не дольше, чем для неизвестного логина, а ошибки доставки только логируются.
+func (uc *AuthUseCase) ForgotPassword(ctx context.Context, login string) error {
+	if err := validateLogin(login); err != nil {
+		return err
+	}
+
+	user, err := uc.findUserByLogin(login)
+	if err != nil {
+		return nil
+	}
+
+	byEmail := strings.Contains(login, "@")
+	uc.runInBackground(func() {
+		// Запрос клиента к этому моменту уже завершен, его отмена не должна прерывать отправку
+		uc.sendPasswordResetCode(context.WithoutCancel(ctx), user, byEmail)
+	})
+
+	return nil
+}
+
+// sendPasswordResetCode генерирует код сброса пароля и отправляет его на email или по SMS
+func (uc *AuthUseCase) sendPasswordResetCode(ctx context.Context, user *domain.User, byEmail bool) {
+	code, err := uc.otpService.GenerateOTP(ctx, userOTPKey(user), otp.OTPTypeResetPassword)
+	if err != nil {
+		log.Printf("[AUTH] Failed to generate password reset code for user %d: %v", user.ID, err)
+		return
+	}
+
+	if byEmail {
+		err = uc.sendPasswordResetEmail(*user.Email, code)
+	} else {
+		err = uc.smsService.SendSMS(&sms.SMSMessage{
+			To:   user.Phone,
+			Body: fmt.Sprintf("Ваш код для сброса пароля: %s", code),
+		})
+	}
+	if err != nil {
+		log.Printf("[AUTH] Failed to send password reset code to user %d: %v", user.ID, err)
+	}
+}
+
+// ResetPassword устанавливает новый пароль по коду и завершает все сессии пользователя
+func (uc *AuthUseCase) ResetPassword(ctx context.Context, login, code, newPassword string) error {
+	if err := validateLogin(login); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return errors.New("code is required")
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	user, err := uc.findUserByLogin(login)
+	if err != nil {
+		return errors.New("invalid or expired code")
+	}
+
+	valid, err := uc.otpService.ValidateOTP(ctx, userOTPKey(user), otp.OTPTypeResetPassword, code)
+	if err != nil || !valid {
+		return errors.New("invalid or expired code")
+	}
+
+	if err := uc.setPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	return uc.revokeAllSessions(ctx, user.ID)
+}
+
+// ChangePassword меняет пароль авторизованного пользователя.
+// Все остальные сессии завершаются, текущему клиенту выдаются новые токены.
+func (uc *AuthUseCase) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) (*AuthResponse, error) {
+	if strings.TrimSpace(currentPassword) == "" {
+		return nil, errors.New("current password is required")
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword))
+	if err != nil {
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if err := uc.setPassword(user, newPassword); err != nil {
+		return nil, err
+	}
+
+	if err := uc.revokeAllSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return uc.issueTokens(ctx, user, "")
+}
+
+// setPassword хеширует и сохраняет новый пароль пользователя.
+// Новый пароль снимает требование администратора сбросить пароль.
+func (uc *AuthUseCase) setPassword(user *domain.User, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.Password = string(hashedPassword)
+	user.PasswordResetRequired = false
+	return uc.userRepo.UpdateUserPassword(user.ID, user.Password)
+}
+
+// sendPasswordResetEmail отправляет код сброса пароля на email
+func (uc *AuthUseCase) sendPasswordResetEmail(email, code string) error {
+	body := fmt.Sprintf(`
+		<h2>Сброс пароля</h2>
+		<p>Ваш код для сброса пароля: <strong>%s</strong></p>
+		<br>
+		<p>Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.</p>
+	`, code)
+
+	return uc.mailer.SendEmail(&mailer.EmailMessage{
+		To:      []string{email},
+		Subject: "Сброс пароля",
+		Body:    body,
+		IsHTML:  true,
+	})
+}
+
+// validateLogin проверяет телефон или email, указанный для входа
+func validateLogin(login string) error {
+	if strings.Contains(login, "@") {
+		return validateEmail(normalizeEmail(login))
+	}
+	return validatePhone(login)
+}
+
+// validatePassword проверяет требования к паролю
+func validatePassword(password string) error {
+	if strings.TrimSpace(password) == "" {
+		return errors.New("password is required")
+	}
+
+	// Валидация пароля (минимум 6 символов)
+	if len(password) < 6 {
+		return errors.New("password must be at least 6 characters long")
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"nodabackend/pkg/sms"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingSMSService отправляет SMS только после закрытия release, как медленный провайдер
+type blockingSMSService struct {
+	fakeSMSService
+	release chan struct{}
+}
+
+func (s *blockingSMSService) SendSMS(msg *sms.SMSMessage) error {
+	<-s.release
+	return s.fakeSMSService.SendSMS(msg)
+}
+
+func TestForgotPassword_SendsCodeInBackground(t *testing.T) {
+	uc, _, _, _ := newProfileTestUseCase(t)
+	smsService := &blockingSMSService{release: make(chan struct{})}
+	uc.smsService = smsService
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Ответ не ждет провайдера SMS: для существующего аккаунта он так же быстр, как для неизвестного
+	done := make(chan error, 1)
+	go func() {
+		for _, login := range []string{loginTestPhone, "+77009999999"} {
+			if err := uc.ForgotPassword(ctx, login); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ForgotPassword: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ForgotPassword waited for the SMS provider")
+	}
+
+	// Отмена запроса клиента не прерывает отправку
+	cancel()
+	close(smsService.release)
+	uc.background.Wait()
+
+	sent := smsService.sent()
+	if len(sent) != 1 || sent[0].To != loginTestPhone || !strings.Contains(sent[0].Body, "сброса пароля") {
+		t.Fatalf("expected one reset code to the registered phone, got %+v", sent)
+	}
+}
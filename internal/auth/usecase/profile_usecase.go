@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/otp"
+	"nodabackend/pkg/sms"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	// Встроенная база часовых поясов: проверка timezone не зависит от tzdata в контейнере
+	_ "time/tzdata"
+)
+
+const (
+	maxNameLength      = 100
+	maxAvatarURLLength = 512
+
+	// deletedUserPurgeInterval как часто проверяются аккаунты с истекшим сроком удаления
+	deletedUserPurgeInterval = time.Hour
+	// deletedUserPurgeBatch сколько аккаунтов обезличивается за один проход
+	deletedUserPurgeBatch = 100
+)
+
+// localeRegex упрощенная проверка тега языка BCP 47: "ru", "en-US", "zh-Hant-TW"
+var localeRegex = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// ProfileUpdate изменяемые поля профиля; nil — поле не меняется
+type ProfileUpdate struct {
+	Name      *string
+	AvatarURL *string // пустая строка удаляет аватар
+	Locale    *string
+	Timezone  *string
+}
+
+// AccountDeletion результат удаления аккаунта
+type AccountDeletion struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // после этого момента персональные данные стираются безвозвратно
+}
+
+// UpdateProfile меняет имя, аватар, язык и часовой пояс пользователя
+func (uc *AuthUseCase) UpdateProfile(userID uint, update ProfileUpdate) (*domain.User, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, errors.New("name is required")
+		}
+		if utf8.RuneCountInString(name) > maxNameLength {
+			return nil, fmt.Errorf("name must be at most %d characters long", maxNameLength)
+		}
+		user.Name = name
+	}
+
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if err := validateAvatarURL(avatarURL); err != nil {
+			return nil, err
+		}
+		user.AvatarURL = avatarURL
+	}
+
+	if update.Locale != nil {
+		locale := strings.TrimSpace(*update.Locale)
+		if locale != "" && (len(locale) > 35 || !localeRegex.MatchString(locale)) {
+			return nil, errors.New("invalid locale")
+		}
+		user.Locale = locale
+	}
+
+	if update.Timezone != nil {
+		timezone := strings.TrimSpace(*update.Timezone)
+		if timezone != "" {
+			// LoadLocation принимает и "Local", который зависит от сервера
+			if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+				return nil, errors.New("invalid timezone")
+			}
+		}
+		user.Timezone = timezone
+	}
+
+	if err := uc.userRepo.UpdateUserProfile(user); err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// RequestPhoneChange запоминает новый телефон и отправляет на него код подтверждения.
+// Телефон меняется только в ConfirmPhoneChange, поэтому до подтверждения вход идет по старому номеру.
+func (uc *AuthUseCase) RequestPhoneChange(ctx context.Context, userID uint, phone string) error {
+	phone = strings.TrimSpace(phone)
+	if err := validatePhone(phone); err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if phone == user.Phone {
+		return errors.New("new phone must differ from the current one")
+	}
+	taken, err := uc.userRepo.IsPhoneTaken(phone)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return domain.ErrPhoneTaken
+	}
+
+	code, err := uc.otpService.GenerateOTP(ctx, userOTPKey(user), otp.OTPTypeChangePhone)
+	if err != nil {
+		return err
+	}
+
+	// Код привязан к пользователю, а номер — к записи в базе: подтвердить можно только тот номер,
+	// на который код был отправлен
+	user.PendingPhone = &phone
+	if err := uc.userRepo.UpdateUserPendingPhone(user.ID, &phone); err != nil {
+		return err
+	}
+
+	return uc.smsService.SendSMS(&sms.SMSMessage{
+		To:   phone,
+		Body: fmt.Sprintf("Ваш код для смены номера телефона: %s", code),
+	})
+}
+
+// ConfirmPhoneChange проверяет код, отправленный на новый телефон, и меняет номер.
+// Старый номер получает уведомление о смене.
+func (uc *AuthUseCase) ConfirmPhoneChange(ctx context.Context, userID uint, code string) (*domain.User, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("code is required")
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.PendingPhone == nil {
+		return nil, errors.New("no phone change requested")
+	}
+
+	valid, err := uc.otpService.ValidateOTP(ctx, userOTPKey(user), otp.OTPTypeChangePhone, code)
+	if err != nil || !valid {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	// Номер мог занять другой пользователь, пока ждали подтверждения
+	newPhone := *user.PendingPhone
+	taken, err := uc.userRepo.IsPhoneTaken(newPhone)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, domain.ErrPhoneTaken
+	}
+
+	oldPhone := user.Phone
+	now := time.Now()
+	user.Phone = newPhone
+	user.PhoneVerifiedAt = &now
+	user.PendingPhone = nil
+	if err := uc.userRepo.UpdateUserPhone(user.ID, newPhone, now); err != nil {
+		return nil, err
+	}
+
+	err = uc.smsService.SendSMS(&sms.SMSMessage{
+		To:   oldPhone,
+		Body: "Номер телефона вашего аккаунта был изменен. Если это были не вы, обратитесь в поддержку.",
+	})
+	if err != nil {
+		log.Printf("[AUTH] Failed to notify user %d about phone change: %v", user.ID, err)
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// DeleteAccount удаляет аккаунт по запросу пользователя после проверки пароля.
+// Аккаунт удаляется мягко и сразу перестает быть доступен, все сессии завершаются;
+// персональные данные стираются фоновой задачей после AccountDeletionGracePeriod.
+func (uc *AuthUseCase) DeleteAccount(ctx context.Context, userID uint, password string) (*AccountDeletion, error) {
+	if strings.TrimSpace(password) == "" {
+		return nil, errors.New("password is required")
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, errors.New("password is incorrect")
+	}
+
+	if err := uc.revokeAllSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.userRepo.DeleteUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] User %d deleted their account", user.ID)
+
+	now := time.Now()
+	return &AccountDeletion{
+		DeletedAt: now,
+		PurgeAt:   now.Add(uc.config.AccountDeletionGracePeriod),
+	}, nil
+}
+
+// PurgeDeletedUsers обезличивает аккаунты, удаленные раньше AccountDeletionGracePeriod.
+// Возвращает число обработанных аккаунтов.
+func (uc *AuthUseCase) PurgeDeletedUsers() (int, error) {
+	before := time.Now().Add(-uc.config.AccountDeletionGracePeriod)
+	purged := 0
+
+	for {
+		users, err := uc.userRepo.GetUsersDeletedBefore(before, deletedUserPurgeBatch)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, user := range users {
+			if err := uc.userRepo.AnonymizeUser(user.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(users) < deletedUserPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// StartDeletedUserPurge запускает периодическое обезличивание удаленных аккаунтов
+func (uc *AuthUseCase) StartDeletedUserPurge() {
+	go func() {
+		ticker := time.NewTicker(deletedUserPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := uc.PurgeDeletedUsers()
+			if err != nil {
+				log.Printf("[AUTH] Failed to purge deleted users: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("[AUTH] Anonymized %d deleted users", purged)
+			}
+		}
+	}()
+}
+
+// validateAvatarURL проверяет, что аватар — абсолютная http(s) ссылка
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+	if len(avatarURL) > maxAvatarURLLength {
+		return fmt.Errorf("avatar URL must be at most %d characters long", maxAvatarURLLength)
+	}
+
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("invalid avatar URL")
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"nodabackend/pkg/otp"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// smsCodeRegex выделяет цифровой код из текста SMS
+var smsCodeRegex = regexp.MustCompile(`\d{6}`)
+
+// newProfileTestUseCase создает usecase с OTP в miniredis и SMS в памяти
+func newProfileTestUseCase(t *testing.T) (*AuthUseCase, *fakeUserRepository, *fakeSMSService, *domain.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginTestPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Password: string(hash)}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	smsService := &fakeSMSService{}
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		RevocationStore:  repository.NewTokenRevocationStore(client),
+		JWTHelper:        testJWTHelper,
+		OTPService:       otp.NewOTPService(client, otp.DefaultConfig()),
+		SMSService:       smsService,
+	}, DefaultConfig())
+
+	return uc, users, smsService, user
+}
+
+func TestProfile_UpdateValidatesFields(t *testing.T) {
+	uc, _, _, user := newProfileTestUseCase(t)
+
+	str := func(value string) *string { return &value }
+
+	invalid := []ProfileUpdate{
+		{Name: str("  ")},
+		{AvatarURL: str("javascript:alert(1)")},
+		{Locale: str("not a locale")},
+		{Timezone: str("Mars/Olympus")},
+		{Timezone: str("Local")},
+	}
+	for _, update := range invalid {
+		if _, err := uc.UpdateProfile(user.ID, update); err == nil {
+			t.Errorf("update %+v must be rejected", update)
+		}
+	}
+
+	updated, err := uc.UpdateProfile(user.ID, ProfileUpdate{
+		AvatarURL: str("https://cdn.example.com/a.png"),
+		Locale:    str("ru-RU"),
+		Timezone:  str("Asia/Almaty"),
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if updated.Name != "Test User" || updated.Locale != "ru-RU" || updated.Timezone != "Asia/Almaty" {
+		t.Fatalf("unexpected profile: %+v", updated)
+	}
+}
+
+func TestProfile_PhoneChangeRequiresCodeFromNewPhone(t *testing.T) {
+	uc, users, smsService, user := newProfileTestUseCase(t)
+	ctx := context.Background()
+	const newPhone = "+77009876543"
+
+	taken := &domain.User{Phone: "+77000000001", Name: "Other"}
+	users.CreateUser(taken)
+	if err := uc.RequestPhoneChange(ctx, user.ID, taken.Phone); err == nil {
+		t.Fatal("phone of another user must be rejected")
+	}
+
+	if err := uc.RequestPhoneChange(ctx, user.ID, newPhone); err != nil {
+		t.Fatalf("RequestPhoneChange: %v", err)
+	}
+	messages := smsService.sent()
+	if len(messages) != 1 || messages[0].To != newPhone {
+		t.Fatalf("code must be sent to the new phone, got %+v", messages)
+	}
+
+	// До подтверждения вход по-прежнему по старому номеру
+	if stored, _ := users.GetUserByID(user.ID); stored.Phone != loginTestPhone {
+		t.Fatalf("phone changed before confirmation: %s", stored.Phone)
+	}
+
+	code := smsCodeRegex.FindString(messages[0].Body)
+	updated, err := uc.ConfirmPhoneChange(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmPhoneChange: %v", err)
+	}
+	if updated.Phone != newPhone || updated.PendingPhone != nil || !updated.IsPhoneVerified() {
+		t.Fatalf("unexpected user after phone change: %+v", updated)
+	}
+
+	// Старый номер получает уведомление
+	if messages := smsService.sent(); messages[len(messages)-1].To != loginTestPhone {
+		t.Fatal("old phone must be notified about the change")
+	}
+
+	if _, err := uc.ConfirmPhoneChange(ctx, user.ID, code); err == nil {
+		t.Fatal("code must not be reusable")
+	}
+}
+
+func TestProfile_DeleteAccountAndPurge(t *testing.T) {
+	uc, users, _, user := newProfileTestUseCase(t)
+	ctx := context.Background()
+
+	if _, err := uc.DeleteAccount(ctx, user.ID, "wrong-password"); err == nil {
+		t.Fatal("wrong password must be rejected")
+	}
+
+	deletion, err := uc.DeleteAccount(ctx, user.ID, loginTestPassword)
+	if err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if deletion.PurgeAt.Sub(deletion.DeletedAt) != uc.config.AccountDeletionGracePeriod {
+		t.Fatalf("unexpected purge time: %+v", deletion)
+	}
+
+	// В течение периода ожидания данные сохраняются
+	if purged, err := uc.PurgeDeletedUsers(); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedUsers during grace period = %d, %v", purged, err)
+	}
+
+	uc.config.AccountDeletionGracePeriod = -time.Second
+	if purged, err := uc.PurgeDeletedUsers(); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedUsers = %d, %v, want 1", purged, err)
+	}
+
+	anonymized, err := users.GetUserByIDWithDeleted(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByIDWithDeleted: %v", err)
+	}
+	if anonymized.Phone == loginTestPhone || anonymized.Name != "" || anonymized.Password != "" || anonymized.AnonymizedAt == nil {
+		t.Fatalf("personal data must be erased: %+v", anonymized)
+	}
+}
+
+func TestProfile_PhoneOfDeletedUserStaysTaken(t *testing.T) {
+	uc, users, smsService, user := newProfileTestUseCase(t)
+	ctx := context.Background()
+
+	deleted := &domain.User{Phone: "+77000000002", Name: "Deleted"}
+	users.CreateUser(deleted)
+	users.DeleteUser(deleted.ID)
+
+	if err := uc.RequestPhoneChange(ctx, user.ID, deleted.Phone); !errors.Is(err, domain.ErrPhoneTaken) {
+		t.Fatalf("phone of a deleted user must stay taken until purge, got %v", err)
+	}
+	if len(smsService.sent()) != 0 {
+		t.Fatal("code must not be sent to a taken phone")
+	}
+}
+
+// staleUserRepository отдает запись, прочитанную до конкурентного изменения
+type staleUserRepository struct {
+	*fakeUserRepository
+	stale *domain.User
+}
+
+func (r *staleUserRepository) GetUserByID(id uint) (*domain.User, error) {
+	copied := *r.stale
+	return &copied, nil
+}
+
+func TestProfile_UpdateKeepsConcurrentAdminChanges(t *testing.T) {
+	uc, users, _, user := newProfileTestUseCase(t)
+
+	stale, err := users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+
+	// Администратор блокирует пользователя, пока запрос на правку профиля держит прочитанную ранее запись
+	now := time.Now()
+	if err := users.UpdateUserBan(user.ID, &now, "spam"); err != nil {
+		t.Fatalf("UpdateUserBan: %v", err)
+	}
+	if err := users.RequirePasswordReset(user.ID); err != nil {
+		t.Fatalf("RequirePasswordReset: %v", err)
+	}
+
+	uc.userRepo = &staleUserRepository{fakeUserRepository: users, stale: stale}
+	name := "Renamed"
+	if _, err := uc.UpdateProfile(user.ID, ProfileUpdate{Name: &name}); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+
+	stored, _ := users.GetUserByID(user.ID)
+	if stored.Name != name {
+		t.Fatalf("profile must be updated, got name %q", stored.Name)
+	}
+	if !stored.IsBanned() || !stored.PasswordResetRequired {
+		t.Fatal("profile update must not undo a concurrent ban or password reset requirement")
+	}
+}
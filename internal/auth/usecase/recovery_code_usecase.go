@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/otp"
+)
+
+// recoveryCodeCount сколько кодов восстановления выдается за раз
+const recoveryCodeCount = 10
+
+// recoveryCodeFormat формат кода восстановления: 10 символов без похожих букв, например "7K3QX-9MD2P"
+var recoveryCodeFormat = otp.CodeFormat{Length: 10, Charset: otp.CharsetUnambiguous, GroupSize: 5}
+
+// Profile пользователь с дополнительной информацией для /auth/me
+type Profile struct {
+	*domain.User
+	RecoveryCodesRemaining *int64 `json:"recovery_codes_remaining,omitempty"` // только при включенной 2FA
+}
+
+// GetProfile возвращает пользователя и число оставшихся кодов восстановления
+func (uc *AuthUseCase) GetProfile(userID uint) (*Profile, error) {
+	user, err := uc.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{User: user}
+	if user.IsTOTPEnabled() {
+		remaining, err := uc.recoveryCodeRepo.CountRemainingRecoveryCodes(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		profile.RecoveryCodesRemaining = &remaining
+	}
+
+	return profile, nil
+}
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления; старые перестают действовать
+func (uc *AuthUseCase) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !user.IsTOTPEnabled() {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	return uc.generateRecoveryCodes(user.ID)
+}
+
+// generateRecoveryCodes генерирует и сохраняет коды восстановления.
+// Коды возвращаются в открытом виде только один раз — в базе хранятся их хеши.
+func (uc *AuthUseCase) generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*domain.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := recoveryCodeFormat.Generate()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, recoveryCodeFormat.Format(code))
+		records = append(records, &domain.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+
+	if err := uc.recoveryCodeRepo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode проверяет и гасит код восстановления
+func (uc *AuthUseCase) useRecoveryCode(userID uint, code string) error {
+	normalized := recoveryCodeFormat.Normalize(code)
+	if len(normalized) != recoveryCodeFormat.Length {
+		return errInvalidRecoveryCode
+	}
+
+	used, err := uc.recoveryCodeRepo.UseRecoveryCode(userID, hashRecoveryCode(normalized))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errInvalidRecoveryCode
+	}
+
+	return nil
+}
+
+// hashRecoveryCode хеширует нормализованный код восстановления.
+// Код случайный (50 бит), поэтому, как и для refresh токенов, достаточно SHA-256.
+func hashRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
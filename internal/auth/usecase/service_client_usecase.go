@@ -0,0 +1,324 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/jwthelper"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	maxServiceClientNameLength  = 100
+	maxServiceClientScopeLength = 512
+)
+
+var (
+	// ErrInvalidClient неизвестный или отозванный клиент либо неверный секрет
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidScope запрошен scope, не разрешенный клиенту
+	ErrInvalidScope = errors.New("requested scope is not allowed for this client")
+	// ErrUnauthorizedClient клиенту не разрешена операция (например, проверка токенов)
+	ErrUnauthorizedClient = errors.New("client is not allowed to perform this operation")
+	// ErrServiceClientNotFound клиент не найден или уже отозван
+	ErrServiceClientNotFound = errors.New("service client not found")
+)
+
+// scopeTokenRegex допустимые символы scope (RFC 6749, раздел 3.3)
+var scopeTokenRegex = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
+// ServiceClientCredentials новый клиент вместе с секретом. Секрет показывается только один раз.
+type ServiceClientCredentials struct {
+	Client       *domain.ServiceClient `json:"client"`
+	ClientSecret string                `json:"client_secret"`
+}
+
+// ServiceTokenResponse ответ client credentials grant (RFC 6749, раздел 5.1)
+type ServiceTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse ответ проверки токена (RFC 7662, раздел 2.2).
+// Для недействительного токена заполняется только Active=false.
+type IntrospectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	// Расширения: тип субъекта (user, service), роль пользователя и его сессия
+	PrincipalType string `json:"principal_type,omitempty"`
+	Role          string `json:"role,omitempty"`
+	SessionID     string `json:"sid,omitempty"`
+}
+
+// CreateServiceClient регистрирует сервисный клиент с набором scopes (для администратора)
+func (uc *AuthUseCase) CreateServiceClient(name string, scopes []string) (*ServiceClientCredentials, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(name) > maxServiceClientNameLength {
+		return nil, errors.New("name is too long")
+	}
+
+	scope, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, err := randomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &domain.ServiceClient{
+		ID:         "svc_" + clientID,
+		Name:       name,
+		SecretHash: hashClientSecret(secret),
+		Scope:      scope,
+	}
+	if err := uc.serviceClientRepo.CreateServiceClient(client); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[AUTH] Service client %s (%s) registered with scope %q", client.ID, client.Name, client.Scope)
+	return &ServiceClientCredentials{Client: client, ClientSecret: secret}, nil
+}
+
+// ListServiceClients возвращает зарегистрированных клиентов (для администратора)
+func (uc *AuthUseCase) ListServiceClients() ([]*domain.ServiceClient, error) {
+	return uc.serviceClientRepo.ListServiceClients()
+}
+
+// RevokeServiceClient отзывает клиента. Его токены перестают приниматься сразу,
+// так как AuthMiddleware и проверка токенов сверяются с базой.
+func (uc *AuthUseCase) RevokeServiceClient(clientID string) error {
+	revoked, err := uc.serviceClientRepo.RevokeServiceClient(clientID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrServiceClientNotFound
+	}
+
+	log.Printf("[AUTH] Service client %s revoked", clientID)
+	return nil
+}
+
+// IssueServiceToken выдает access токен по client credentials grant.
+// Пустой scope означает все scopes, разрешенные клиенту.
+func (uc *AuthUseCase) IssueServiceToken(clientID, clientSecret, scope string) (*ServiceTokenResponse, error) {
+	client, err := uc.authenticateServiceClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		requested = client.Scopes()
+	}
+	for _, s := range requested {
+		if !client.HasScope(s) {
+			return nil, ErrInvalidScope
+		}
+	}
+	scope = strings.Join(requested, " ")
+
+	token, err := uc.jwtHelper.GenerateServiceToken(client.ID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(uc.jwtHelper.AccessTTL().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// IntrospectToken проверяет токен по запросу доверенного клиента со scope introspect.
+// Access токены — JWT, refresh токены — непрозрачные строки, поэтому подсказка
+// token_type_hint не нужна: сначала проверяется JWT, затем refresh токен по хешу.
+func (uc *AuthUseCase) IntrospectToken(ctx context.Context, clientID, clientSecret, token string) (*IntrospectionResponse, error) {
+	client, err := uc.authenticateServiceClient(clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.HasScope(domain.ScopeIntrospect) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, errors.New("token is required")
+	}
+
+	if claims, err := uc.jwtHelper.ValidateToken(token); err == nil {
+		return uc.introspectAccessToken(ctx, claims)
+	}
+	return uc.introspectRefreshToken(token)
+}
+
+// introspectAccessToken проверяет, что access токен не отозван и его владелец еще активен
+func (uc *AuthUseCase) introspectAccessToken(ctx context.Context, claims *jwthelper.Claims) (*IntrospectionResponse, error) {
+	inactive := &IntrospectionResponse{Active: false}
+
+	revoked, err := uc.revocationStore.IsTokenRevoked(ctx, claims.ID, claims.SessionID, claims.UserID, claims.IssuedAtTime())
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactive, nil
+	}
+
+	response := &IntrospectionResponse{
+		Active:    true,
+		TokenType: "Bearer",
+		Exp:       numericDate(claims.ExpiresAt),
+		Iat:       numericDate(claims.IssuedAt),
+		Nbf:       numericDate(claims.NotBefore),
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}
+
+	if claims.IsService() {
+		client, err := uc.serviceClientRepo.GetServiceClient(claims.ClientID)
+		if err != nil || client.IsRevoked() {
+			return inactive, nil
+		}
+		response.PrincipalType = domain.PrincipalService
+		response.ClientID = claims.ClientID
+		response.Scope = claims.Scope
+		response.Sub = claims.ClientID
+		return response, nil
+	}
+
+	user, err := uc.userRepo.GetUserByID(claims.UserID)
+	if err != nil || user.IsBanned() {
+		return inactive, nil
+	}
+	response.PrincipalType = domain.PrincipalUser
+	response.Sub = strconv.FormatUint(uint64(claims.UserID), 10)
+	response.Role = claims.Role
+	response.SessionID = claims.SessionID
+	return response, nil
+}
+
+// introspectRefreshToken проверяет непрозрачный refresh токен по хешу в базе
+func (uc *AuthUseCase) introspectRefreshToken(token string) (*IntrospectionResponse, error) {
+	inactive := &IntrospectionResponse{Active: false}
+
+	stored, err := uc.refreshTokenRepo.GetRefreshTokenByHash(jwthelper.HashRefreshToken(token))
+	if err != nil || stored.RevokedAt != nil || stored.RotatedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return inactive, nil
+	}
+
+	user, err := uc.userRepo.GetUserByID(stored.UserID)
+	if err != nil || user.IsBanned() {
+		return inactive, nil
+	}
+
+	return &IntrospectionResponse{
+		Active:        true,
+		TokenType:     "refresh_token",
+		Exp:           stored.ExpiresAt.Unix(),
+		Iat:           stored.CreatedAt.Unix(),
+		Sub:           strconv.FormatUint(uint64(stored.UserID), 10),
+		PrincipalType: domain.PrincipalUser,
+		Role:          string(user.Role),
+		SessionID:     stored.FamilyID,
+	}, nil
+}
+
+// authenticateServiceClient проверяет ID и секрет клиента.
+// Все ошибки сводятся к ErrInvalidClient, чтобы не раскрывать, какие клиенты существуют.
+func (uc *AuthUseCase) authenticateServiceClient(clientID, clientSecret string) (*domain.ServiceClient, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := uc.serviceClientRepo.GetServiceClient(clientID)
+	if err != nil || client.IsRevoked() {
+		return nil, ErrInvalidClient
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashClientSecret(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// normalizeScopes проверяет scopes и объединяет их через пробел без повторов
+func normalizeScopes(scopes []string) (string, error) {
+	seen := make(map[string]bool, len(scopes))
+	var result []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !scopeTokenRegex.MatchString(scope) {
+			return "", errors.New("invalid scope: " + scope)
+		}
+		seen[scope] = true
+		result = append(result, scope)
+	}
+
+	scope := strings.Join(result, " ")
+	if len(scope) > maxServiceClientScopeLength {
+		return "", errors.New("too many scopes")
+	}
+	return scope, nil
+}
+
+// randomToken генерирует случайную строку из size байт в base64url
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashClientSecret хеширует секрет клиента.
+// Секрет случайный (256 бит), поэтому, как и для refresh токенов, достаточно SHA-256.
+func hashClientSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// numericDate переводит время из claims в секунды Unix
+func numericDate(date *jwt.NumericDate) int64 {
+	if date == nil {
+		return 0
+	}
+	return date.Unix()
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newServiceClientTestUseCase создает usecase с пользователем, сервисными клиентами и denylist в miniredis
+func newServiceClientTestUseCase(t *testing.T) (*AuthUseCase, *domain.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginTestPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	users := newFakeUserRepository()
+	user := &domain.User{Phone: loginTestPhone, Name: "Test User", Password: string(hash), Role: domain.UserRole}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:          users,
+		RefreshTokenRepo:  &fakeRefreshTokenRepository{},
+		SessionRepo:       newFakeSessionRepository(),
+		RevocationStore:   repository.NewTokenRevocationStore(client),
+		LoginAttempts:     repository.NewLoginAttemptStore(client),
+		ServiceClientRepo: newFakeServiceClientRepository(),
+		JWTHelper:         testJWTHelper,
+	}, DefaultConfig())
+
+	return uc, user
+}
+
+func TestServiceClient_ClientCredentialsGrant(t *testing.T) {
+	uc, _ := newServiceClientTestUseCase(t)
+
+	credentials, err := uc.CreateServiceClient("reports worker", []string{"reports:read", "reports:write", "reports:read"})
+	if err != nil {
+		t.Fatalf("CreateServiceClient: %v", err)
+	}
+	client := credentials.Client
+	if client.Scope != "reports:read reports:write" {
+		t.Fatalf("unexpected scope %q", client.Scope)
+	}
+	if client.SecretHash == credentials.ClientSecret {
+		t.Fatal("secret must be stored hashed")
+	}
+
+	if _, err := uc.IssueServiceToken(client.ID, "wrong-secret", ""); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("expected invalid client, got %v", err)
+	}
+	if _, err := uc.IssueServiceToken(client.ID, credentials.ClientSecret, "users:write"); !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("expected invalid scope, got %v", err)
+	}
+
+	token, err := uc.IssueServiceToken(client.ID, credentials.ClientSecret, "reports:read")
+	if err != nil {
+		t.Fatalf("IssueServiceToken: %v", err)
+	}
+	claims, err := testJWTHelper.ValidateToken(token.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if !claims.IsService() || claims.ClientID != client.ID || claims.Scope != "reports:read" || claims.UserID != 0 {
+		t.Fatalf("unexpected service claims: %+v", claims)
+	}
+
+	// Отозванный клиент больше не получает токены
+	if err := uc.RevokeServiceClient(client.ID); err != nil {
+		t.Fatalf("RevokeServiceClient: %v", err)
+	}
+	if _, err := uc.IssueServiceToken(client.ID, credentials.ClientSecret, ""); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("expected invalid client after revoke, got %v", err)
+	}
+}
+
+func TestServiceClient_Introspection(t *testing.T) {
+	uc, user := newServiceClientTestUseCase(t)
+	ctx := context.Background()
+
+	introspector, err := uc.CreateServiceClient("gateway", []string{domain.ScopeIntrospect})
+	if err != nil {
+		t.Fatalf("CreateServiceClient: %v", err)
+	}
+	worker, err := uc.CreateServiceClient("worker", []string{"reports:read"})
+	if err != nil {
+		t.Fatalf("CreateServiceClient: %v", err)
+	}
+	introspect := func(token string) *IntrospectionResponse {
+		t.Helper()
+		response, err := uc.IntrospectToken(ctx, introspector.Client.ID, introspector.ClientSecret, token)
+		if err != nil {
+			t.Fatalf("IntrospectToken: %v", err)
+		}
+		return response
+	}
+
+	// Без scope introspect проверять чужие токены нельзя
+	if _, err := uc.IntrospectToken(ctx, worker.Client.ID, worker.ClientSecret, "token"); !errors.Is(err, ErrUnauthorizedClient) {
+		t.Fatalf("expected unauthorized client, got %v", err)
+	}
+
+	tokens, err := uc.AuthenticateUser(ctx, loginTestPhone, loginTestPassword, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+
+	access := introspect(tokens.Token)
+	if !access.Active || access.PrincipalType != domain.PrincipalUser || access.Sub != strconv.FormatUint(uint64(user.ID), 10) {
+		t.Fatalf("unexpected access token introspection: %+v", access)
+	}
+	if refresh := introspect(tokens.RefreshToken); !refresh.Active || refresh.TokenType != "refresh_token" {
+		t.Fatalf("unexpected refresh token introspection: %+v", refresh)
+	}
+	if garbage := introspect("not-a-token"); garbage.Active {
+		t.Fatal("unknown token must be inactive")
+	}
+
+	// Токен сервиса активен, пока клиент не отозван
+	serviceToken, err := uc.IssueServiceToken(worker.Client.ID, worker.ClientSecret, "")
+	if err != nil {
+		t.Fatalf("IssueServiceToken: %v", err)
+	}
+	service := introspect(serviceToken.AccessToken)
+	if !service.Active || service.PrincipalType != domain.PrincipalService || service.ClientID != worker.Client.ID || service.Scope != "reports:read" {
+		t.Fatalf("unexpected service token introspection: %+v", service)
+	}
+	if err := uc.RevokeServiceClient(worker.Client.ID); err != nil {
+		t.Fatalf("RevokeServiceClient: %v", err)
+	}
+	if introspect(serviceToken.AccessToken).Active {
+		t.Fatal("token of a revoked client must be inactive")
+	}
+
+	// После выхода access токен неактивен
+	claims, err := testJWTHelper.ValidateToken(tokens.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if err := uc.Logout(ctx, claims, ""); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if introspect(tokens.Token).Active {
+		t.Fatal("revoked access token must be inactive")
+	}
+}
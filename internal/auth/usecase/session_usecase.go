@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"strings"
+	"unicode/utf8"
+)
+
+// GetSessions возвращает активные сессии пользователя; currentSessionID помечается как текущая
+func (uc *AuthUseCase) GetSessions(userID uint, currentSessionID string) ([]*domain.Session, error) {
+	sessions, err := uc.sessionRepo.GetActiveSessionsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя на другом устройстве:
+// отзывает ее refresh токены и уже выданные access токены
+func (uc *AuthUseCase) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return errors.New("session ID is required")
+	}
+
+	revoked, err := uc.sessionRepo.RevokeSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("session not found")
+	}
+
+	return uc.revokeSessionTokens(ctx, sessionID)
+}
+
+// revokeSession завершает сессию при выходе или обнаружении повторного использования refresh токена
+func (uc *AuthUseCase) revokeSession(ctx context.Context, userID uint, sessionID string) error {
+	if _, err := uc.sessionRepo.RevokeSession(userID, sessionID); err != nil {
+		return err
+	}
+	return uc.revokeSessionTokens(ctx, sessionID)
+}
+
+// revokeSessionTokens отзывает refresh токены сессии и добавляет ее в denylist access токенов
+func (uc *AuthUseCase) revokeSessionTokens(ctx context.Context, sessionID string) error {
+	if err := uc.refreshTokenRepo.RevokeRefreshTokenFamily(sessionID); err != nil {
+		return err
+	}
+	return uc.revocationStore.RevokeSession(ctx, sessionID, uc.accessRevocationTTL())
+}
+
+// truncate обрезает строку до max байт, не разрывая UTF-8 символы
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
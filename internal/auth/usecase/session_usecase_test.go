@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"nodabackend/pkg/jwthelper"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newSessionTestUseCase создает usecase с сессиями в памяти и denylist в miniredis
+func newSessionTestUseCase(t *testing.T) (*AuthUseCase, *repository.TokenRevocationStore, *domain.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	users := newFakeUserRepository()
+	revocationStore := repository.NewTokenRevocationStore(client)
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		RevocationStore:  revocationStore,
+		JWTHelper:        testJWTHelper,
+	}, DefaultConfig())
+
+	user := &domain.User{Phone: "+77001234567", Name: "Test User"}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return uc, revocationStore, user
+}
+
+// loginFrom выдает токены так, как если бы вход был выполнен с устройства client
+func loginFrom(t *testing.T, uc *AuthUseCase, user *domain.User, client ClientInfo) *jwthelper.Claims {
+	t.Helper()
+
+	response, err := uc.issueTokens(WithClientInfo(context.Background(), client), user, "")
+	if err != nil {
+		t.Fatalf("issueTokens: %v", err)
+	}
+
+	claims, err := testJWTHelper.ValidateToken(response.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.SessionID == "" {
+		t.Fatal("access token has no session ID")
+	}
+	return claims
+}
+
+func TestSessions_ListMarksCurrent(t *testing.T) {
+	uc, _, user := newSessionTestUseCase(t)
+
+	phone := loginFrom(t, uc, user, ClientInfo{IP: "10.0.0.1", UserAgent: "NodaApp/1.0 (iOS)", DeviceName: "iPhone"})
+	loginFrom(t, uc, user, ClientInfo{IP: "10.0.0.2", UserAgent: "Mozilla/5.0"})
+
+	sessions, err := uc.GetSessions(user.ID, phone.SessionID)
+	if err != nil {
+		t.Fatalf("GetSessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+
+	for _, session := range sessions {
+		if session.ID == phone.SessionID {
+			if !session.Current || session.DeviceName != "iPhone" || session.IP != "10.0.0.1" {
+				t.Fatalf("unexpected current session: %+v", session)
+			}
+		} else if session.Current {
+			t.Fatalf("session %s must not be current", session.ID)
+		}
+	}
+}
+
+func TestSessions_RevokeRejectsAccessTokens(t *testing.T) {
+	uc, revocationStore, user := newSessionTestUseCase(t)
+	ctx := context.Background()
+
+	current := loginFrom(t, uc, user, ClientInfo{IP: "10.0.0.1"})
+	other := loginFrom(t, uc, user, ClientInfo{IP: "10.0.0.2"})
+
+	if err := uc.RevokeSession(ctx, user.ID, other.SessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	revoked, err := revocationStore.IsTokenRevoked(ctx, other.ID, other.SessionID, other.UserID, other.IssuedAt.Time)
+	if err != nil || !revoked {
+		t.Fatalf("token of revoked session is still valid (revoked=%v, err=%v)", revoked, err)
+	}
+
+	revoked, err = revocationStore.IsTokenRevoked(ctx, current.ID, current.SessionID, current.UserID, current.IssuedAt.Time)
+	if err != nil || revoked {
+		t.Fatalf("token of another session must stay valid (revoked=%v, err=%v)", revoked, err)
+	}
+
+	sessions, _ := uc.GetSessions(user.ID, current.SessionID)
+	if len(sessions) != 1 || sessions[0].ID != current.SessionID {
+		t.Fatalf("expected only the current session to remain, got %d", len(sessions))
+	}
+
+	if err := uc.RevokeSession(ctx, user.ID, other.SessionID); err == nil {
+		t.Fatal("expected error for already revoked session")
+	}
+}
+
+func TestSessions_CannotRevokeForeignSession(t *testing.T) {
+	uc, _, user := newSessionTestUseCase(t)
+
+	claims := loginFrom(t, uc, user, ClientInfo{IP: "10.0.0.1"})
+	if err := uc.RevokeSession(context.Background(), user.ID+1, claims.SessionID); err == nil {
+		t.Fatal("expected error when revoking another user's session")
+	}
+}
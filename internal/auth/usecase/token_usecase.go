@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/pkg/jwthelper"
+	"strings"
+	"time"
+)
+
+// RefreshTokens обменивает refresh токен на новую пару токенов (ротация).
+// Повторное предъявление уже использованного токена считается кражей:
+// все токены семейства отзываются, и пользователю придется войти заново.
+func (uc *AuthUseCase) RefreshTokens(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	if strings.TrimSpace(refreshToken) == "" {
+		return nil, errors.New("refresh token is required")
+	}
+
+	stored, err := uc.refreshTokenRepo.GetRefreshTokenByHash(jwthelper.HashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	if stored.RotatedAt != nil {
+		// Токен уже был обменян — кто-то использует его повторно
+		if err := uc.revokeSession(ctx, stored.UserID, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	rotated, err := uc.refreshTokenRepo.MarkRefreshTokenRotated(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Параллельный запрос успел обменять этот же токен
+		if err := uc.revokeSession(ctx, stored.UserID, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	user, err := uc.userRepo.GetUserByID(stored.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	return uc.issueTokens(ctx, user, stored.FamilyID)
+}
+
+// Logout отзывает текущий access токен и завершает его сессию.
+// Если передан refresh токен другой сессии пользователя, завершается и она.
+func (uc *AuthUseCase) Logout(ctx context.Context, claims *jwthelper.Claims, refreshToken string) error {
+	if err := uc.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		if err := uc.revokeSession(ctx, claims.UserID, claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(refreshToken) == "" {
+		return nil
+	}
+
+	stored, err := uc.refreshTokenRepo.GetRefreshTokenByHash(jwthelper.HashRefreshToken(refreshToken))
+	if err != nil || stored.UserID != claims.UserID || stored.FamilyID == claims.SessionID {
+		// Access токен уже отозван; чужой или неизвестный refresh токен просто игнорируем
+		return nil
+	}
+
+	return uc.revokeSession(ctx, stored.UserID, stored.FamilyID)
+}
+
+// LogoutAll завершает все сессии пользователя на всех устройствах
+func (uc *AuthUseCase) LogoutAll(ctx context.Context, claims *jwthelper.Claims) error {
+	if err := uc.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	return uc.revokeAllSessions(ctx, claims.UserID)
+}
+
+// revokeAllSessions отзывает все сессии и refresh токены пользователя и делает
+// недействительными все ранее выпущенные access токены
+func (uc *AuthUseCase) revokeAllSessions(ctx context.Context, userID uint) error {
+	if err := uc.refreshTokenRepo.RevokeUserRefreshTokens(userID); err != nil {
+		return err
+	}
+	if err := uc.sessionRepo.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	return uc.revocationStore.RevokeUserTokens(ctx, userID, time.Now(), uc.accessRevocationTTL())
+}
+
+// revokeAccessToken добавляет access токен в denylist до истечения его срока действия
+func (uc *AuthUseCase) revokeAccessToken(ctx context.Context, claims *jwthelper.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return errors.New("token cannot be revoked")
+	}
+	return uc.revocationStore.RevokeToken(ctx, claims.ID, uc.tokenRevocationTTL(claims))
+}
+
+// accessRevocationTTL время хранения отзыва сессии или всех токенов пользователя: срок жизни
+// access токена плюс leeway, в течение которого парсер еще принимает истекший токен
+func (uc *AuthUseCase) accessRevocationTTL() time.Duration {
+	return uc.jwtHelper.AccessTTL() + uc.jwtHelper.Leeway()
+}
+
+// tokenRevocationTTL время хранения отзыва конкретного токена: до его истечения плюс leeway
+func (uc *AuthUseCase) tokenRevocationTTL(claims *jwthelper.Claims) time.Duration {
+	return time.Until(claims.ExpiresAt.Time) + uc.jwtHelper.Leeway()
+}
+
+// issueTokens выдает пользователю access токен и новый refresh токен.
+// Пустой familyID означает новый логин: создается новое семейство токенов и сессия
+// с данными клиента из контекста. Иначе продлевается существующая сессия.
+// Заблокированному пользователю токены не выдаются ни при входе, ни при обновлении.
+func (uc *AuthUseCase) issueTokens(ctx context.Context, user *domain.User, familyID string) (*AuthResponse, error) {
+	if user.IsBanned() {
+		return nil, ErrUserBanned
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(uc.jwtHelper.RefreshTTL())
+	client := clientInfoFromContext(ctx)
+
+	if familyID == "" {
+		var err error
+		familyID, err = generateFamilyID()
+		if err != nil {
+			return nil, err
+		}
+
+		err = uc.sessionRepo.CreateSession(&domain.Session{
+			ID:         familyID,
+			UserID:     user.ID,
+			DeviceName: truncate(client.DeviceName, 100),
+			UserAgent:  truncate(client.UserAgent, 512),
+			IP:         client.IP,
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if err := uc.sessionRepo.ExtendSession(familyID, client.IP, now, expiresAt); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := uc.jwtHelper.GenerateToken(user.ID, user.Phone, string(user.Role), familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := jwthelper.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.refreshTokenRepo.CreateRefreshToken(&domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: jwthelper.HashRefreshToken(refreshToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Очищаем пароль перед возвратом
+	user.Password = ""
+
+	return &AuthResponse{
+		User:             user,
+		Token:            accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(uc.jwtHelper.AccessTTL().Seconds()),
+		RefreshExpiresIn: int64(uc.jwtHelper.RefreshTTL().Seconds()),
+	}, nil
+}
+
+// generateFamilyID генерирует идентификатор семейства refresh токенов
+func generateFamilyID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
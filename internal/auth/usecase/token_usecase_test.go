@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"nodabackend/pkg/jwthelper"
+	"testing"
+)
+
+// loginForTokens выполняет вход по паролю и возвращает пару токенов
+func loginForTokens(t *testing.T, uc *AuthUseCase) *AuthResponse {
+	t.Helper()
+
+	tokens, err := uc.AuthenticateUser(context.Background(), loginTestPhone, loginTestPassword, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	return tokens
+}
+
+// accessClaims проверяет access токен и возвращает его claims
+func accessClaims(t *testing.T, token string) *jwthelper.Claims {
+	t.Helper()
+
+	claims, err := testJWTHelper.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	return claims
+}
+
+func TestRefresh_RotatesTokens(t *testing.T) {
+	uc, store, _ := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	tokens := loginForTokens(t, uc)
+	refreshed, err := uc.RefreshTokens(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatal("refresh must return a new refresh token")
+	}
+
+	// Новая пара принадлежит той же сессии
+	if accessClaims(t, refreshed.Token).SessionID != accessClaims(t, tokens.Token).SessionID {
+		t.Fatal("rotation must keep the session")
+	}
+	if isAccessTokenRevoked(t, store, refreshed.Token) {
+		t.Fatal("rotated access token must be valid")
+	}
+
+	if _, err := uc.RefreshTokens(ctx, refreshed.RefreshToken); err != nil {
+		t.Fatalf("RefreshTokens with the new token: %v", err)
+	}
+}
+
+func TestRefresh_ReuseRevokesSession(t *testing.T) {
+	uc, store, _ := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	tokens := loginForTokens(t, uc)
+	refreshed, err := uc.RefreshTokens(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+
+	// Повторное предъявление обменянного токена — признак кражи
+	if _, err := uc.RefreshTokens(ctx, tokens.RefreshToken); err == nil {
+		t.Fatal("reused refresh token must be rejected")
+	}
+
+	if _, err := uc.RefreshTokens(ctx, refreshed.RefreshToken); err == nil {
+		t.Fatal("refresh tokens of the session must be revoked after reuse")
+	}
+	if !isAccessTokenRevoked(t, store, refreshed.Token) {
+		t.Fatal("access tokens of the session must be revoked after reuse")
+	}
+
+	// Другие сессии пользователя не затронуты
+	other := loginForTokens(t, uc)
+	if isAccessTokenRevoked(t, store, other.Token) {
+		t.Fatal("new session must not be affected")
+	}
+}
+
+func TestLogout_RevokesTokenAndSession(t *testing.T) {
+	uc, store, _ := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	current := loginForTokens(t, uc)
+	other := loginForTokens(t, uc)
+
+	if err := uc.Logout(ctx, accessClaims(t, current.Token), ""); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	if !isAccessTokenRevoked(t, store, current.Token) {
+		t.Fatal("access token must be revoked after logout")
+	}
+	if _, err := uc.RefreshTokens(ctx, current.RefreshToken); err == nil {
+		t.Fatal("refresh token of the session must be revoked after logout")
+	}
+	if isAccessTokenRevoked(t, store, other.Token) {
+		t.Fatal("other sessions must stay active")
+	}
+}
+
+func TestRevocation_RevokedJTI(t *testing.T) {
+	uc, store, _ := newAdminTestUseCase(t)
+
+	tokens := loginForTokens(t, uc)
+	refreshed, err := uc.RefreshTokens(context.Background(), tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+
+	// Отзыв по jti затрагивает только этот токен, а не всю сессию
+	if err := uc.revokeAccessToken(context.Background(), accessClaims(t, tokens.Token)); err != nil {
+		t.Fatalf("revokeAccessToken: %v", err)
+	}
+	if !isAccessTokenRevoked(t, store, tokens.Token) {
+		t.Fatal("token must be revoked by jti")
+	}
+	if isAccessTokenRevoked(t, store, refreshed.Token) {
+		t.Fatal("other tokens of the session must stay valid")
+	}
+}
+
+func TestLogoutAll_RevokesAllTokensByWatermark(t *testing.T) {
+	uc, store, _ := newAdminTestUseCase(t)
+	ctx := context.Background()
+
+	first := loginForTokens(t, uc)
+	second := loginForTokens(t, uc)
+
+	if err := uc.LogoutAll(ctx, accessClaims(t, first.Token)); err != nil {
+		t.Fatalf("LogoutAll: %v", err)
+	}
+
+	for _, tokens := range []*AuthResponse{first, second} {
+		if !isAccessTokenRevoked(t, store, tokens.Token) {
+			t.Fatal("all access tokens issued before LogoutAll must be revoked")
+		}
+		if _, err := uc.RefreshTokens(ctx, tokens.RefreshToken); err == nil {
+			t.Fatal("all refresh tokens must be revoked")
+		}
+	}
+
+	// Вход после отзыва, даже в ту же секунду, дает действующие токены
+	after := loginForTokens(t, uc)
+	if isAccessTokenRevoked(t, store, after.Token) {
+		t.Fatal("tokens issued after LogoutAll must be valid")
+	}
+}
+
+func TestRevocation_OutlivesLeeway(t *testing.T) {
+	uc, store, user, mr := newRevocationTestUseCase(t)
+	ctx := context.Background()
+	// Токены истекли, но парсер еще принимает их в пределах leeway — отзыв должен действовать
+	insideLeeway := testJWTHelper.AccessTTL() + testJWTHelper.Leeway()/2
+
+	single := loginForTokens(t, uc)
+	session := loginForTokens(t, uc)
+	if err := uc.revokeAccessToken(ctx, accessClaims(t, single.Token)); err != nil {
+		t.Fatalf("revokeAccessToken: %v", err)
+	}
+	if err := uc.revokeSession(ctx, user.ID, accessClaims(t, session.Token).SessionID); err != nil {
+		t.Fatalf("revokeSession: %v", err)
+	}
+
+	mr.FastForward(insideLeeway)
+	if !isAccessTokenRevoked(t, store, single.Token) {
+		t.Error("jti revocation must outlive the token expiry by the leeway")
+	}
+	if !isAccessTokenRevoked(t, store, session.Token) {
+		t.Error("session revocation must outlive the token expiry by the leeway")
+	}
+
+	all := loginForTokens(t, uc)
+	if err := uc.revokeAllSessions(ctx, user.ID); err != nil {
+		t.Fatalf("revokeAllSessions: %v", err)
+	}
+
+	mr.FastForward(insideLeeway)
+	if !isAccessTokenRevoked(t, store, all.Token) {
+		t.Error("user revocation must outlive the token expiry by the leeway")
+	}
+}
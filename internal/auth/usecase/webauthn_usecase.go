@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nodabackend/internal/auth/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// webAuthnSessionTTL сколько живет challenge незавершенной церемонии
+const webAuthnSessionTTL = 5 * time.Minute
+
+// WebAuthnLoginChallenge параметры для navigator.credentials.get()
+type WebAuthnLoginChallenge struct {
+	SessionID string                        `json:"session_id"` // передается обратно в FinishWebAuthnLogin
+	Options   *protocol.CredentialAssertion `json:"options"`
+}
+
+// webAuthnUser адаптер domain.User для библиотеки WebAuthn
+type webAuthnUser struct {
+	user        *domain.User
+	credentials []*domain.WebAuthnCredential
+}
+
+// WebAuthnID user handle — ID пользователя, без персональных данных
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.user.ID), 10))
+}
+
+// WebAuthnName имя аккаунта, которое показывает аутентификатор
+func (u *webAuthnUser) WebAuthnName() string {
+	return accountName(u.user)
+}
+
+// WebAuthnDisplayName отображаемое имя пользователя
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.Name != "" {
+		return u.user.Name
+	}
+	return accountName(u.user)
+}
+
+// WebAuthnCredentials ключи доступа пользователя в формате библиотеки
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		var transports []protocol.AuthenticatorTransport
+		if c.Transports != "" {
+			for _, transport := range strings.Split(c.Transports, ",") {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// credential находит сохраненный ключ по credential ID
+func (u *webAuthnUser) credential(id []byte) *domain.WebAuthnCredential {
+	for _, c := range u.credentials {
+		if bytes.Equal(c.CredentialID, id) {
+			return c
+		}
+	}
+	return nil
+}
+
+// BeginWebAuthnRegistration начинает регистрацию нового ключа доступа для пользователя
+func (uc *AuthUseCase) BeginWebAuthnRegistration(ctx context.Context, userID uint) (*protocol.CredentialCreation, error) {
+	if uc.webAuthn == nil {
+		return nil, errors.New("passkeys are not configured")
+	}
+
+	user, err := uc.loadWebAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	creation, session, err := uc.webAuthn.BeginRegistration(user,
+		// Один аутентификатор нельзя зарегистрировать дважды
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		// Для входа без логина ключ должен быть discoverable
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin passkey registration: %w", err)
+	}
+
+	if err := uc.saveWebAuthnSession(ctx, webAuthnRegistrationKey(userID), session); err != nil {
+		return nil, err
+	}
+
+	return creation, nil
+}
+
+// FinishWebAuthnRegistration проверяет ответ navigator.credentials.create() и сохраняет ключ
+func (uc *AuthUseCase) FinishWebAuthnRegistration(ctx context.Context, userID uint, name string, response []byte) (*domain.WebAuthnCredential, error) {
+	if uc.webAuthn == nil {
+		return nil, errors.New("passkeys are not configured")
+	}
+
+	session, err := uc.popWebAuthnSession(ctx, webAuthnRegistrationKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, errors.New("invalid passkey registration response")
+	}
+
+	user, err := uc.loadWebAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := uc.webAuthn.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, errors.New("passkey registration failed")
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	stored := &domain.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		Transports:      strings.Join(transports, ","),
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	}
+	if err := uc.webAuthnRepo.CreateWebAuthnCredential(stored); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// BeginWebAuthnLogin начинает вход по ключу доступа.
+// Пользователь не указывается: аутентификатор сам предлагает подходящий ключ (discoverable credential).
+func (uc *AuthUseCase) BeginWebAuthnLogin(ctx context.Context) (*WebAuthnLoginChallenge, error) {
+	if uc.webAuthn == nil {
+		return nil, errors.New("passkeys are not configured")
+	}
+
+	// Passkey заменяет пароль, поэтому проверка пользователя (биометрия/PIN) обязательна
+	assertion, session, err := uc.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin passkey login: %w", err)
+	}
+
+	sessionID, err := generateWebAuthnSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.saveWebAuthnSession(ctx, webAuthnLoginKey(sessionID), session); err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnLoginChallenge{
+		SessionID: sessionID,
+		Options:   assertion,
+	}, nil
+}
+
+// FinishWebAuthnLogin проверяет ответ navigator.credentials.get() и выдает токены.
+// Ключ доступа с проверкой пользователя уже является двухфакторным, поэтому TOTP не запрашивается.
+func (uc *AuthUseCase) FinishWebAuthnLogin(ctx context.Context, sessionID string, response []byte) (*AuthResponse, error) {
+	if uc.webAuthn == nil {
+		return nil, errors.New("passkeys are not configured")
+	}
+
+	if strings.TrimSpace(sessionID) == "" {
+		return nil, errors.New("session ID is required")
+	}
+
+	session, err := uc.popWebAuthnSession(ctx, webAuthnLoginKey(sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, errors.New("invalid passkey login response")
+	}
+
+	var user *webAuthnUser
+	credential, err := uc.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := strconv.ParseUint(string(userHandle), 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid user handle")
+		}
+		user, err = uc.loadWebAuthnUser(uint(userID))
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}, *session, parsed)
+	if err != nil {
+		return nil, errors.New("passkey login failed")
+	}
+
+	// Счетчик подписей не вырос — возможно, ключ скопирован
+	if credential.Authenticator.CloneWarning {
+		return nil, errors.New("passkey sign count check failed")
+	}
+
+	stored := user.credential(credential.ID)
+	if stored == nil {
+		return nil, errors.New("passkey login failed")
+	}
+
+	now := time.Now()
+	stored.SignCount = credential.Authenticator.SignCount
+	stored.BackupState = credential.Flags.BackupState
+	stored.LastUsedAt = &now
+	if err := uc.webAuthnRepo.UpdateWebAuthnCredential(stored); err != nil {
+		return nil, err
+	}
+
+	if err := uc.requireVerifiedPhone(ctx, user.user); err != nil {
+		return nil, err
+	}
+
+	return uc.issueTokens(ctx, user.user, "")
+}
+
+// loadWebAuthnUser загружает пользователя вместе с его ключами доступа
+func (uc *AuthUseCase) loadWebAuthnUser(userID uint) (*webAuthnUser, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	credentials, err := uc.webAuthnRepo.GetWebAuthnCredentialsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// saveWebAuthnSession сохраняет challenge церемонии
+func (uc *AuthUseCase) saveWebAuthnSession(ctx context.Context, key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return uc.webAuthnSessions.SaveWebAuthnSession(ctx, key, data, webAuthnSessionTTL)
+}
+
+// popWebAuthnSession забирает challenge церемонии; повторно его использовать нельзя
+func (uc *AuthUseCase) popWebAuthnSession(ctx context.Context, key string) (*webauthn.SessionData, error) {
+	data, err := uc.webAuthnSessions.PopWebAuthnSession(ctx, key)
+	if errors.Is(err, domain.ErrWebAuthnSessionNotFound) {
+		return nil, errors.New("passkey challenge not found or expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// newWebAuthn создает WebAuthn relying party из конфигурации
+func newWebAuthn(config *Config) (*webauthn.WebAuthn, error) {
+	return webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthnRPID,
+		RPDisplayName: config.WebAuthnRPName,
+		RPOrigins:     config.WebAuthnRPOrigins,
+	})
+}
+
+// webAuthnRegistrationKey ключ церемонии регистрации; у пользователя одна активная регистрация
+func webAuthnRegistrationKey(userID uint) string {
+	return fmt.Sprintf("register:%d", userID)
+}
+
+// webAuthnLoginKey ключ церемонии входа
+func webAuthnLoginKey(sessionID string) string {
+	return "login:" + sessionID
+}
+
+// generateWebAuthnSessionID генерирует идентификатор церемонии входа
+func generateWebAuthnSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
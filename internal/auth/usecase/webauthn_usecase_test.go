@@ -0,0 +1,338 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"nodabackend/internal/auth/domain"
+	"nodabackend/internal/auth/repository"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+)
+
+// Флаги authenticator data (WebAuthn §6.1)
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// softAuthenticator программный аутентификатор: создает ES256 ключ
+// и подписывает церемонии так же, как это делает браузер с passkey
+type softAuthenticator struct {
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	return &softAuthenticator{origin: testOrigin, key: key, credentialID: credentialID}
+}
+
+// clientDataJSON формирует CollectedClientData для церемонии
+func (a *softAuthenticator) clientDataJSON(t *testing.T, ceremony string, challenge []byte) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		t.Fatalf("marshal client data: %v", err)
+	}
+	return data
+}
+
+// authenticatorData формирует authenticator data с текущим счетчиком подписей
+func (a *softAuthenticator) authenticatorData(flags byte, attestedCredentialData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attestedCredentialData...)
+}
+
+// create отвечает на navigator.credentials.create() с attestation "none"
+func (a *softAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+
+	userHandle, ok := options.Response.User.ID.(protocol.URLEncodedBase64)
+	if !ok {
+		t.Fatalf("unexpected user ID type %T", options.Response.User.ID)
+	}
+	a.userHandle = userHandle
+
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(flagUserPresent|flagUserVerified|flagAttestedData, attested),
+	})
+	if err != nil {
+		t.Fatalf("marshal attestation object: %v", err)
+	}
+
+	return a.marshalCredential(t, map[string]any{
+		"clientDataJSON":    a.encode(a.clientDataJSON(t, "webauthn.create", options.Response.Challenge)),
+		"attestationObject": a.encode(attestationObject),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get отвечает на navigator.credentials.get(), увеличивая счетчик подписей
+func (a *softAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion) []byte {
+	t.Helper()
+
+	a.signCount++
+	authenticatorData := a.authenticatorData(flagUserPresent|flagUserVerified, nil)
+	clientDataJSON := a.clientDataJSON(t, "webauthn.get", options.Response.Challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+
+	return a.marshalCredential(t, map[string]any{
+		"clientDataJSON":    a.encode(clientDataJSON),
+		"authenticatorData": a.encode(authenticatorData),
+		"signature":         a.encode(signature),
+		"userHandle":        a.encode(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (a *softAuthenticator) marshalCredential(t *testing.T, response map[string]any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"id":       a.encode(a.credentialID),
+		"rawId":    a.encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("marshal credential: %v", err)
+	}
+	return data
+}
+
+// newWebAuthnTestUseCase создает usecase с репозиториями в памяти и challenge в miniredis
+func newWebAuthnTestUseCase(t *testing.T) (*AuthUseCase, *fakeWebAuthnCredentialRepository, *domain.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	users := newFakeUserRepository()
+	credentials := &fakeWebAuthnCredentialRepository{}
+
+	config := DefaultConfig()
+	config.WebAuthnRPID = testRPID
+	config.WebAuthnRPOrigins = []string{testOrigin}
+
+	uc := NewAuthUseCase(Dependencies{
+		UserRepo:         users,
+		RefreshTokenRepo: &fakeRefreshTokenRepository{},
+		SessionRepo:      newFakeSessionRepository(),
+		WebAuthnRepo:     credentials,
+		WebAuthnSessions: repository.NewWebAuthnSessionStore(client),
+		JWTHelper:        testJWTHelper,
+	}, config)
+
+	user := &domain.User{Phone: "+77001234567", Name: "Test User"}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return uc, credentials, user
+}
+
+// registerPasskey проходит церемонию регистрации программным аутентификатором
+func registerPasskey(t *testing.T, uc *AuthUseCase, userID uint) *softAuthenticator {
+	t.Helper()
+	ctx := context.Background()
+
+	options, err := uc.BeginWebAuthnRegistration(ctx, userID)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration: %v", err)
+	}
+
+	authenticator := newSoftAuthenticator(t)
+	if _, err := uc.FinishWebAuthnRegistration(ctx, userID, "Test key", authenticator.create(t, options)); err != nil {
+		t.Fatalf("FinishWebAuthnRegistration: %v", err)
+	}
+
+	return authenticator
+}
+
+// loginWithPasskey проходит церемонию входа программным аутентификатором
+func loginWithPasskey(t *testing.T, uc *AuthUseCase, authenticator *softAuthenticator) (*AuthResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	challenge, err := uc.BeginWebAuthnLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin: %v", err)
+	}
+
+	return uc.FinishWebAuthnLogin(ctx, challenge.SessionID, authenticator.get(t, challenge.Options))
+}
+
+func TestWebAuthn_RegisterAndLogin(t *testing.T) {
+	uc, credentials, user := newWebAuthnTestUseCase(t)
+
+	authenticator := registerPasskey(t, uc, user.ID)
+
+	stored, _ := credentials.GetWebAuthnCredentialsByUserID(user.ID)
+	if len(stored) != 1 || stored[0].Name != "Test key" || stored[0].Transports != "internal" {
+		t.Fatalf("stored credentials = %+v; want one credential named Test key", stored)
+	}
+
+	authResponse, err := loginWithPasskey(t, uc, authenticator)
+	if err != nil {
+		t.Fatalf("FinishWebAuthnLogin: %v", err)
+	}
+	if authResponse.Token == "" || authResponse.RefreshToken == "" {
+		t.Fatal("FinishWebAuthnLogin must issue access and refresh tokens")
+	}
+	if authResponse.User.ID != user.ID {
+		t.Fatalf("User.ID = %d; want %d", authResponse.User.ID, user.ID)
+	}
+
+	stored, _ = credentials.GetWebAuthnCredentialsByUserID(user.ID)
+	if stored[0].SignCount != 1 || stored[0].LastUsedAt == nil {
+		t.Fatalf("SignCount = %d, LastUsedAt = %v; want 1 and set", stored[0].SignCount, stored[0].LastUsedAt)
+	}
+}
+
+func TestWebAuthn_RejectsDuplicateRegistrationChallenge(t *testing.T) {
+	uc, _, user := newWebAuthnTestUseCase(t)
+	ctx := context.Background()
+
+	options, err := uc.BeginWebAuthnRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration: %v", err)
+	}
+
+	authenticator := newSoftAuthenticator(t)
+	response := authenticator.create(t, options)
+	if _, err := uc.FinishWebAuthnRegistration(ctx, user.ID, "", response); err != nil {
+		t.Fatalf("FinishWebAuthnRegistration: %v", err)
+	}
+
+	// Challenge одноразовый
+	if _, err := uc.FinishWebAuthnRegistration(ctx, user.ID, "", response); err == nil {
+		t.Fatal("registration response must not be accepted twice")
+	}
+}
+
+func TestWebAuthn_LoginChallengeIsSingleUse(t *testing.T) {
+	uc, _, user := newWebAuthnTestUseCase(t)
+	ctx := context.Background()
+	authenticator := registerPasskey(t, uc, user.ID)
+
+	challenge, err := uc.BeginWebAuthnLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin: %v", err)
+	}
+
+	response := authenticator.get(t, challenge.Options)
+	if _, err := uc.FinishWebAuthnLogin(ctx, challenge.SessionID, response); err != nil {
+		t.Fatalf("FinishWebAuthnLogin: %v", err)
+	}
+
+	if _, err := uc.FinishWebAuthnLogin(ctx, challenge.SessionID, response); err == nil {
+		t.Fatal("assertion must not be accepted twice")
+	}
+}
+
+func TestWebAuthn_RejectsSignCountRollback(t *testing.T) {
+	uc, _, user := newWebAuthnTestUseCase(t)
+	authenticator := registerPasskey(t, uc, user.ID)
+
+	authenticator.signCount = 10
+	if _, err := loginWithPasskey(t, uc, authenticator); err != nil {
+		t.Fatalf("FinishWebAuthnLogin: %v", err)
+	}
+
+	// Копия ключа со старым счетчиком
+	authenticator.signCount = 3
+	_, err := loginWithPasskey(t, uc, authenticator)
+	if err == nil || err.Error() != "passkey sign count check failed" {
+		t.Fatalf("FinishWebAuthnLogin error = %v; want sign count check failure", err)
+	}
+}
+
+func TestWebAuthn_RejectsForeignOrigin(t *testing.T) {
+	uc, _, user := newWebAuthnTestUseCase(t)
+	authenticator := registerPasskey(t, uc, user.ID)
+
+	authenticator.origin = "https://evil.example.com"
+	if _, err := loginWithPasskey(t, uc, authenticator); err == nil {
+		t.Fatal("assertion from a foreign origin must be rejected")
+	}
+}
+
+func TestWebAuthn_RejectsWrongKey(t *testing.T) {
+	uc, _, user := newWebAuthnTestUseCase(t)
+	authenticator := registerPasskey(t, uc, user.ID)
+
+	// Тот же credential ID, но другой приватный ключ
+	other := newSoftAuthenticator(t)
+	authenticator.key = other.key
+	if _, err := loginWithPasskey(t, uc, authenticator); err == nil {
+		t.Fatal("assertion signed with a different key must be rejected")
+	}
+}
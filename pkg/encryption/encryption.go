@@ -0,0 +1,93 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"nodabackend/pkg/env"
+	"os"
+)
+
+const (
+	// minKeyLength минимальная длина ENCRYPTION_KEY вне режима разработки
+	minKeyLength = 32
+	// defaultDevelopmentKey ключ для локальной разработки (APP_ENV=development)
+	defaultDevelopmentKey = "your-encryption-key"
+)
+
+// Encryptor шифрует секреты, которые хранятся в базе данных (например, TOTP секреты).
+// Используется AES-256-GCM; nonce хранится вместе с шифротекстом.
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor создает Encryptor. Ключ произвольной длины приводится к 256 битам через SHA-256.
+func NewEncryptor(key string) (*Encryptor, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is required")
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Encryptor{aead: aead}, nil
+}
+
+// NewEncryptorFromEnv создает Encryptor с ключом из ENCRYPTION_KEY.
+// Вне режима разработки ключ обязателен и должен быть не короче minKeyLength символов.
+func NewEncryptorFromEnv() (*Encryptor, error) {
+	key := os.Getenv("ENCRYPTION_KEY")
+	if key == "" && env.IsDevelopment() {
+		log.Println("[ENCRYPTION] ENCRYPTION_KEY is not set, using the default development key")
+		key = defaultDevelopmentKey
+	}
+
+	if !env.IsDevelopment() && (len(key) < minKeyLength || key == defaultDevelopmentKey) {
+		return nil, fmt.Errorf("ENCRYPTION_KEY must be set to a secret of at least %d characters outside development mode", minKeyLength)
+	}
+
+	return NewEncryptor(key)
+}
+
+// Encrypt шифрует строку и возвращает base64(nonce || ciphertext)
+func (e *Encryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает строку, полученную из Encrypt
+func (e *Encryptor) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("ciphertext is too short")
+	}
+
+	plaintext, err := e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt secret")
+	}
+	return string(plaintext), nil
+}
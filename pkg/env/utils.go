@@ -1,22 +1,28 @@
-package env
-
-import (
-	"os"
-	"strconv"
-)
-
-func GetEnvOrDefault(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	return fallback
-}
-
-func GetEnvIntOrDefault(key string, fallback int) int {
-	if value := GetEnvOrDefault(key, ""); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return fallback
-}
+package env
+
+import (
+	"os"
+	"strconv"
+)
+
+func GetEnvOrDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func GetEnvIntOrDefault(key string, fallback int) int {
+	if value := GetEnvOrDefault(key, ""); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return fallback
+}
+
+// IsDevelopment проверяет, запущено ли приложение в режиме разработки (APP_ENV=development).
+// Без явного APP_ENV считается, что это продакшен, и небезопасные значения по умолчанию запрещены.
+func IsDevelopment() bool {
+	return GetEnvOrDefault("APP_ENV", "") == "development"
+}
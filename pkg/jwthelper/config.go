@@ -0,0 +1,101 @@
+package jwthelper
+
+import (
+	"errors"
+	"fmt"
+	"nodabackend/pkg/env"
+	"strings"
+	"time"
+)
+
+// Config настройки выпуска и проверки JWT токенов
+type Config struct {
+	// Issuer значение iss; если задано, токены с другим издателем отклоняются
+	Issuer string
+	// Audiences значения aud; если заданы, токен должен быть выпущен хотя бы для одного из них
+	Audiences []string
+	// AllowedAlgorithms алгоритмы подписи, которые принимаются при проверке
+	AllowedAlgorithms []string
+	// Leeway допустимое расхождение часов при проверке exp, nbf и iat
+	Leeway time.Duration
+	// AccessTTL время жизни access токена
+	AccessTTL time.Duration
+	// RefreshTTL время жизни refresh токена и сессии
+	RefreshTTL time.Duration
+}
+
+// DefaultConfig возвращает конфигурацию по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		Issuer:            "nodabackend",
+		Audiences:         []string{"nodabackend-api"},
+		AllowedAlgorithms: []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA, AlgorithmHS256},
+		Leeway:            30 * time.Second,
+		AccessTTL:         15 * time.Minute,
+		RefreshTTL:        720 * time.Hour,
+	}
+}
+
+// NewConfigFromEnv создает конфигурацию из переменных окружения.
+// Списки (JWT_AUDIENCES, JWT_ALLOWED_ALGORITHMS) задаются через запятую.
+func NewConfigFromEnv() *Config {
+	config := DefaultConfig()
+	config.Issuer = env.GetEnvOrDefault("JWT_ISSUER", config.Issuer)
+	if audiences, ok := splitList(env.GetEnvOrDefault("JWT_AUDIENCES", "")); ok {
+		config.Audiences = audiences
+	}
+	if algorithms, ok := splitList(env.GetEnvOrDefault("JWT_ALLOWED_ALGORITHMS", "")); ok {
+		config.AllowedAlgorithms = algorithms
+	}
+	config.Leeway = time.Duration(env.GetEnvIntOrDefault("JWT_LEEWAY_SECONDS", 30)) * time.Second
+	config.AccessTTL = time.Duration(env.GetEnvIntOrDefault("JWT_ACCESS_TTL_MINUTES", 15)) * time.Minute
+	config.RefreshTTL = time.Duration(env.GetEnvIntOrDefault("JWT_REFRESH_TTL_HOURS", 720)) * time.Hour
+	return config
+}
+
+// validate проверяет, что конфигурация совместима с ключом подписи
+func (c *Config) validate(keyring *Keyring) error {
+	if c.AccessTTL <= 0 {
+		return errors.New("access token TTL must be positive")
+	}
+	if c.Leeway < 0 {
+		return errors.New("leeway must not be negative")
+	}
+	for _, algorithm := range c.AllowedAlgorithms {
+		switch algorithm {
+		case AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA, AlgorithmHS256:
+		default:
+			return fmt.Errorf("unsupported algorithm %q", algorithm)
+		}
+	}
+	for _, audience := range c.Audiences {
+		if strings.HasPrefix(audience, purposeAudiencePrefix) {
+			return fmt.Errorf("audience %q is reserved for internal tokens", audience)
+		}
+	}
+	if !c.allows(keyring.SigningKey().Algorithm) {
+		return fmt.Errorf("signing key algorithm %s is not in the allowed algorithms", keyring.SigningKey().Algorithm)
+	}
+	return nil
+}
+
+// allows проверяет, разрешен ли алгоритм
+func (c *Config) allows(algorithm string) bool {
+	for _, allowed := range c.AllowedAlgorithms {
+		if allowed == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList разбирает список через запятую; false — значение не задано
+func splitList(value string) ([]string, bool) {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, len(items) > 0
+}
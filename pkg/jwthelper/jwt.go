@@ -1,83 +1,371 @@
-package jwthelper
-
-import (
-	"errors"
-	"os"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-)
-
-// Claims представляет JWT claims
-type Claims struct {
-	UserID uint   `json:"user_id"`
-	Phone  string `json:"phone"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
-}
-
-// JWTHelper содержит методы для работы с JWT токенами
-type JWTHelper struct {
-	secretKey []byte
-}
-
-// NewJWTHelper создает новый JWT helper
-func NewJWTHelper() *JWTHelper {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key" // для разработки, в продакшене обязательно через переменную окружения
-	}
-	return &JWTHelper{
-		secretKey: []byte(secret),
-	}
-}
-
-// GenerateToken создает новый JWT токен
-func (j *JWTHelper) GenerateToken(userID uint, phone string, role string) (string, error) {
-	// Устанавливаем время жизни токена - 24 часа
-	expirationTime := time.Now().Add(24 * time.Hour)
-
-	claims := &Claims{
-		UserID: userID,
-		Phone:  phone,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
-}
-
-// ValidateToken проверяет и парсит JWT токен
-func (j *JWTHelper) ValidateToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.secretKey, nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-
-	return claims, nil
-}
-
-// RefreshToken создает новый токен на основе старого
-func (j *JWTHelper) RefreshToken(tokenString string) (string, error) {
-	claims, err := j.ValidateToken(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	// Создаем новый токен с теми же данными пользователя
-	return j.GenerateToken(claims.UserID, claims.Phone, claims.Role)
-}
-
+package jwthelper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PurposeMFA назначение токена, подтверждающего первый фактор при входе с 2FA
+const PurposeMFA = "mfa"
+
+// PurposeMagicLink назначение токена из ссылки для входа по email
+const PurposeMagicLink = "magic_link"
+
+// purposeAudiencePrefix префикс aud токенов с назначением (MFA challenge, ссылка для входа).
+// Их подписывают те же опубликованные в JWKS ключи, поэтому у них своя аудитория и typ,
+// и сервисы, проверяющие aud access токенов, их отклоняют.
+const purposeAudiencePrefix = "nodabackend-auth:"
+
+// mfaTokenTTL время жизни MFA challenge токена
+const mfaTokenTTL = 5 * time.Minute
+
+// Ошибки проверки токена. Отдельные ошибки позволяют клиенту понять,
+// нужно ли обновить токен (истек) или проблема в настройке (чужая аудитория, издатель).
+var (
+	ErrTokenInvalid         = errors.New("invalid token")
+	ErrTokenExpired         = errors.New("token has expired")
+	ErrTokenNotYetValid     = errors.New("token is not valid yet")
+	ErrTokenInvalidAudience = errors.New("token has invalid audience")
+	ErrTokenInvalidIssuer   = errors.New("token has invalid issuer")
+)
+
+// Claims представляет JWT claims
+type Claims struct {
+	UserID    uint   `json:"user_id"`
+	Phone     string `json:"phone"`
+	Role      string `json:"role"`
+	Purpose   string `json:"purpose,omitempty"`   // пусто для access токена
+	SessionID string `json:"sid,omitempty"`       // сессия (семейство refresh токенов), для которой выпущен токен
+	ClientID  string `json:"client_id,omitempty"` // сервисный клиент; пусто для токенов пользователя
+	Scope     string `json:"scope,omitempty"`     // scopes сервисного токена через пробел
+	// IssuedAtNano время выпуска в наносекундах Unix. iat хранится с точностью до секунды,
+	// а для отзыва всех токенов пользователя нужно отличать токены, выпущенные в ту же секунду.
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IssuedAtTime возвращает время выпуска токена с максимальной доступной точностью.
+// Для токенов без iat_ns — iat с точностью до секунды, без iat — нулевое время.
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAtNano != 0 {
+		return time.Unix(0, c.IssuedAtNano)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// IsService проверяет, выпущен ли токен сервисному клиенту (client credentials), а не пользователю
+func (c *Claims) IsService() bool {
+	return c.ClientID != ""
+}
+
+// JWTHelper содержит методы для работы с JWT токенами
+type JWTHelper struct {
+	keyring        *Keyring
+	config         *Config
+	parser         *jwt.Parser            // access токены
+	purposeParsers map[string]*jwt.Parser // токены с назначением, по Claims.Purpose
+}
+
+// NewJWTHelper создает новый JWT helper, подписывающий токены ключами из keyring.
+// nil config — конфигурация по умолчанию.
+func NewJWTHelper(keyring *Keyring, config *Config) (*JWTHelper, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.validate(keyring); err != nil {
+		return nil, err
+	}
+
+	// Принимаются только разрешенные алгоритмы ключей из keyring
+	var methods []string
+	for _, algorithm := range keyring.algorithms() {
+		if config.allows(algorithm) {
+			methods = append(methods, algorithm)
+		}
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(config.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+
+	// Access токены и токены с назначением проверяются с разной аудиторией
+	withAudience := func(audiences ...string) *jwt.Parser {
+		if len(audiences) == 0 {
+			return jwt.NewParser(options...)
+		}
+		return jwt.NewParser(append(append([]jwt.ParserOption{}, options...), jwt.WithAudience(audiences...))...)
+	}
+
+	purposeParsers := make(map[string]*jwt.Parser)
+	for _, purpose := range []string{PurposeMFA, PurposeMagicLink} {
+		purposeParsers[purpose] = withAudience(purposeAudience(purpose))
+	}
+
+	return &JWTHelper{
+		keyring:        keyring,
+		config:         config,
+		parser:         withAudience(config.Audiences...),
+		purposeParsers: purposeParsers,
+	}, nil
+}
+
+// NewJWTHelperFromEnv создает JWT helper с ключами (см. NewKeyringFromEnv) и настройками
+// (см. NewConfigFromEnv) из окружения. Возвращает ошибку, если ключи не настроены вне dev режима.
+func NewJWTHelperFromEnv() (*JWTHelper, error) {
+	keyring, err := NewKeyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTHelper(keyring, NewConfigFromEnv())
+}
+
+// JWKS возвращает открытые ключи для проверки токенов сторонними сервисами
+func (j *JWTHelper) JWKS() *JWKSet {
+	return j.keyring.JWKS()
+}
+
+// AccessTTL возвращает время жизни access токена
+func (j *JWTHelper) AccessTTL() time.Duration {
+	return j.config.AccessTTL
+}
+
+// Leeway возвращает допустимое расхождение часов. Истекший токен принимается еще это время,
+// поэтому отзыв токена нужно хранить дольше его срока действия на эту величину.
+func (j *JWTHelper) Leeway() time.Duration {
+	return j.config.Leeway
+}
+
+// RefreshTTL возвращает время жизни refresh токена
+func (j *JWTHelper) RefreshTTL() time.Duration {
+	return j.config.RefreshTTL
+}
+
+// GenerateToken создает новый короткоживущий access токен для сессии sessionID
+func (j *JWTHelper) GenerateToken(userID uint, phone string, role string, sessionID string) (string, error) {
+	now := time.Now()
+
+	// jti нужен для точечного отзыва токена (logout)
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:           userID,
+		Phone:            phone,
+		Role:             role,
+		SessionID:        sessionID,
+		IssuedAtNano:     now.UnixNano(),
+		RegisteredClaims: j.registeredClaims(tokenID, now, j.config.AccessTTL),
+	}
+
+	return j.keyring.sign(claims, "")
+}
+
+// GenerateServiceToken создает access токен сервисного клиента с указанными scopes.
+// Токен не привязан к пользователю: sub содержит ID клиента.
+func (j *JWTHelper) GenerateServiceToken(clientID string, scope string) (string, error) {
+	if clientID == "" {
+		return "", errors.New("client id is required")
+	}
+
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		ClientID:         clientID,
+		Scope:            scope,
+		IssuedAtNano:     now.UnixNano(),
+		RegisteredClaims: j.registeredClaims(tokenID, now, j.config.AccessTTL),
+	}
+	claims.Subject = clientID
+
+	return j.keyring.sign(claims, "")
+}
+
+// GenerateMFAToken создает короткоживущий challenge токен после проверки пароля.
+// Он не дает доступа к API и обменивается на access токен вместе с кодом второго фактора.
+func (j *JWTHelper) GenerateMFAToken(userID uint) (string, error) {
+	now := time.Now()
+
+	// jti нужен, чтобы токен можно было использовать только один раз
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:           userID,
+		Purpose:          PurposeMFA,
+		IssuedAtNano:     now.UnixNano(),
+		RegisteredClaims: j.purposeClaims(PurposeMFA, tokenID, now, mfaTokenTTL),
+	}
+
+	return j.keyring.sign(claims, purposeTokenType(PurposeMFA))
+}
+
+// GenerateMagicLinkToken создает токен для ссылки входа по email и возвращает его вместе с jti.
+// По jti на сервере хранится одноразовая запись о ссылке.
+func (j *JWTHelper) GenerateMagicLinkToken(userID uint, ttl time.Duration) (string, string, error) {
+	now := time.Now()
+
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := &Claims{
+		UserID:           userID,
+		Purpose:          PurposeMagicLink,
+		IssuedAtNano:     now.UnixNano(),
+		RegisteredClaims: j.purposeClaims(PurposeMagicLink, tokenID, now, ttl),
+	}
+
+	signed, err := j.keyring.sign(claims, purposeTokenType(PurposeMagicLink))
+	if err != nil {
+		return "", "", err
+	}
+	return signed, tokenID, nil
+}
+
+// registeredClaims заполняет стандартные claims: jti, срок действия, издателя и аудиторию
+func (j *JWTHelper) registeredClaims(tokenID string, now time.Time, ttl time.Duration) jwt.RegisteredClaims {
+	claims := jwt.RegisteredClaims{
+		ID:        tokenID,
+		Issuer:    j.config.Issuer,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	if len(j.config.Audiences) > 0 {
+		claims.Audience = jwt.ClaimStrings(j.config.Audiences)
+	}
+	return claims
+}
+
+// purposeClaims заполняет стандартные claims токена с назначением: aud только его собственная,
+// чтобы токен не принимался там, где ожидается access токен
+func (j *JWTHelper) purposeClaims(purpose, tokenID string, now time.Time, ttl time.Duration) jwt.RegisteredClaims {
+	claims := j.registeredClaims(tokenID, now, ttl)
+	claims.Audience = jwt.ClaimStrings{purposeAudience(purpose)}
+	return claims
+}
+
+// purposeAudience возвращает aud токена с назначением purpose
+func purposeAudience(purpose string) string {
+	return purposeAudiencePrefix + purpose
+}
+
+// purposeTokenType возвращает заголовок typ токена с назначением purpose (RFC 8725, 3.11)
+func purposeTokenType(purpose string) string {
+	return purpose + "+jwt"
+}
+
+// MFATokenTTL возвращает время жизни MFA challenge токена
+func (j *JWTHelper) MFATokenTTL() time.Duration {
+	return mfaTokenTTL
+}
+
+// ValidateToken проверяет и парсит access токен пользователя или сервисного клиента (см. Claims.IsService).
+// Токены с другим назначением (например, MFA challenge) отклоняются.
+func (j *JWTHelper) ValidateToken(tokenString string) (*Claims, error) {
+	return j.parseToken(tokenString, "")
+}
+
+// ValidateMFAToken проверяет и парсит MFA challenge токен
+func (j *JWTHelper) ValidateMFAToken(tokenString string) (*Claims, error) {
+	return j.parseToken(tokenString, PurposeMFA)
+}
+
+// ValidateMagicLinkToken проверяет и парсит токен из ссылки для входа
+func (j *JWTHelper) ValidateMagicLinkToken(tokenString string) (*Claims, error) {
+	return j.parseToken(tokenString, PurposeMagicLink)
+}
+
+// parseToken проверяет подпись, алгоритм, срок действия, издателя и аудиторию JWT токена,
+// а также что токен выпущен с назначением purpose (пусто — access токен).
+// Ключ проверки выбирается по kid, поэтому токены, подписанные выведенным из ротации ключом,
+// остаются действительными, пока ключ есть в keyring.
+func (j *JWTHelper) parseToken(tokenString, purpose string) (*Claims, error) {
+	parser := j.parser
+	if purpose != "" {
+		parser = j.purposeParsers[purpose]
+	}
+
+	claims := &Claims{}
+	token, err := parser.ParseWithClaims(tokenString, claims, j.keyring.keyFunc)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	if !token.Valid || claims.Purpose != purpose {
+		return nil, ErrTokenInvalid
+	}
+	if purpose != "" {
+		if typ, _ := token.Header["typ"].(string); typ != purposeTokenType(purpose) {
+			return nil, ErrTokenInvalid
+		}
+	}
+
+	return claims, nil
+}
+
+// classifyError сводит ошибки golang-jwt к ошибкам пакета.
+// Подпись проверяется раньше claims, поэтому «истек» и т.п. означает, что подпись верна.
+func classifyError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenInvalidAudience
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenInvalidIssuer
+	default:
+		return ErrTokenInvalid
+	}
+}
+
+// GenerateRefreshToken создает непрозрачный refresh токен.
+// Токен не является JWT и проверяется только по хешу, сохраненному на сервере.
+func GenerateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// generateTokenID генерирует уникальный идентификатор токена (jti)
+func generateTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashRefreshToken хеширует refresh токен для хранения в базе данных
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
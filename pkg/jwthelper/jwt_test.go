@@ -0,0 +1,179 @@
+package jwthelper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTHelper_TypedValidationErrors(t *testing.T) {
+	helper := newTestHelper(t, newTestKey(t, AlgorithmES256))
+	config := DefaultConfig()
+	now := time.Now()
+
+	// sign подписывает access токен с измененными стандартными claims
+	sign := func(modify func(*jwt.RegisteredClaims)) string {
+		registered := helper.registeredClaims("jti", now, time.Minute)
+		modify(&registered)
+		token, err := helper.keyring.sign(&Claims{UserID: 1, RegisteredClaims: registered}, "")
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*jwt.RegisteredClaims)
+		want   error
+	}{
+		{"valid", func(*jwt.RegisteredClaims) {}, nil},
+		{"expired within leeway", func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-config.Leeway / 2))
+		}, nil},
+		{"expired", func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-config.Leeway - time.Second))
+		}, ErrTokenExpired},
+		{"missing exp", func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil }, ErrTokenInvalid},
+		{"not yet valid", func(c *jwt.RegisteredClaims) {
+			c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour))
+		}, ErrTokenNotYetValid},
+		{"issued in the future", func(c *jwt.RegisteredClaims) {
+			c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour))
+		}, ErrTokenNotYetValid},
+		{"wrong audience", func(c *jwt.RegisteredClaims) {
+			c.Audience = jwt.ClaimStrings{"another-api"}
+		}, ErrTokenInvalidAudience},
+		{"wrong issuer", func(c *jwt.RegisteredClaims) { c.Issuer = "evil" }, ErrTokenInvalidIssuer},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := helper.ValidateToken(sign(tc.modify))
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("ValidateToken error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestJWTHelper_AllowedAlgorithms(t *testing.T) {
+	hmacKey := NewHMACKey([]byte("secret"))
+
+	config := DefaultConfig()
+	config.AllowedAlgorithms = []string{AlgorithmEdDSA}
+	keyring, err := NewKeyring(hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if _, err := NewJWTHelper(keyring, config); err == nil {
+		t.Fatal("signing key with a disallowed algorithm must be rejected")
+	}
+
+	// HS256 ключ остался в keyring, но алгоритм запрещен: его токены отклоняются
+	legacyToken, err := newTestHelper(t, hmacKey).GenerateToken(1, "", "user", "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	keyring, err = NewKeyring(newTestKey(t, AlgorithmEdDSA), hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	helper, err := NewJWTHelper(keyring, config)
+	if err != nil {
+		t.Fatalf("NewJWTHelper: %v", err)
+	}
+	if _, err := helper.ValidateToken(legacyToken); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("HS256 token must be rejected, got %v", err)
+	}
+}
+
+func TestJWTHelper_IssuedAtTimeSubSecond(t *testing.T) {
+	helper := newTestHelper(t, newTestKey(t, AlgorithmES256))
+
+	before := time.Now()
+	token, err := helper.GenerateToken(1, "", "user", "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	claims, err := helper.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	// iat_ns сохраняет доли секунды, которые теряются в iat
+	issuedAt := claims.IssuedAtTime()
+	if issuedAt.Before(before) || issuedAt.After(time.Now()) {
+		t.Fatalf("IssuedAtTime = %v, want between %v and now", issuedAt, before)
+	}
+	if issuedAt.Unix() != claims.IssuedAt.Unix() {
+		t.Fatalf("iat_ns %v does not match iat %v", issuedAt, claims.IssuedAt.Time)
+	}
+
+	// Токены без iat_ns проверяются по iat
+	legacy := &Claims{RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(before)}}
+	if !legacy.IssuedAtTime().Equal(before.Truncate(time.Second)) {
+		t.Fatalf("IssuedAtTime without iat_ns = %v, want %v", legacy.IssuedAtTime(), before.Truncate(time.Second))
+	}
+}
+
+func TestJWTHelper_PurposeTokensHaveOwnAudience(t *testing.T) {
+	helper := newTestHelper(t, newTestKey(t, AlgorithmES256))
+
+	accessToken, err := helper.GenerateToken(1, "", "user", "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	mfaToken, err := helper.GenerateMFAToken(1)
+	if err != nil {
+		t.Fatalf("GenerateMFAToken: %v", err)
+	}
+	magicLinkToken, _, err := helper.GenerateMagicLinkToken(1, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMagicLinkToken: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		token    string
+		validate func(string) (*Claims, error)
+		want     error
+	}{
+		{"access as access", accessToken, helper.ValidateToken, nil},
+		{"mfa as access", mfaToken, helper.ValidateToken, ErrTokenInvalidAudience},
+		{"magic link as access", magicLinkToken, helper.ValidateToken, ErrTokenInvalidAudience},
+		{"mfa as mfa", mfaToken, helper.ValidateMFAToken, nil},
+		{"access as mfa", accessToken, helper.ValidateMFAToken, ErrTokenInvalidAudience},
+		{"magic link as mfa", magicLinkToken, helper.ValidateMFAToken, ErrTokenInvalidAudience},
+		{"magic link as magic link", magicLinkToken, helper.ValidateMagicLinkToken, nil},
+		{"mfa as magic link", mfaToken, helper.ValidateMagicLinkToken, ErrTokenInvalidAudience},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.validate(tc.token); !errors.Is(err, tc.want) {
+				t.Fatalf("error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	// Сторонний сервис, проверяющий токены по JWKS, отличает MFA токен по aud и typ
+	token, _, err := jwt.NewParser().ParseUnverified(mfaToken, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	audience, _ := token.Claims.GetAudience()
+	if len(audience) != 1 || audience[0] != purposeAudience(PurposeMFA) {
+		t.Fatalf("MFA token aud = %v", audience)
+	}
+	if token.Header["typ"] != "mfa+jwt" {
+		t.Fatalf("MFA token typ = %v", token.Header["typ"])
+	}
+
+	// Аудитория токенов с назначением не может быть аудиторией access токенов
+	config := DefaultConfig()
+	config.Audiences = []string{purposeAudience(PurposeMFA)}
+	if _, err := NewJWTHelper(helper.keyring, config); err == nil {
+		t.Fatal("reserved audience must be rejected")
+	}
+}
@@ -0,0 +1,469 @@
+package jwthelper
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"nodabackend/pkg/env"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmHS256 = "HS256"
+)
+
+// defaultSecret секрет для локальной разработки; вне dev режима запуск с ним запрещен
+const defaultSecret = "insecure-development-only-jwt-secret"
+
+// minHMACSecretLength минимальная длина JWT_SECRET в байтах (RFC 7518, 3.2: не короче хеша SHA-256)
+const minHMACSecretLength = 32
+
+// hmacKeyID kid ключа JWT_SECRET. Токены без kid, выпущенные до ротации ключей,
+// проверяются этим ключом, пока открыт переходный период (см. NewLegacyHMACKey).
+const hmacKeyID = "hs256"
+
+// Key ключ подписи или проверки JWT.
+// Асимметричный ключ без закрытой части годится только для проверки (например, выведенный из ротации).
+type Key struct {
+	ID        string // kid: RFC 7638 thumbprint открытого ключа
+	Algorithm string
+
+	signKey     interface{} // закрытый ключ или HMAC секрет; nil — ключ только для проверки
+	verifyKey   interface{} // открытый ключ или HMAC секрет
+	verifyUntil time.Time   // ненулевое — после этого момента ключ больше не проверяет токены
+}
+
+// CanSign проверяет, есть ли у ключа закрытая часть
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// NewHMACKey создает симметричный ключ HS256
+func NewHMACKey(secret []byte) *Key {
+	return &Key{ID: hmacKeyID, Algorithm: AlgorithmHS256, signKey: secret, verifyKey: secret}
+}
+
+// NewLegacyHMACKey создает HS256 ключ только для проверки токенов, выданных до перехода
+// на асимметричные ключи. Он принимает и токены без kid, но только до момента until.
+func NewLegacyHMACKey(secret []byte, until time.Time) *Key {
+	return &Key{ID: hmacKeyID, Algorithm: AlgorithmHS256, verifyKey: secret, verifyUntil: until}
+}
+
+// NewPrivateKey создает ключ подписи из закрытого ключа RSA, ECDSA P-256 или Ed25519.
+// Алгоритм определяется по типу ключа.
+func NewPrivateKey(privateKey crypto.Signer) (*Key, error) {
+	key, err := NewPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.signKey = privateKey
+	return key, nil
+}
+
+// NewPublicKey создает ключ только для проверки подписи
+func NewPublicKey(publicKey crypto.PublicKey) (*Key, error) {
+	var algorithm string
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		algorithm = AlgorithmRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 ECDSA keys are supported")
+		}
+		algorithm = AlgorithmES256
+	case ed25519.PublicKey:
+		algorithm = AlgorithmEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+
+	kid, err := thumbprint(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{ID: kid, Algorithm: algorithm, verifyKey: publicKey}, nil
+}
+
+// ParseKeysPEM разбирает один или несколько PEM блоков с закрытыми (PKCS#8, PKCS#1, SEC 1)
+// или открытыми (PKIX) ключами
+func ParseKeysPEM(data []byte) ([]*Key, error) {
+	var keys []*Key
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		key, err := parsePEMBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM encoded keys found")
+	}
+	return keys, nil
+}
+
+// parsePEMBlock разбирает один PEM блок
+func parsePEMBlock(block *pem.Block) (*Key, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		return NewPrivateKey(signer)
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(parsed)
+	case "EC PRIVATE KEY":
+		parsed, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(parsed)
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(parsed)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// Keyring набор ключей: один ключ подписи и ключи, которыми еще проверяются ранее выданные токены.
+// При ротации новый ключ становится ключом подписи, а старый остается для проверки,
+// пока не истекут подписанные им токены.
+type Keyring struct {
+	signing *Key
+	keys    []*Key // ключ подписи первым, затем ключи проверки в порядке настройки
+	byID    map[string]*Key
+}
+
+// NewKeyring создает набор ключей; ключ подписи автоматически доступен и для проверки
+func NewKeyring(signing *Key, verification ...*Key) (*Keyring, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, errors.New("signing key must include a private key")
+	}
+
+	keyring := &Keyring{signing: signing, byID: make(map[string]*Key, len(verification)+1)}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if existing, ok := keyring.byID[key.ID]; ok {
+			if existing.Algorithm == AlgorithmHS256 {
+				return nil, errors.New("only one HMAC key can be configured")
+			}
+			// Тот же асимметричный ключ указан дважды (например, закрытый и открытый)
+			continue
+		}
+		keyring.byID[key.ID] = key
+		keyring.keys = append(keyring.keys, key)
+	}
+	return keyring, nil
+}
+
+// NewKeyringFromEnv загружает ключи из окружения:
+//   - JWT_SIGNING_KEY_FILE или JWT_SIGNING_KEY — PEM закрытый ключ подписи (RSA, ECDSA P-256, Ed25519);
+//   - JWT_VERIFICATION_KEY_FILES (через запятую) или JWT_VERIFICATION_KEYS — PEM ключи,
+//     выведенные из ротации, которыми только проверяются ранее выданные токены;
+//   - JWT_SECRET — HS256 секрет не короче 32 байт. Без асимметричного ключа им подписываются токены;
+//   - JWT_LEGACY_HS256_UNTIL — время в RFC 3339, до которого JWT_SECRET при асимметричном ключе
+//     подписи еще проверяет токены, выданные до перехода (в том числе без kid). Без него секрет не используется.
+//
+// Без ключей запуск возможен только в dev режиме (APP_ENV=development) с секретом по умолчанию.
+func NewKeyringFromEnv() (*Keyring, error) {
+	signingKeys, err := loadKeysFromEnv("JWT_SIGNING_KEY_FILE", "JWT_SIGNING_KEY")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT signing key: %w", err)
+	}
+	if len(signingKeys) > 1 {
+		return nil, errors.New("exactly one JWT signing key must be configured")
+	}
+
+	verificationKeys, err := loadKeysFromEnv("JWT_VERIFICATION_KEY_FILES", "JWT_VERIFICATION_KEYS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT verification keys: %w", err)
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == defaultSecret && !env.IsDevelopment() {
+		return nil, errors.New("JWT_SECRET must not use the default value outside development mode")
+	}
+	if secret != "" && len(secret) < minHMACSecretLength {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes", minHMACSecretLength)
+	}
+
+	if len(signingKeys) == 1 {
+		if secret != "" {
+			legacyKey, err := legacyHMACKeyFromEnv(secret)
+			if err != nil {
+				return nil, err
+			}
+			if legacyKey != nil {
+				verificationKeys = append(verificationKeys, legacyKey)
+			}
+		}
+		return NewKeyring(signingKeys[0], verificationKeys...)
+	}
+
+	if secret == "" {
+		if !env.IsDevelopment() {
+			return nil, errors.New("JWT signing key is not configured: set JWT_SIGNING_KEY_FILE, JWT_SIGNING_KEY or JWT_SECRET")
+		}
+		log.Println("[JWT] No signing key configured, using the default development secret")
+		secret = defaultSecret
+	}
+
+	// Подписываем HS256 секретом, асимметричные ключи остаются для проверки
+	return NewKeyring(NewHMACKey([]byte(secret)), verificationKeys...)
+}
+
+// legacyHMACKeyFromEnv возвращает ключ проверки из JWT_SECRET на переходный период
+// JWT_LEGACY_HS256_UNTIL или nil, если период не задан или уже закончился
+func legacyHMACKeyFromEnv(secret string) (*Key, error) {
+	value := os.Getenv("JWT_LEGACY_HS256_UNTIL")
+	if value == "" {
+		log.Println("[JWT] JWT_SECRET is ignored with an asymmetric signing key; set JWT_LEGACY_HS256_UNTIL to accept HS256 tokens")
+		return nil, nil
+	}
+
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_LEGACY_HS256_UNTIL: %w", err)
+	}
+	if !time.Now().Before(until) {
+		log.Printf("[JWT] JWT_LEGACY_HS256_UNTIL %s has passed, HS256 tokens are no longer accepted", value)
+		return nil, nil
+	}
+	return NewLegacyHMACKey([]byte(secret), until), nil
+}
+
+// loadKeysFromEnv читает PEM ключи из файлов (список через запятую) и из значения переменной.
+// В значении переменной переводы строк можно передать как \n.
+func loadKeysFromEnv(filesVar, valueVar string) ([]*Key, error) {
+	var keys []*Key
+
+	for _, path := range strings.Split(os.Getenv(filesVar), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseKeysPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	if value := os.Getenv(valueVar); value != "" {
+		parsed, err := ParseKeysPEM([]byte(strings.ReplaceAll(value, `\n`, "\n")))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", valueVar, err)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	return keys, nil
+}
+
+// SigningKey возвращает ключ, которым подписываются новые токены
+func (k *Keyring) SigningKey() *Key {
+	return k.signing
+}
+
+// sign подписывает claims текущим ключом и указывает его kid в заголовке.
+// Непустой typ заменяет стандартный заголовок typ "JWT".
+func (k *Keyring) sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	return token.SignedString(k.signing.signKey)
+}
+
+// keyFunc выбирает ключ проверки по kid из заголовка токена.
+// Алгоритм токена обязан совпадать с алгоритмом ключа, чтобы открытый ключ
+// нельзя было использовать как HMAC секрет.
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	withoutKid := kid == ""
+	if withoutKid {
+		// Токены, выпущенные до появления kid, подписаны JWT_SECRET
+		kid = hmacKeyID
+	}
+
+	key, ok := k.byID[kid]
+	// Токены без kid принимаются только ключом переходного периода
+	if !ok || (withoutKid && key.verifyUntil.IsZero()) {
+		return nil, errors.New("unknown signing key")
+	}
+	if !key.verifyUntil.IsZero() && time.Now().After(key.verifyUntil) {
+		return nil, errors.New("signing key is no longer accepted")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
+}
+
+// algorithms возвращает алгоритмы всех ключей набора
+func (k *Keyring) algorithms() []string {
+	seen := make(map[string]bool)
+	var algorithms []string
+	for _, key := range k.keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
+
+// JWK открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKSet набор открытых ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые ключи набора. Ключ подписи идет первым; HMAC секреты не публикуются.
+func (k *Keyring) JWKS() *JWKSet {
+	set := &JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if jwk, ok := publicJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// publicJWK представляет открытую часть ключа как JWK; для HMAC ключа возвращает false
+func publicJWK(key *Key) (JWK, bool) {
+	jwk, err := jwkMembers(key.verifyKey)
+	if err != nil {
+		return JWK{}, false
+	}
+	jwk.KeyID = key.ID
+	jwk.Use = "sig"
+	jwk.Algorithm = key.Algorithm
+	return jwk, true
+}
+
+// jwkMembers заполняет обязательные параметры JWK открытого ключа
+func jwkMembers(publicKey interface{}) (JWK, error) {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		// Координаты дополняются нулями до размера поля кривой (RFC 7518, 6.2.1.2)
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			KeyType: "EC",
+			Curve:   pub.Curve.Params().Name,
+			X:       base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+			Y:       base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// thumbprint вычисляет JWK thumbprint (RFC 7638), который используется как kid
+func thumbprint(publicKey crypto.PublicKey) (string, error) {
+	jwk, err := jwkMembers(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Только обязательные параметры в лексикографическом порядке
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,253 @@
+package jwthelper
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestKey генерирует закрытый ключ для алгоритма
+func newTestKey(t *testing.T, algorithm string) *Key {
+	t.Helper()
+
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatalf("generate %s key: %v", algorithm, err)
+	}
+
+	key, err := NewPrivateKey(signer)
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	if key.Algorithm != algorithm {
+		t.Fatalf("algorithm = %s, want %s", key.Algorithm, algorithm)
+	}
+	return key
+}
+
+// newTestHelper создает helper с ключом подписи signing и ключами проверки verification
+func newTestHelper(t *testing.T, signing *Key, verification ...*Key) *JWTHelper {
+	t.Helper()
+
+	keyring, err := NewKeyring(signing, verification...)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	helper, err := NewJWTHelper(keyring, nil)
+	if err != nil {
+		t.Fatalf("NewJWTHelper: %v", err)
+	}
+	return helper
+}
+
+func TestKeyring_SignAndVerifyAllAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := newTestKey(t, algorithm)
+			helper := newTestHelper(t, key)
+
+			token, err := helper.GenerateToken(1, "+77001234567", "user", "sid")
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != algorithm {
+				t.Fatalf("unexpected header: %v", parsed.Header)
+			}
+
+			claims, err := helper.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if claims.UserID != 1 || claims.SessionID != "sid" {
+				t.Fatalf("unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	oldKey := newTestKey(t, AlgorithmRS256)
+	newKey := newTestKey(t, AlgorithmES256)
+
+	oldToken, err := newTestHelper(t, oldKey).GenerateToken(1, "", "user", "")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	// Старый ключ выведен из ротации, но его токены еще действуют
+	rotated := newTestHelper(t, newKey, oldKey)
+	if _, err := rotated.ValidateToken(oldToken); err != nil {
+		t.Fatalf("token signed with the retired key must be valid: %v", err)
+	}
+
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != newKey.ID || jwks.Keys[1].KeyID != oldKey.ID {
+		t.Fatalf("unexpected JWKS: %+v", jwks)
+	}
+
+	// После удаления старого ключа его токены отклоняются
+	if _, err := newTestHelper(t, newKey).ValidateToken(oldToken); err == nil {
+		t.Fatal("token signed with a removed key must be rejected")
+	}
+}
+
+func TestKeyring_RejectsAlgorithmConfusion(t *testing.T) {
+	key := newTestKey(t, AlgorithmRS256)
+	helper := newTestHelper(t, key)
+
+	// Токен подписан HMAC с открытым ключом в качестве секрета
+	publicDER, err := x509.MarshalPKIXPublicKey(key.verifyKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID:           1,
+		Role:             "admin",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	forged.Header["kid"] = key.ID
+	token, err := forged.SignedString(publicPEM)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	if _, err := helper.ValidateToken(token); err == nil {
+		t.Fatal("HS256 token signed with the public key must be rejected")
+	}
+}
+
+func TestKeyring_LegacyHMACTokenWithoutKid(t *testing.T) {
+	secret := []byte("legacy-secret-0123456789abcdefgh")
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID:           1,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	})
+	token, err := legacy.SignedString(secret)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		key   *Key
+		valid bool
+	}{
+		{"transition period", NewLegacyHMACKey(secret, time.Now().Add(time.Hour)), true},
+		{"transition period is over", NewLegacyHMACKey(secret, time.Now().Add(-time.Second)), false},
+		{"secret without transition period", NewHMACKey(secret), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Токены до ротации не содержат iss и aud, поэтому на время перехода их проверка отключена
+			keyring, err := NewKeyring(newTestKey(t, AlgorithmEdDSA), tc.key)
+			if err != nil {
+				t.Fatalf("NewKeyring: %v", err)
+			}
+			config := DefaultConfig()
+			config.Issuer = ""
+			config.Audiences = nil
+			helper, err := NewJWTHelper(keyring, config)
+			if err != nil {
+				t.Fatalf("NewJWTHelper: %v", err)
+			}
+
+			if _, err := helper.ValidateToken(token); (err == nil) != tc.valid {
+				t.Fatalf("ValidateToken error = %v, want valid = %v", err, tc.valid)
+			}
+
+			for _, jwk := range helper.JWKS().Keys {
+				if jwk.Algorithm == AlgorithmHS256 {
+					t.Fatal("HMAC secret must not be published in JWKS")
+				}
+			}
+		})
+	}
+}
+
+func TestKeyringFromEnv(t *testing.T) {
+	key := newTestKey(t, AlgorithmEdDSA)
+	der, err := x509.MarshalPKCS8PrivateKey(key.signKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_KEY", "")
+	if _, err := NewKeyringFromEnv(); err == nil {
+		t.Fatal("missing keys must fail outside development mode")
+	}
+
+	t.Setenv("JWT_SECRET", defaultSecret)
+	if _, err := NewKeyringFromEnv(); err == nil {
+		t.Fatal("default secret must be rejected outside development mode")
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_KEY", string(keyPEM))
+	keyring, err := NewKeyringFromEnv()
+	if err != nil {
+		t.Fatalf("NewKeyringFromEnv: %v", err)
+	}
+	if keyring.SigningKey().ID != key.ID {
+		t.Fatal("signing key must be loaded from JWT_SIGNING_KEY")
+	}
+
+	// JWT_SECRET при асимметричном ключе проверяет токены только в переходный период
+	t.Setenv("JWT_SECRET", "short-secret")
+	if _, err := NewKeyringFromEnv(); err == nil {
+		t.Fatal("secret shorter than 32 bytes must be rejected")
+	}
+	t.Setenv("JWT_SECRET", "legacy-secret-0123456789abcdefgh")
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", "")
+	if keyring, err = NewKeyringFromEnv(); err != nil {
+		t.Fatalf("NewKeyringFromEnv: %v", err)
+	}
+	if _, ok := keyring.byID[hmacKeyID]; ok {
+		t.Fatal("JWT_SECRET must be ignored without JWT_LEGACY_HS256_UNTIL")
+	}
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", "tomorrow")
+	if _, err := NewKeyringFromEnv(); err == nil {
+		t.Fatal("invalid JWT_LEGACY_HS256_UNTIL must be rejected")
+	}
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", time.Now().Add(time.Hour).Format(time.RFC3339))
+	if keyring, err = NewKeyringFromEnv(); err != nil {
+		t.Fatalf("NewKeyringFromEnv: %v", err)
+	}
+	if key, ok := keyring.byID[hmacKeyID]; !ok || key.CanSign() {
+		t.Fatal("JWT_SECRET must verify tokens until JWT_LEGACY_HS256_UNTIL")
+	}
+
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_KEY", "")
+	if _, err := NewKeyringFromEnv(); err != nil {
+		t.Fatalf("development mode must fall back to the default secret: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package otp
+
+import (
+	"fmt"
+	"time"
+)
+
+// Причины отказа в выпуске нового OTP
+const (
+	RateLimitCooldown = "cooldown"
+	RateLimitHourly   = "hourly_limit"
+	RateLimitDaily    = "daily_limit"
+)
+
+// RateLimitError возвращается GenerateOTP, когда новый код пока нельзя отправить
+type RateLimitError struct {
+	Reason     string        // RateLimitCooldown, RateLimitHourly или RateLimitDaily
+	RetryAfter time.Duration // через сколько можно повторить запрос
+}
+
+// Error реализует интерфейс error
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("OTP rate limit exceeded (%s), retry after %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
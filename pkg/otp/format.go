@@ -0,0 +1,117 @@
+package otp
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Charset алфавит, из которого генерируется OTP
+type Charset string
+
+const (
+	// CharsetNumeric только цифры — удобно вводить с цифровой клавиатуры телефона
+	CharsetNumeric Charset = "numeric"
+	// CharsetAlphanumeric английские буквы и цифры (A-Z0-9)
+	CharsetAlphanumeric Charset = "alphanumeric"
+	// CharsetUnambiguous base32 алфавит Крокфорда без I, L, O и U.
+	// При проверке O считается за 0, а I и L — за 1.
+	CharsetUnambiguous Charset = "unambiguous"
+)
+
+// alphabets символы каждого алфавита
+var alphabets = map[Charset]string{
+	CharsetNumeric:      "0123456789",
+	CharsetAlphanumeric: "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	CharsetUnambiguous:  "0123456789ABCDEFGHJKMNPQRSTVWXYZ",
+}
+
+// DefaultSeparator разделитель групп символов по умолчанию
+const DefaultSeparator = "-"
+
+// CodeFormat формат кода: длина, алфавит и группировка для отображения
+type CodeFormat struct {
+	Length    int
+	Charset   Charset
+	GroupSize int    // размер группы символов при отображении (0 — без группировки)
+	Separator string // разделитель групп (по умолчанию DefaultSeparator)
+}
+
+// alphabet возвращает символы алфавита; пустой Charset означает A-Z0-9
+func (f CodeFormat) alphabet() string {
+	if alphabet, ok := alphabets[f.Charset]; ok {
+		return alphabet
+	}
+	return alphabets[CharsetAlphanumeric]
+}
+
+// Generate генерирует случайный код без форматирования
+func (f CodeFormat) Generate() (string, error) {
+	alphabet := f.alphabet()
+	alphabetLength := big.NewInt(int64(len(alphabet)))
+
+	otp := make([]byte, f.Length)
+	for i := range otp {
+		randomIndex, err := rand.Int(rand.Reader, alphabetLength)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random character: %w", err)
+		}
+		otp[i] = alphabet[randomIndex.Int64()]
+	}
+
+	return string(otp), nil
+}
+
+// Format разбивает код на группы для отображения, например "ABCD-EFGH"
+func (f CodeFormat) Format(otp string) string {
+	if f.GroupSize <= 0 || len(otp) <= f.GroupSize {
+		return otp
+	}
+
+	separator := f.Separator
+	if separator == "" {
+		separator = DefaultSeparator
+	}
+
+	groups := make([]string, 0, (len(otp)+f.GroupSize-1)/f.GroupSize)
+	for start := 0; start < len(otp); start += f.GroupSize {
+		end := min(start+f.GroupSize, len(otp))
+		groups = append(groups, otp[start:end])
+	}
+
+	return strings.Join(groups, separator)
+}
+
+// Normalize приводит введенный пользователем код к виду, в котором он хранится:
+// убирает пробелы и разделители групп, переводит буквы в верхний регистр
+// и для CharsetUnambiguous заменяет похожие символы
+func (f CodeFormat) Normalize(input string) string {
+	separator := f.Separator
+	if separator == "" {
+		separator = DefaultSeparator
+	}
+	input = strings.ReplaceAll(input, separator, "")
+
+	var b strings.Builder
+	b.Grow(len(input))
+	for _, r := range input {
+		if unicode.IsSpace(r) || r == '-' {
+			continue
+		}
+
+		r = unicode.ToUpper(r)
+		if f.Charset == CharsetUnambiguous {
+			switch r {
+			case 'O':
+				r = '0'
+			case 'I', 'L':
+				r = '1'
+			}
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package otp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeFormat_Generate(t *testing.T) {
+	tests := []struct {
+		charset Charset
+		allowed string
+	}{
+		{CharsetNumeric, alphabets[CharsetNumeric]},
+		{CharsetAlphanumeric, alphabets[CharsetAlphanumeric]},
+		{CharsetUnambiguous, alphabets[CharsetUnambiguous]},
+		{"", alphabets[CharsetAlphanumeric]},
+	}
+
+	for _, tt := range tests {
+		format := CodeFormat{Length: 32, Charset: tt.charset}
+		code, err := format.Generate()
+		if err != nil {
+			t.Fatalf("generate(%q): %v", tt.charset, err)
+		}
+		if len(code) != 32 {
+			t.Fatalf("generate(%q) length = %d; want 32", tt.charset, len(code))
+		}
+		if strings.Trim(code, tt.allowed) != "" {
+			t.Fatalf("generate(%q) = %q contains characters outside %q", tt.charset, code, tt.allowed)
+		}
+	}
+}
+
+func TestCodeFormat_Format(t *testing.T) {
+	tests := []struct {
+		format CodeFormat
+		code   string
+		want   string
+	}{
+		{CodeFormat{}, "123456", "123456"},
+		{CodeFormat{GroupSize: 3}, "123456", "123-456"},
+		{CodeFormat{GroupSize: 4}, "ABCDEFGHJK", "ABCD-EFGH-JK"},
+		{CodeFormat{GroupSize: 3, Separator: " "}, "123456", "123 456"},
+		{CodeFormat{GroupSize: 8}, "ABCD", "ABCD"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.Format(tt.code); got != tt.want {
+			t.Errorf("Format(%q) with %+v = %q; want %q", tt.code, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestCodeFormat_Normalize(t *testing.T) {
+	tests := []struct {
+		format CodeFormat
+		input  string
+		want   string
+	}{
+		{CodeFormat{Charset: CharsetNumeric}, " 123 456 ", "123456"},
+		{CodeFormat{Charset: CharsetAlphanumeric}, "ab-cd", "ABCD"},
+		{CodeFormat{Charset: CharsetAlphanumeric}, "O1IL", "O1IL"},
+		{CodeFormat{Charset: CharsetUnambiguous}, "oo1i-lXyz", "00111XYZ"},
+		{CodeFormat{Charset: CharsetUnambiguous, Separator: "."}, "ab.cd", "ABCD"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.Normalize(tt.input); got != tt.want {
+			t.Errorf("Normalize(%q) with %+v = %q; want %q", tt.input, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestOTPConfig_FormatFor(t *testing.T) {
+	config := DefaultConfig()
+
+	login := config.FormatFor(OTPTypeLogin)
+	if login.Length != 6 || login.Charset != CharsetNumeric {
+		t.Fatalf("FormatFor(login) = %+v; want 6 numeric", login)
+	}
+
+	email := config.FormatFor(OTPTypeVerifyEmail)
+	if email.Length != 8 || email.Charset != CharsetUnambiguous {
+		t.Fatalf("FormatFor(verify_email) = %+v; want 8 unambiguous", email)
+	}
+}
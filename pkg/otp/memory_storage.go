@@ -0,0 +1,220 @@
+package otp
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval как часто MemoryStorage удаляет истекшие записи
+const memorySweepInterval = time.Minute
+
+// memoryCode код с временем истечения
+type memoryCode struct {
+	data      OTPData
+	expiresAt time.Time
+}
+
+// memoryCounter счетчик отправок в окне
+type memoryCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// MemoryStorage хранилище OTP в памяти процесса.
+// Подходит для тестов и разработки на одном узле: данные не переживают
+// перезапуск и не разделяются между экземплярами приложения.
+type MemoryStorage struct {
+	mu        sync.Mutex
+	codes     map[string]*memoryCode
+	cooldowns map[string]time.Time
+	counters  map[string]*memoryCounter
+	lastSweep time.Time
+	now       func() time.Time // подменяется в тестах
+}
+
+// NewMemoryStorage создает хранилище OTP в памяти
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		codes:     make(map[string]*memoryCode),
+		cooldowns: make(map[string]time.Time),
+		counters:  make(map[string]*memoryCounter),
+		now:       time.Now,
+	}
+}
+
+// ReserveIssue проверяет cooldown и квоты и учитывает новую отправку
+func (s *MemoryStorage) ReserveIssue(ctx context.Context, key string, limits IssueLimits) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if until, ok := s.cooldowns[key]; ok && until.After(now) {
+		return &RateLimitError{Reason: RateLimitCooldown, RetryAfter: until.Sub(now)}
+	}
+
+	hourly := s.counter(key+":hourly", now)
+	if limits.MaxPerHour > 0 && hourly != nil && hourly.count >= limits.MaxPerHour {
+		return &RateLimitError{Reason: RateLimitHourly, RetryAfter: hourly.expiresAt.Sub(now)}
+	}
+
+	daily := s.counter(key+":daily", now)
+	if limits.MaxPerDay > 0 && daily != nil && daily.count >= limits.MaxPerDay {
+		return &RateLimitError{Reason: RateLimitDaily, RetryAfter: daily.expiresAt.Sub(now)}
+	}
+
+	if limits.Cooldown > 0 {
+		s.cooldowns[key] = now.Add(limits.Cooldown)
+	}
+	s.increment(key+":hourly", now, time.Hour)
+	s.increment(key+":daily", now, 24*time.Hour)
+
+	return nil
+}
+
+// Save сохраняет код, заменяя предыдущий
+func (s *MemoryStorage) Save(ctx context.Context, key string, data *OTPData, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	s.codes[key] = &memoryCode{data: *data, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Verify проверяет код под мьютексом
+func (s *MemoryStorage) Verify(ctx context.Context, key, otp string, maxAttempts int) (VerifyResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := s.code(key, s.now())
+	if code == nil {
+		return VerifyNotFound, nil
+	}
+
+	if code.data.Attempts >= maxAttempts {
+		delete(s.codes, key)
+		return VerifyMaxAttempts, nil
+	}
+
+	hashed := hashOTP(otp, code.data.Salt)
+	if subtle.ConstantTimeCompare([]byte(hashed), []byte(code.data.HashedOTP)) == 1 {
+		delete(s.codes, key)
+		return VerifyOK, nil
+	}
+
+	code.data.Attempts++
+	return VerifyInvalid, nil
+}
+
+// Get возвращает копию сохраненного кода
+func (s *MemoryStorage) Get(ctx context.Context, key string) (*OTPData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := s.code(key, s.now())
+	if code == nil {
+		return nil, ErrOTPNotFound
+	}
+
+	data := code.data
+	return &data, nil
+}
+
+// NextSendAt вычисляет, когда будет разрешена следующая отправка кода
+func (s *MemoryStorage) NextSendAt(ctx context.Context, key string, limits IssueLimits) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	var cooldownTTL time.Duration
+	if until, ok := s.cooldowns[key]; ok {
+		cooldownTTL = until.Sub(now)
+	}
+
+	var hourly, daily int
+	var hourlyTTL, dailyTTL time.Duration
+	if counter := s.counter(key+":hourly", now); counter != nil {
+		hourly, hourlyTTL = counter.count, counter.expiresAt.Sub(now)
+	}
+	if counter := s.counter(key+":daily", now); counter != nil {
+		daily, dailyTTL = counter.count, counter.expiresAt.Sub(now)
+	}
+
+	return nextAllowed(now, limits, cooldownTTL, hourly, hourlyTTL, daily, dailyTTL), nil
+}
+
+// Delete удаляет код
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.codes, key)
+	return nil
+}
+
+// code возвращает неистекший код; вызывается под мьютексом
+func (s *MemoryStorage) code(key string, now time.Time) *memoryCode {
+	code, ok := s.codes[key]
+	if !ok {
+		return nil
+	}
+	if !code.expiresAt.After(now) {
+		delete(s.codes, key)
+		return nil
+	}
+	return code
+}
+
+// counter возвращает неистекший счетчик; вызывается под мьютексом
+func (s *MemoryStorage) counter(key string, now time.Time) *memoryCounter {
+	counter, ok := s.counters[key]
+	if !ok {
+		return nil
+	}
+	if !counter.expiresAt.After(now) {
+		delete(s.counters, key)
+		return nil
+	}
+	return counter
+}
+
+// increment увеличивает счетчик; окно начинается с первой отправки, как INCR + EXPIRE в Redis
+func (s *MemoryStorage) increment(key string, now time.Time, window time.Duration) {
+	if counter := s.counter(key, now); counter != nil {
+		counter.count++
+		return
+	}
+	s.counters[key] = &memoryCounter{count: 1, expiresAt: now.Add(window)}
+}
+
+// sweep периодически удаляет истекшие записи, чтобы память не росла бесконечно;
+// вызывается под мьютексом
+func (s *MemoryStorage) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, code := range s.codes {
+		if !code.expiresAt.After(now) {
+			delete(s.codes, key)
+		}
+	}
+	for key, until := range s.cooldowns {
+		if !until.After(now) {
+			delete(s.cooldowns, key)
+		}
+	}
+	for key, counter := range s.counters {
+		if !counter.expiresAt.After(now) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package otp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Поля Redis hash, в котором хранится OTP
+const (
+	fieldHashedOTP = "hashed_otp"
+	fieldSalt      = "salt"
+	fieldAttempts  = "attempts"
+	fieldCreatedAt = "created_at"
+)
+
+// Результаты validateScript
+const (
+	validateResultOK          = 1
+	validateResultInvalid     = 0
+	validateResultNotFound    = -1
+	validateResultMaxAttempts = -2
+)
+
+// validateScript атомарно проверяет OTP.
+// KEYS[1] — ключ OTP; ARGV[1] — хеш введенного кода; ARGV[2] — соль,
+// с которой он посчитан; ARGV[3] — максимальное число попыток.
+// Если код был перевыпущен между чтением соли и запуском скрипта,
+// соль не совпадет и попытка не засчитается.
+var validateScript = redis.NewScript(`
+local data = redis.call('HMGET', KEYS[1], 'hashed_otp', 'salt', 'attempts')
+if not data[1] then
+	return -1
+end
+
+local attempts = tonumber(data[3]) or 0
+if attempts >= tonumber(ARGV[3]) then
+	redis.call('DEL', KEYS[1])
+	return -2
+end
+
+if data[2] ~= ARGV[2] then
+	return 0
+end
+
+if data[1] == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+
+redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+return 0
+`)
+
+// issueScript атомарно проверяет cooldown и квоты и резервирует отправку.
+// KEYS[1] — ключ cooldown, KEYS[2] — часовой счетчик, KEYS[3] — суточный счетчик;
+// ARGV[1] — cooldown в мс, ARGV[2] — лимит в час, ARGV[3] — лимит в сутки (0 — без лимита).
+// Возвращает {0, 0} при успехе или {причина, мс до повтора}: 1 — cooldown, 2 — час, 3 — сутки.
+var issueScript = redis.NewScript(`
+local cooldown = tonumber(ARGV[1])
+local maxHour = tonumber(ARGV[2])
+local maxDay = tonumber(ARGV[3])
+
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	return {1, ttl}
+end
+
+if maxHour > 0 and (tonumber(redis.call('GET', KEYS[2])) or 0) >= maxHour then
+	return {2, redis.call('PTTL', KEYS[2])}
+end
+
+if maxDay > 0 and (tonumber(redis.call('GET', KEYS[3])) or 0) >= maxDay then
+	return {3, redis.call('PTTL', KEYS[3])}
+end
+
+if cooldown > 0 then
+	redis.call('SET', KEYS[1], 1, 'PX', cooldown)
+end
+if redis.call('INCR', KEYS[2]) == 1 then
+	redis.call('PEXPIRE', KEYS[2], 3600000)
+end
+if redis.call('INCR', KEYS[3]) == 1 then
+	redis.call('PEXPIRE', KEYS[3], 86400000)
+end
+return {0, 0}
+`)
+
+// issueReasons соответствие кодов issueScript причинам RateLimitError
+var issueReasons = map[int64]string{
+	1: RateLimitCooldown,
+	2: RateLimitHourly,
+	3: RateLimitDaily,
+}
+
+// RedisStorage хранилище OTP в Redis.
+// Код хранится как hash, проверка и учет отправок выполняются Lua скриптами.
+type RedisStorage struct {
+	redisClient *redis.Client
+}
+
+// NewRedisStorage создает хранилище OTP в Redis
+func NewRedisStorage(redisClient *redis.Client) *RedisStorage {
+	return &RedisStorage{redisClient: redisClient}
+}
+
+// makeLimitKeys создает ключи cooldown и счетчиков выпуска
+func (s *RedisStorage) makeLimitKeys(key string) []string {
+	return []string{key + ":cooldown", key + ":hourly", key + ":daily"}
+}
+
+// ReserveIssue проверяет cooldown и квоты и учитывает новую отправку
+func (s *RedisStorage) ReserveIssue(ctx context.Context, key string, limits IssueLimits) error {
+	result, err := issueScript.Run(ctx, s.redisClient, s.makeLimitKeys(key),
+		limits.Cooldown.Milliseconds(), limits.MaxPerHour, limits.MaxPerDay,
+	).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("failed to check OTP limits in Redis: %w", err)
+	}
+
+	if result[0] == 0 {
+		return nil
+	}
+
+	return &RateLimitError{
+		Reason:     issueReasons[result[0]],
+		RetryAfter: time.Duration(result[1]) * time.Millisecond,
+	}
+}
+
+// Save сохраняет код как Redis hash; предыдущий код (если был) заменяется целиком
+func (s *RedisStorage) Save(ctx context.Context, key string, data *OTPData, ttl time.Duration) error {
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key,
+			fieldHashedOTP, data.HashedOTP,
+			fieldSalt, data.Salt,
+			fieldAttempts, data.Attempts,
+			fieldCreatedAt, data.CreatedAt.Unix(),
+		)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store OTP in Redis: %w", err)
+	}
+	return nil
+}
+
+// Verify проверяет код Lua скриптом
+func (s *RedisStorage) Verify(ctx context.Context, key, otp string, maxAttempts int) (VerifyResult, error) {
+	// Соль нужна, чтобы посчитать хеш введенного кода на стороне приложения
+	salt, err := s.redisClient.HGet(ctx, key, fieldSalt).Result()
+	if err == redis.Nil {
+		return VerifyNotFound, nil
+	}
+	if err != nil {
+		return VerifyInvalid, fmt.Errorf("failed to get OTP from Redis: %w", err)
+	}
+
+	result, err := validateScript.Run(ctx, s.redisClient, []string{key},
+		hashOTP(otp, salt), salt, maxAttempts,
+	).Int()
+	if err != nil {
+		return VerifyInvalid, fmt.Errorf("failed to validate OTP in Redis: %w", err)
+	}
+
+	switch result {
+	case validateResultOK:
+		return VerifyOK, nil
+	case validateResultNotFound:
+		return VerifyNotFound, nil
+	case validateResultMaxAttempts:
+		return VerifyMaxAttempts, nil
+	case validateResultInvalid:
+		return VerifyInvalid, nil
+	default:
+		return VerifyInvalid, fmt.Errorf("unexpected OTP validation result: %d", result)
+	}
+}
+
+// Get возвращает сохраненный код
+func (s *RedisStorage) Get(ctx context.Context, key string) (*OTPData, error) {
+	data, err := s.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OTP from Redis: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, ErrOTPNotFound
+	}
+
+	attempts, err := strconv.Atoi(data[fieldAttempts])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OTP attempts: %w", err)
+	}
+
+	createdAt, err := strconv.ParseInt(data[fieldCreatedAt], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OTP creation time: %w", err)
+	}
+
+	return &OTPData{
+		HashedOTP: data[fieldHashedOTP],
+		Salt:      data[fieldSalt],
+		Attempts:  attempts,
+		CreatedAt: time.Unix(createdAt, 0),
+	}, nil
+}
+
+// NextSendAt вычисляет, когда будет разрешена следующая отправка кода
+func (s *RedisStorage) NextSendAt(ctx context.Context, key string, limits IssueLimits) (time.Time, error) {
+	keys := s.makeLimitKeys(key)
+
+	pipe := s.redisClient.Pipeline()
+	cooldownTTL := pipe.PTTL(ctx, keys[0])
+	hourlyCount := pipe.Get(ctx, keys[1])
+	hourlyTTL := pipe.PTTL(ctx, keys[1])
+	dailyCount := pipe.Get(ctx, keys[2])
+	dailyTTL := pipe.PTTL(ctx, keys[2])
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return time.Time{}, fmt.Errorf("failed to get OTP limits from Redis: %w", err)
+	}
+
+	hourly, _ := hourlyCount.Int()
+	daily, _ := dailyCount.Int()
+
+	return nextAllowed(time.Now(), limits,
+		cooldownTTL.Val(), hourly, hourlyTTL.Val(), daily, dailyTTL.Val()), nil
+}
+
+// Delete удаляет код из Redis
+func (s *RedisStorage) Delete(ctx context.Context, key string) error {
+	return s.redisClient.Del(ctx, key).Err()
+}
@@ -0,0 +1,404 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newServiceFunc создает OTP сервис поверх тестируемого хранилища и
+// возвращает функцию, сдвигающую часы хранилища вперед
+type newServiceFunc func(t *testing.T, config *OTPConfig) (*DefaultOTPService, func(time.Duration))
+
+// newRedisService создает OTP сервис поверх in-process Redis (miniredis)
+func newRedisService(t *testing.T, config *OTPConfig) (*DefaultOTPService, func(time.Duration)) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewOTPService(client, config).(*DefaultOTPService), mr.FastForward
+}
+
+// newMemoryService создает OTP сервис поверх MemoryStorage с управляемыми часами
+func newMemoryService(t *testing.T, config *OTPConfig) (*DefaultOTPService, func(time.Duration)) {
+	t.Helper()
+
+	var offset atomic.Int64
+	storage := NewMemoryStorage()
+	storage.now = func() time.Time {
+		return time.Now().Add(time.Duration(offset.Load()))
+	}
+
+	advance := func(d time.Duration) { offset.Add(int64(d)) }
+	return NewOTPServiceWithStorage(storage, config).(*DefaultOTPService), advance
+}
+
+// conformanceCases общий набор проверок, который должно проходить любое хранилище
+var conformanceCases = []struct {
+	name string
+	run  func(t *testing.T, newService newServiceFunc)
+}{
+	{"ValidateOTP_Success", testValidateOTP_Success},
+	{"ValidateOTP_WrongCodeIncrementsAttempts", testValidateOTP_WrongCodeIncrementsAttempts},
+	{"ValidateOTP_MaxAttemptsExceeded", testValidateOTP_MaxAttemptsExceeded},
+	{"ValidateOTP_ConcurrentGuessesRespectMaxAttempts", testValidateOTP_ConcurrentGuessesRespectMaxAttempts},
+	{"ValidateOTP_Expired", testValidateOTP_Expired},
+	{"GenerateOTP_ReplacesPreviousCode", testGenerateOTP_ReplacesPreviousCode},
+	{"GetOTPInfo", testGetOTPInfo},
+	{"GenerateOTP_ResendCooldown", testGenerateOTP_ResendCooldown},
+	{"GenerateOTP_HourlyAndDailyLimits", testGenerateOTP_HourlyAndDailyLimits},
+	{"GetOTPInfo_NextSendAt", testGetOTPInfo_NextSendAt},
+	{"ValidateOTP_NormalizesInput", testValidateOTP_NormalizesInput},
+	{"GenerateOTP_NumericByDefault", testGenerateOTP_NumericByDefault},
+}
+
+func TestOTPService_Redis(t *testing.T) {
+	for _, tc := range conformanceCases {
+		t.Run(tc.name, func(t *testing.T) { tc.run(t, newRedisService) })
+	}
+}
+
+func TestOTPService_Memory(t *testing.T) {
+	for _, tc := range conformanceCases {
+		t.Run(tc.name, func(t *testing.T) { tc.run(t, newMemoryService) })
+	}
+}
+
+// wrongCode возвращает код той же длины, гарантированно отличающийся от code
+func wrongCode(code string) string {
+	b := []byte(code)
+	if b[0] == '0' {
+		b[0] = '1'
+	} else {
+		b[0] = '0'
+	}
+	return string(b)
+}
+
+func testValidateOTP_Success(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	valid, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, code)
+	if err != nil || !valid {
+		t.Fatalf("ValidateOTP = %v, %v; want true, nil", valid, err)
+	}
+
+	// Код одноразовый
+	if _, err := service.storage.Get(ctx, service.makeKey("user1", OTPTypeLogin)); !errors.Is(err, ErrOTPNotFound) {
+		t.Fatal("OTP must be deleted after successful validation")
+	}
+	if valid, _ := service.ValidateOTP(ctx, "user1", OTPTypeLogin, code); valid {
+		t.Fatal("OTP must not be accepted twice")
+	}
+}
+
+func testValidateOTP_WrongCodeIncrementsAttempts(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	valid, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, wrongCode(code))
+	if valid || err == nil || err.Error() != "invalid OTP" {
+		t.Fatalf("ValidateOTP = %v, %v; want false, invalid OTP", valid, err)
+	}
+
+	info, err := service.GetOTPInfo(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GetOTPInfo: %v", err)
+	}
+	if info.Attempts != 1 {
+		t.Fatalf("Attempts = %d; want 1", info.Attempts)
+	}
+
+	// Правильный код все еще принимается
+	valid, err = service.ValidateOTP(ctx, "user1", OTPTypeLogin, code)
+	if err != nil || !valid {
+		t.Fatalf("ValidateOTP = %v, %v; want true, nil", valid, err)
+	}
+}
+
+func testValidateOTP_MaxAttemptsExceeded(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	for i := 0; i < service.config.MaxAttempts; i++ {
+		if valid, _ := service.ValidateOTP(ctx, "user1", OTPTypeLogin, wrongCode(code)); valid {
+			t.Fatal("wrong code must not be accepted")
+		}
+	}
+
+	valid, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, code)
+	if valid || err == nil || err.Error() != "maximum attempts exceeded" {
+		t.Fatalf("ValidateOTP = %v, %v; want false, maximum attempts exceeded", valid, err)
+	}
+	if _, err := service.storage.Get(ctx, service.makeKey("user1", OTPTypeLogin)); !errors.Is(err, ErrOTPNotFound) {
+		t.Fatal("OTP must be deleted after exceeding attempts")
+	}
+}
+
+func testValidateOTP_ConcurrentGuessesRespectMaxAttempts(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, &OTPConfig{
+		OTPLength:   6,
+		OTPExpiry:   time.Minute,
+		MaxAttempts: 3,
+	})
+	ctx := context.Background()
+
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	guess := wrongCode(code)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		checked int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, guess)
+			if err != nil && err.Error() == "invalid OTP" {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Каждый ответ "invalid OTP" означает, что код был реально сравнен
+	if checked > service.config.MaxAttempts {
+		t.Fatalf("%d guesses were checked; want at most %d", checked, service.config.MaxAttempts)
+	}
+}
+
+func testValidateOTP_Expired(t *testing.T, newService newServiceFunc) {
+	service, advance := newService(t, nil)
+	ctx := context.Background()
+
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	advance(service.config.OTPExpiry + time.Second)
+
+	valid, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, code)
+	if valid || err == nil || err.Error() != "OTP not found or expired" {
+		t.Fatalf("ValidateOTP = %v, %v; want false, OTP not found or expired", valid, err)
+	}
+}
+
+func testGenerateOTP_ReplacesPreviousCode(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, &OTPConfig{
+		OTPLength:   6,
+		OTPExpiry:   time.Minute,
+		MaxAttempts: 3,
+	})
+	ctx := context.Background()
+
+	first, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	if _, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, wrongCode(first)); err == nil {
+		t.Fatal("wrong code must be rejected")
+	}
+
+	second, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	info, err := service.GetOTPInfo(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GetOTPInfo: %v", err)
+	}
+	if info.Attempts != 0 {
+		t.Fatalf("Attempts = %d; want 0 for a new code", info.Attempts)
+	}
+
+	if first != second {
+		if valid, _ := service.ValidateOTP(ctx, "user1", OTPTypeLogin, first); valid {
+			t.Fatal("previous code must not be accepted")
+		}
+	}
+	if valid, err := service.ValidateOTP(ctx, "user1", OTPTypeLogin, second); err != nil || !valid {
+		t.Fatalf("ValidateOTP = %v, %v; want true, nil", valid, err)
+	}
+}
+
+func testGetOTPInfo(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	before := time.Now().Add(-time.Second)
+	if _, err := service.GenerateOTP(ctx, "user1", OTPTypeVerifyPhone); err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	info, err := service.GetOTPInfo(ctx, "user1", OTPTypeVerifyPhone)
+	if err != nil {
+		t.Fatalf("GetOTPInfo: %v", err)
+	}
+	if info.HashedOTP == "" || info.Salt == "" {
+		t.Fatal("GetOTPInfo must return hash and salt")
+	}
+	if info.CreatedAt.Before(before) || info.CreatedAt.After(time.Now()) {
+		t.Fatalf("CreatedAt = %v; want around now", info.CreatedAt)
+	}
+
+	if _, err := service.GetOTPInfo(ctx, "user2", OTPTypeVerifyPhone); err == nil {
+		t.Fatal("GetOTPInfo must fail for missing OTP")
+	}
+}
+
+func testGenerateOTP_ResendCooldown(t *testing.T, newService newServiceFunc) {
+	service, advance := newService(t, nil)
+	ctx := context.Background()
+
+	if _, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin); err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	_, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("GenerateOTP error = %v; want *RateLimitError", err)
+	}
+	if rateErr.Reason != RateLimitCooldown {
+		t.Fatalf("Reason = %q; want %q", rateErr.Reason, RateLimitCooldown)
+	}
+	if rateErr.RetryAfter <= 0 || rateErr.RetryAfter > service.config.ResendCooldown {
+		t.Fatalf("RetryAfter = %v; want within (0, %v]", rateErr.RetryAfter, service.config.ResendCooldown)
+	}
+
+	// Cooldown не распространяется на другие типы и других пользователей
+	if _, err := service.GenerateOTP(ctx, "user1", OTPTypeResetPassword); err != nil {
+		t.Fatalf("GenerateOTP for another type: %v", err)
+	}
+	if _, err := service.GenerateOTP(ctx, "user2", OTPTypeLogin); err != nil {
+		t.Fatalf("GenerateOTP for another user: %v", err)
+	}
+
+	advance(service.config.ResendCooldown)
+	if _, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin); err != nil {
+		t.Fatalf("GenerateOTP after cooldown: %v", err)
+	}
+}
+
+func testGenerateOTP_HourlyAndDailyLimits(t *testing.T, newService newServiceFunc) {
+	service, advance := newService(t, &OTPConfig{
+		OTPLength:   6,
+		OTPExpiry:   time.Minute,
+		MaxAttempts: 3,
+		MaxPerHour:  2,
+		MaxPerDay:   3,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin); err != nil {
+			t.Fatalf("GenerateOTP #%d: %v", i+1, err)
+		}
+	}
+
+	_, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) || rateErr.Reason != RateLimitHourly {
+		t.Fatalf("GenerateOTP error = %v; want hourly limit", err)
+	}
+
+	advance(time.Hour)
+	if _, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin); err != nil {
+		t.Fatalf("GenerateOTP after an hour: %v", err)
+	}
+
+	_, err = service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if !errors.As(err, &rateErr) || rateErr.Reason != RateLimitDaily {
+		t.Fatalf("GenerateOTP error = %v; want daily limit", err)
+	}
+	if rateErr.RetryAfter <= time.Hour {
+		t.Fatalf("RetryAfter = %v; want remaining part of the day", rateErr.RetryAfter)
+	}
+}
+
+func testGetOTPInfo_NextSendAt(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	if _, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin); err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+
+	info, err := service.GetOTPInfo(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GetOTPInfo: %v", err)
+	}
+
+	wait := time.Until(info.NextSendAt)
+	if wait <= 0 || wait > service.config.ResendCooldown {
+		t.Fatalf("NextSendAt is %v from now; want within (0, %v]", wait, service.config.ResendCooldown)
+	}
+}
+
+func testValidateOTP_NormalizesInput(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	// Для verify_email по умолчанию 8 символов без похожих букв, группами по 4
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeVerifyEmail)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	if len(code) != 9 || code[4] != '-' {
+		t.Fatalf("code = %q; want XXXX-XXXX", code)
+	}
+
+	// Пользователь ввел код строчными буквами, с пробелом вместо дефиса
+	input := strings.ToLower(code[:4] + " " + code[5:])
+	valid, err := service.ValidateOTP(ctx, "user1", OTPTypeVerifyEmail, input)
+	if err != nil || !valid {
+		t.Fatalf("ValidateOTP(%q) = %v, %v; want true, nil", input, valid, err)
+	}
+}
+
+func testGenerateOTP_NumericByDefault(t *testing.T, newService newServiceFunc) {
+	service, _ := newService(t, nil)
+	ctx := context.Background()
+
+	code, err := service.GenerateOTP(ctx, "user1", OTPTypeLogin)
+	if err != nil {
+		t.Fatalf("GenerateOTP: %v", err)
+	}
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		t.Fatalf("code = %q; want 6 digits", code)
+	}
+}
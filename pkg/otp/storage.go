@@ -0,0 +1,80 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Бэкенды хранилища OTP
+const (
+	// StorageRedis хранит коды в Redis (по умолчанию)
+	StorageRedis = "redis"
+	// StorageMemory хранит коды в памяти процесса — для тестов и single-node разработки
+	StorageMemory = "memory"
+)
+
+// ErrOTPNotFound возвращается хранилищем, если кода нет или он истек
+var ErrOTPNotFound = errors.New("OTP not found")
+
+// VerifyResult результат проверки кода хранилищем
+type VerifyResult int
+
+const (
+	// VerifyInvalid код не совпал, попытка засчитана
+	VerifyInvalid VerifyResult = iota
+	// VerifyOK код совпал и удален
+	VerifyOK
+	// VerifyNotFound кода нет или он истек
+	VerifyNotFound
+	// VerifyMaxAttempts попытки исчерпаны, код удален
+	VerifyMaxAttempts
+)
+
+// IssueLimits ограничения на выпуск новых кодов
+type IssueLimits struct {
+	Cooldown   time.Duration
+	MaxPerHour int
+	MaxPerDay  int
+}
+
+// Storage хранилище OTP кодов и счетчиков отправки.
+// Все методы должны быть безопасны для конкурентного вызова, а
+// ReserveIssue и Verify — атомарны относительно других вызовов с тем же ключом.
+type Storage interface {
+	// ReserveIssue проверяет cooldown и квоты и учитывает новую отправку.
+	// При превышении лимита возвращает *RateLimitError.
+	ReserveIssue(ctx context.Context, key string, limits IssueLimits) error
+	// Save сохраняет код, заменяя предыдущий вместе со счетчиком попыток
+	Save(ctx context.Context, key string, data *OTPData, ttl time.Duration) error
+	// Verify сравнивает введенный код с сохраненным, увеличивает счетчик попыток
+	// при несовпадении и удаляет код при успехе или исчерпании попыток
+	Verify(ctx context.Context, key, otp string, maxAttempts int) (VerifyResult, error)
+	// Get возвращает сохраненный код или ErrOTPNotFound
+	Get(ctx context.Context, key string) (*OTPData, error)
+	// NextSendAt возвращает момент, когда ReserveIssue снова разрешит отправку
+	NextSendAt(ctx context.Context, key string, limits IssueLimits) (time.Time, error)
+	// Delete удаляет код
+	Delete(ctx context.Context, key string) error
+}
+
+// nextAllowed вычисляет момент следующей разрешенной отправки по оставшимся TTL
+// cooldown и счетчиков; счетчик учитывается, только если его лимит исчерпан
+func nextAllowed(now time.Time, limits IssueLimits, cooldownTTL time.Duration, hourly int, hourlyTTL time.Duration, daily int, dailyTTL time.Duration) time.Time {
+	next := now
+	wait := func(ttl time.Duration) {
+		if ttl > 0 && now.Add(ttl).After(next) {
+			next = now.Add(ttl)
+		}
+	}
+
+	wait(cooldownTTL)
+	if limits.MaxPerHour > 0 && hourly >= limits.MaxPerHour {
+		wait(hourlyTTL)
+	}
+	if limits.MaxPerDay > 0 && daily >= limits.MaxPerDay {
+		wait(dailyTTL)
+	}
+
+	return next
+}
@@ -1,27 +1,59 @@
-package otp
-
-import (
-	"time"
-)
-
-// OTPConfig конфигурация OTP сервиса
-type OTPConfig struct {
-	OTPLength   int
-	OTPExpiry   time.Duration
-	MaxAttempts int
-}
-
-// DefaultConfig возвращает конфигурацию по умолчанию
-func DefaultConfig() *OTPConfig {
-	return &OTPConfig{
-		OTPLength:   6,
-		OTPExpiry:   10 * time.Minute,
-		MaxAttempts: 3,
-	}
-}
-
-
-
-
-
-
+package otp
+
+import (
+	"time"
+)
+
+// OTPConfig конфигурация OTP сервиса
+type OTPConfig struct {
+	OTPLength   int
+	OTPExpiry   time.Duration
+	MaxAttempts int
+
+	// Charset алфавит кода (пустое значение — A-Z0-9)
+	Charset Charset
+	// GroupSize разбивает код на группы при отображении (0 — без группировки)
+	GroupSize int
+	// TypeFormats переопределяет формат кода для отдельных типов OTP,
+	// например 6 цифр для SMS и 8 символов для email
+	TypeFormats map[string]CodeFormat
+
+	// ResendCooldown минимальный интервал между отправками кода (0 — без ограничения)
+	ResendCooldown time.Duration
+	// MaxPerHour и MaxPerDay ограничивают число выпущенных кодов
+	// для пары userID + тип OTP (0 — без ограничения)
+	MaxPerHour int
+	MaxPerDay  int
+
+	// Storage бэкенд хранилища: StorageRedis (по умолчанию, пустое значение) или StorageMemory
+	Storage string
+}
+
+// DefaultConfig возвращает конфигурацию по умолчанию
+func DefaultConfig() *OTPConfig {
+	return &OTPConfig{
+		OTPLength:   6,
+		OTPExpiry:   10 * time.Minute,
+		MaxAttempts: 3,
+		Charset:     CharsetNumeric,
+		TypeFormats: map[string]CodeFormat{
+			OTPTypeVerifyEmail: {Length: 8, Charset: CharsetUnambiguous, GroupSize: 4},
+		},
+		ResendCooldown: time.Minute,
+		MaxPerHour:     5,
+		MaxPerDay:      10,
+		Storage:        StorageRedis,
+	}
+}
+
+// FormatFor возвращает формат кода для указанного типа OTP
+func (c *OTPConfig) FormatFor(otpType string) CodeFormat {
+	if format, ok := c.TypeFormats[otpType]; ok {
+		return format
+	}
+	return CodeFormat{
+		Length:    c.OTPLength,
+		Charset:   c.Charset,
+		GroupSize: c.GroupSize,
+	}
+}
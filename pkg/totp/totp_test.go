@@ -0,0 +1,80 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret ключ "12345678901234567890" из RFC 6238, приложение B, в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238, приложение B (SHA1). В RFC коды из 8 цифр; 6-значный код — их последние 6 цифр.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestGenerateCode_RFC6238Vectors(t *testing.T) {
+	for _, tt := range rfcVectors {
+		code, err := GenerateCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateCode(%d): %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("GenerateCode(%d) = %s; want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidate_RFC6238Vectors(t *testing.T) {
+	for _, tt := range rfcVectors {
+		step, ok := Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("Validate(%d, %s) rejected a valid code", tt.unix, tt.code)
+			continue
+		}
+		if want := tt.unix / 30; step != want {
+			t.Errorf("Validate(%d) step = %d; want %d", tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := GenerateCode(rfcSecret, now)
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+
+	if _, ok := Validate(rfcSecret, code, now.Add(Period)); !ok {
+		t.Error("code from the previous step must be accepted")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(-Period)); !ok {
+		t.Error("code from the next step must be accepted")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(3*Period)); ok {
+		t.Error("code outside the skew window must be rejected")
+	}
+}
+
+func TestValidate_RejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870821", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate(%q) must be rejected", code)
+		}
+	}
+	if _, ok := Validate("not base32!", "287082", now); ok {
+		t.Error("invalid secret must be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "287 082", now); !ok {
+		t.Error("spaces in the code must be ignored")
+	}
+}